@@ -77,6 +77,41 @@ func NewAddressResolver(
 	addr netip.Addr,
 	flags LookupFlags) (*AddressResolver, error) {
 
+	return newAddressResolver(context.Background(),
+		"NewAddressResolver", clnt, ifidx, proto, addr, flags)
+}
+
+// NewAddressResolverContext creates a new [AddressResolver], like the
+// [NewAddressResolver] does, and associates it with the context.
+//
+// The ctx doesn't limit the AddressResolver lifetime, it lives until
+// closed. Instead, ctx is passed to the [Observer], so instrumentation
+// (i.e., tracing) may link the AddressResolver with the operation it
+// was created for.
+func NewAddressResolverContext(
+	ctx context.Context,
+	clnt *Client,
+	ifidx IfIndex,
+	proto Protocol,
+	addr netip.Addr,
+	flags LookupFlags) (*AddressResolver, error) {
+
+	return newAddressResolver(ctx, "NewAddressResolverContext",
+		clnt, ifidx, proto, addr, flags)
+}
+
+// newAddressResolver creates a new [AddressResolver]. It is the common
+// implementation of the NewAddressResolver and NewAddressResolverContext.
+// op is the operation name, used for errors reporting.
+func newAddressResolver(
+	ctx context.Context,
+	op string,
+	clnt *Client,
+	ifidx IfIndex,
+	proto Protocol,
+	addr netip.Addr,
+	flags LookupFlags) (*AddressResolver, error) {
+
 	// Initialize AddressResolver structure
 	resolver := &AddressResolver{clnt: clnt}
 	resolver.handle = cgo.NewHandle(resolver)
@@ -85,32 +120,46 @@ func NewAddressResolver(
 	// Convert address to AvahiAddress
 	caddr, err := makeAvahiAddress(addr)
 	if err != nil {
-		return nil, ErrInvalidAddress
+		resolver.queue.Close()
+		resolver.handle.Delete()
+		return nil, newError(op, ErrInvalidAddress, addr)
 	}
 
 	// Create AvahiAddressResolver
-	avahiClient := clnt.begin()
-	defer clnt.end()
-
-	resolver.avahiResolver = C.avahi_address_resolver_new(
-		avahiClient,
-		C.AvahiIfIndex(ifidx),
-		C.AvahiProtocol(proto),
-		&caddr,
-		C.AvahiLookupFlags(flags),
-		C.AvahiAddressResolverCallback(C.addressResolverCallback),
-		unsafe.Pointer(&resolver.handle),
-	)
-
-	if resolver.avahiResolver == nil {
+	info := &ObjectInfo{
+		Kind:  "AddressResolver",
+		IfIdx: ifidx,
+		Proto: proto,
+		Addr:  addr,
+		Flags: flags,
+	}
+
+	err = newObject(ctx, clnt, resolver, &resolver.queue,
+		info, func(avahiClient *C.AvahiClient) error {
+			resolver.avahiResolver = C.avahi_address_resolver_new(
+				avahiClient,
+				C.AvahiIfIndex(ifidx),
+				C.AvahiProtocol(proto),
+				&caddr,
+				C.AvahiLookupFlags(flags),
+				C.AvahiAddressResolverCallback(
+					C.addressResolverCallback),
+				unsafe.Pointer(&resolver.handle),
+			)
+
+			if resolver.avahiResolver == nil {
+				return clnt.errno()
+			}
+
+			return nil
+		})
+
+	if err != nil {
 		resolver.queue.Close()
 		resolver.handle.Delete()
-		return nil, clnt.errno()
+		return nil, newError(op, err, addr)
 	}
 
-	// Register self to be closed if Client is closed
-	resolver.clnt.addCloser(resolver)
-
 	return resolver, nil
 }
 
@@ -141,10 +190,14 @@ func (resolver *AddressResolver) Get(ctx context.Context) (
 // Note, double close is safe.
 func (resolver *AddressResolver) Close() {
 	if !resolver.closed.Swap(true) {
+		resolver.clnt.notifyClose("AddressResolver", resolver)
+
 		resolver.clnt.begin()
 		resolver.clnt.delCloser(resolver)
-		C.avahi_address_resolver_free(resolver.avahiResolver)
-		resolver.avahiResolver = nil
+		if resolver.avahiResolver != nil {
+			C.avahi_address_resolver_free(resolver.avahiResolver)
+			resolver.avahiResolver = nil
+		}
 		resolver.clnt.end()
 
 		resolver.queue.Close()
@@ -192,5 +245,6 @@ func addressResolverCallback(
 		evnt.Err = clnt.errno()
 	}
 
+	resolver.clnt.notifyEvent("AddressResolver", resolver, evnt)
 	resolver.queue.Push(evnt)
 }
@@ -0,0 +1,175 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Client backends
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// #include <avahi-client/client.h>
+// #include <avahi-common/thread-watch.h>
+import "C"
+
+// clientBackend is the Client backend. The native backend, created
+// by the NewClient, talks to the avahi-daemon. The replay backend,
+// created by the NewReplayClient, replays the capture, written by
+// the Recorder.
+//
+// Object constructors don't talk to the backend directly. Instead,
+// they call newObject, passing the callback that creates the
+// underlying Avahi object, and the backend decides how to handle it.
+type clientBackend interface {
+	// begin locks the backend and returns *C.AvahiClient,
+	// nil if backend doesn't have one.
+	begin() *C.AvahiClient
+
+	// end unlocks the backend.
+	end()
+
+	// close closes the backend. It calls closeChildren to close
+	// children objects of the Client at the appropriate moment.
+	close(closeChildren func())
+
+	// create creates the new object. The native backend calls the
+	// native callback; the replay backend returns recorded events
+	// of the object. Called under the backend lock.
+	create(info *ObjectInfo,
+		native func(*C.AvahiClient) error) ([]json.RawMessage, error)
+
+	// errno returns error code of the latest failed operation.
+	errno() ErrCode
+
+	// Client parameters. Called under the backend lock.
+	versionString() string
+	hostName() string
+	domainName() string
+	hostFQDN() string
+	state() ClientState
+}
+
+// nativeBackend is the clientBackend that talks to the avahi-daemon.
+type nativeBackend struct {
+	threadedPoll *C.AvahiThreadedPoll // Avahi event loop
+	avahiClient  *C.AvahiClient       // Underlying AvahiClient
+}
+
+// begin locks the Avahi event loop and returns *C.AvahiClient.
+func (b *nativeBackend) begin() *C.AvahiClient {
+	C.avahi_threaded_poll_lock(b.threadedPoll)
+	return b.avahiClient
+}
+
+// end unlocks the Avahi event loop.
+func (b *nativeBackend) end() {
+	C.avahi_threaded_poll_unlock(b.threadedPoll)
+}
+
+// close stops the Avahi event loop and frees AvahiClient.
+func (b *nativeBackend) close(closeChildren func()) {
+	C.avahi_threaded_poll_stop(b.threadedPoll)
+
+	closeChildren()
+
+	C.avahi_client_free(b.avahiClient)
+	b.avahiClient = nil
+
+	C.avahi_threaded_poll_free(b.threadedPoll)
+	b.threadedPoll = nil
+}
+
+// create creates the new object, using the native callback.
+func (b *nativeBackend) create(info *ObjectInfo,
+	native func(*C.AvahiClient) error) ([]json.RawMessage, error) {
+	return nil, native(b.avahiClient)
+}
+
+// errno returns error code of the latest failed operation.
+func (b *nativeBackend) errno() ErrCode {
+	// The very first Client callback may come too early, even
+	// before C.avahi_client_new returns, so avahiClient may be
+	// not yet initialized at that time...
+	if b.avahiClient == nil {
+		return ErrFailure
+	}
+
+	return ErrCode(C.avahi_client_errno(b.avahiClient))
+}
+
+// versionString returns avahi-daemon version string.
+func (b *nativeBackend) versionString() string {
+	return C.GoString(C.avahi_client_get_version_string(b.avahiClient))
+}
+
+// hostName returns host name.
+func (b *nativeBackend) hostName() string {
+	return C.GoString(C.avahi_client_get_host_name(b.avahiClient))
+}
+
+// domainName returns domain name.
+func (b *nativeBackend) domainName() string {
+	return C.GoString(C.avahi_client_get_domain_name(b.avahiClient))
+}
+
+// hostFQDN returns FQDN host name.
+func (b *nativeBackend) hostFQDN() string {
+	return C.GoString(C.avahi_client_get_host_name_fqdn(b.avahiClient))
+}
+
+// state returns the current Client state.
+func (b *nativeBackend) state() ClientState {
+	return ClientState(C.avahi_client_get_state(b.avahiClient))
+}
+
+// newObject creates the new object, using the Client backend.
+//
+// The create callback creates the underlying Avahi object. It is
+// called under the Client lock and only by the native backend;
+// with the replay backend, recorded events of the object are
+// queued instead.
+//
+// On success, the object is registered to be closed if Client is
+// closed, and its creation is reported to the hooks. On failure,
+// error is returned unwrapped, and caller is responsible for
+// cleanup.
+func newObject[T any](ctx context.Context, clnt *Client, obj closer,
+	q *eventqueue[T], info *ObjectInfo,
+	create func(*C.AvahiClient) error) error {
+
+	info.QueueLen = q.Len
+
+	clnt.begin()
+	events, err := clnt.backend.create(info, create)
+	if err == nil {
+		// Register self to be closed if Client is closed
+		clnt.addCloser(obj)
+
+		// Report object creation
+		if hooks := clnt.hooks(); hooks != nil {
+			hooks.create(ctx, obj, info)
+		}
+	}
+	clnt.end()
+
+	if err != nil {
+		return err
+	}
+
+	// Queue recorded events
+	for _, data := range events {
+		var evnt T
+		if json.Unmarshal(data, &evnt) == nil {
+			clnt.notifyEvent(info.Kind, obj, evnt)
+			q.Push(evnt)
+		}
+	}
+
+	return nil
+}
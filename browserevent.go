@@ -44,7 +44,7 @@ var browserEventNames = map[BrowserEvent]string{
 	BrowserRemove:         "BrowserRemove",
 	BrowserCacheExhausted: "BrowserCacheExhausted",
 	BrowserAllForNow:      "BrowserAllForNow",
-	BrowserFailure:        "BrowserAllForNow",
+	BrowserFailure:        "BrowserFailure",
 }
 
 // String returns a name of BrowserEvent
@@ -55,3 +55,23 @@ func (e BrowserEvent) String() string {
 	}
 	return n
 }
+
+// MarshalText returns a name of the BrowserEvent.
+// It implements the [encoding.TextMarshaler] interface.
+func (e BrowserEvent) MarshalText() ([]byte, error) {
+	return enumMarshalText(e, browserEventNames)
+}
+
+// UnmarshalText decodes BrowserEvent from its name.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (e *BrowserEvent) UnmarshalText(text []byte) error {
+	return enumUnmarshalText("BrowserEvent.UnmarshalText", e, text,
+		browserEventNames)
+}
+
+// UnmarshalJSON decodes BrowserEvent from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (e *BrowserEvent) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("BrowserEvent.UnmarshalJSON", e, data,
+		e.UnmarshalText)
+}
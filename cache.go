@@ -0,0 +1,481 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Resolver results cache
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache caches results of the host names and service instances
+// resolving, so repeated lookups of the same name don't need to
+// round-trip through the avahi-daemon.
+//
+// Cached result is kept until one of the following happens:
+//   - the max age, specified by the [NewCache], expires
+//   - the [RecordBrowser], watching A and AAAA records of the host
+//     name (and SRV and TXT records of the service instance) reports
+//     that some of these records are removed, or reports the new
+//     record, that doesn't match the cached result (for example,
+//     the IPv6 address, that came after the lookup has completed)
+//   - the [ServiceBrowser], watching the service type, reports
+//     removal of the service instance
+//   - the [Cache.Flush] is called or the [Client] is closed
+//
+// Concurrent lookups of the same name are coalesced: only the
+// first of them actually queries the network, and others wait for
+// its result.
+//
+// Failed lookups are not cached.
+//
+// Cache can be used with the [Dialer] by setting the Dialer.Cache
+// field, so both the [Dialer] and the [HTTPTransport] will benefit
+// from caching.
+type Cache struct {
+	clnt    *Client                // Owning Client
+	maxAge  time.Duration          // Max age of the cached results
+	lock    sync.Mutex             // Access lock
+	entries map[string]*cacheEntry // Cached entries, by key
+}
+
+// cacheEntry represents a single cached lookup result.
+type cacheEntry struct {
+	key      string         // Key in the Cache.entries
+	done     chan struct{}  // Closed when lookup is completed
+	addrs    []netip.Addr   // Resolved addresses (host lookup)
+	svc      *dialerService // Resolved service (service lookup)
+	err      error          // Lookup error
+	retry    bool           // Lookup canceled by its initiator
+	dropped  bool           // Entry was dropped from the Cache
+	watchers []closer       // Browsers that invalidate the entry
+	timer    *time.Timer    // Max age timer
+}
+
+// ResolvedService contains the resolved service instance parameters,
+// as returned by the [Cache.ResolveService].
+type ResolvedService struct {
+	InstanceName string       // Service instance name
+	SvcType      string       // Service type
+	Domain       string       // Service domain
+	Hostname     string       // Service hostname
+	Port         uint16       // Service IP port
+	Addrs        []netip.Addr // Service IP addresses
+	Txt          []string     // TXT record ("key=value"...)
+}
+
+// NewCache creates a new [Cache].
+//
+// maxAge limits lifetime of the cached results. If it is zero or
+// negative, results are kept until invalidated by the network events.
+func NewCache(clnt *Client, maxAge time.Duration) *Cache {
+	return &Cache{
+		clnt:    clnt,
+		maxAge:  maxAge,
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+// LookupHost looks up the given host (e.g., "printer.local"), like
+// [LookupHost] does, but uses the cached result, if available.
+//
+// Errors are reported the same way as by [LookupHost].
+//
+// The returned slice is owned by the caller.
+func (c *Cache) LookupHost(ctx context.Context,
+	name string) ([]netip.Addr, error) {
+
+	// localhost is resolved without contacting avahi-daemon
+	if c.clnt.hasFlags(ClientLoopbackWorkarounds) && isLocalhost(name) {
+		addrs, err := lookupHost(ctx, c.clnt, name, 0)
+		if err != nil {
+			return nil, newError("Cache.LookupHost", err, name)
+		}
+		return addrs, nil
+	}
+
+	key := "host:" + DomainToLower(name)
+	entry, err := c.lookup(ctx, key,
+		func(ctx context.Context, entry *cacheEntry) error {
+			addrs, err := lookupHost(ctx, c.clnt, name, 0)
+			entry.addrs = addrs
+			return err
+		},
+		func(entry *cacheEntry) error {
+			return c.watchHost(entry, name, entry.addrs)
+		})
+
+	if err != nil {
+		return nil, newError("Cache.LookupHost", err, name)
+	}
+
+	return append([]netip.Addr(nil), entry.addrs...), nil
+}
+
+// ResolveService resolves the service instance into the host name,
+// port, set of addresses and the TXT record, using the cached result,
+// if available.
+//
+// instance, svctype and domain identify the service instance, the
+// same way as in the [NewServiceResolver] function. If domain is
+// empty, the [Client.GetDomainName] is used.
+//
+// Service instances, registered with the zero port (placeholders, see
+// [NewServiceResolver] for details), cannot be resolved; for them
+// [ErrInvalidPort] is returned. Other errors are reported the same
+// way as by [LookupHost].
+//
+// The returned ResolvedService is owned by the caller.
+func (c *Cache) ResolveService(ctx context.Context,
+	instance, svctype, domain string) (*ResolvedService, error) {
+
+	if domain == "" {
+		domain = c.clnt.GetDomainName()
+	}
+
+	svc, err := c.resolveService(ctx, instance, svctype, domain)
+	if err != nil {
+		return nil, newError("Cache.ResolveService", err,
+			instance, svctype, domain)
+	}
+
+	return &ResolvedService{
+		InstanceName: instance,
+		SvcType:      svctype,
+		Domain:       domain,
+		Hostname:     svc.hostname,
+		Port:         svc.port,
+		Addrs:        append([]netip.Addr(nil), svc.addrs...),
+		Txt:          append([]string(nil), svc.txt...),
+	}, nil
+}
+
+// Flush drops all cached results.
+//
+// Lookups, currently in progress, are not affected, but their
+// results will not be cached.
+func (c *Cache) Flush() {
+	c.lock.Lock()
+	entries := c.entries
+	c.entries = make(map[string]*cacheEntry)
+	c.lock.Unlock()
+
+	for _, entry := range entries {
+		c.invalidate(entry)
+	}
+}
+
+// resolveService is the internal version of the ResolveService.
+// It returns shared cached value and doesn't wrap errors.
+func (c *Cache) resolveService(ctx context.Context,
+	instance, svctype, domain string) (*dialerService, error) {
+
+	if domain == "" {
+		domain = c.clnt.GetDomainName()
+	}
+
+	name := DomainServiceNameJoin(instance, svctype, domain)
+	if name == "" {
+		return nil, ErrInvalidServiceName
+	}
+
+	key := "service:" + DomainToLower(name)
+	entry, err := c.lookup(ctx, key,
+		func(ctx context.Context, entry *cacheEntry) error {
+			svc, err := resolveService(ctx, c.clnt,
+				instance, svctype, domain, true, true, 0)
+			entry.svc = svc
+			return err
+		},
+		func(entry *cacheEntry) error {
+			return c.watchService(entry, instance, svctype, domain)
+		})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.svc, nil
+}
+
+// lookup returns the cached entry for the key. If entry is not
+// cached yet, it performs the lookup, using the provided callback,
+// and starts watching the entry for invalidation, using the watch
+// callback.
+//
+// If lookup for the same key is already in progress, it waits for
+// its completion. If the pending lookup was canceled by the context
+// of its initiator, the lookup is retried.
+func (c *Cache) lookup(ctx context.Context, key string,
+	lookup func(context.Context, *cacheEntry) error,
+	watch func(*cacheEntry) error) (*cacheEntry, error) {
+
+	for {
+		// Lookup the cache
+		c.lock.Lock()
+		entry := c.entries[key]
+		initiator := entry == nil
+		if initiator {
+			entry = &cacheEntry{key: key, done: make(chan struct{})}
+			c.entries[key] = entry
+		}
+		c.lock.Unlock()
+
+		// If we are the initiator, perform the lookup
+		if initiator {
+			entry.err = lookup(ctx, entry)
+			if entry.err != nil {
+				entry.retry = ctx.Err() != nil
+				c.invalidate(entry)
+			} else {
+				c.startTimer(entry)
+				if watch(entry) != nil {
+					c.invalidate(entry)
+				}
+			}
+
+			close(entry.done)
+		}
+
+		// Wait for result
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, lookupErr(ctx, nil)
+		}
+
+		if !entry.retry || initiator {
+			return entry, entry.err
+		}
+	}
+}
+
+// watchHost starts watching A and AAAA records of the host name.
+//
+// addrs are the cached addresses of the host. Lookup may complete
+// before all addresses are known (resolution delay or deadline),
+// so the new address invalidates the entry.
+func (c *Cache) watchHost(entry *cacheEntry, hostname string,
+	addrs []netip.Addr) error {
+
+	stale := func(evnt *RecordBrowserEvent) bool {
+		if evnt.Event != BrowserNew {
+			return cacheRecordRemoved(evnt)
+		}
+
+		addr := DNSDecodeA(evnt.RData)
+		if evnt.RType == DNSTypeAAAA {
+			addr = DNSDecodeAAAA(evnt.RData)
+		}
+
+		return addr.IsValid() && !cacheHasAddr(addrs, addr)
+	}
+
+	for _, rtype := range []DNSType{DNSTypeA, DNSTypeAAAA} {
+		browser, err := NewRecordBrowser(c.clnt, IfIndexUnspec,
+			ProtocolUnspec, hostname, DNSClassIN, rtype, 0)
+		if err != nil {
+			return err
+		}
+
+		c.addWatcher(entry, browser)
+		go cacheWatch(c, entry, browser.Chan(), stale)
+	}
+
+	return nil
+}
+
+// watchService starts watching the service instance, its SRV and
+// TXT records and A and AAAA records of its host name.
+func (c *Cache) watchService(entry *cacheEntry,
+	instance, svctype, domain string) error {
+
+	browser, err := NewServiceBrowser(c.clnt, IfIndexUnspec,
+		ProtocolUnspec, svctype, domain, 0)
+	if err != nil {
+		return err
+	}
+
+	c.addWatcher(entry, browser)
+	go cacheWatch(c, entry, browser.Chan(),
+		func(evnt *ServiceBrowserEvent) bool {
+			switch evnt.Event {
+			case BrowserRemove:
+				return strings.EqualFold(evnt.InstanceName,
+					instance)
+			case BrowserFailure:
+				return true
+			}
+			return false
+		})
+
+	svc := entry.svc
+	stale := func(evnt *RecordBrowserEvent) bool {
+		if evnt.Event != BrowserNew {
+			return cacheRecordRemoved(evnt)
+		}
+
+		if evnt.RType == DNSTypeSRV {
+			return !cacheSameSRV(evnt.RData, svc)
+		}
+
+		return !cacheSameTxt(DNSDecodeTXT(evnt.RData), svc.txt)
+	}
+
+	name := DomainServiceNameJoin(instance, svctype, domain)
+	for _, rtype := range []DNSType{DNSTypeSRV, DNSTypeTXT} {
+		browser, err := NewRecordBrowser(c.clnt, IfIndexUnspec,
+			ProtocolUnspec, name, DNSClassIN, rtype, 0)
+		if err != nil {
+			return err
+		}
+
+		c.addWatcher(entry, browser)
+		go cacheWatch(c, entry, browser.Chan(), stale)
+	}
+
+	return c.watchHost(entry, svc.hostname, svc.addrs)
+}
+
+// addWatcher adds browser, that watches the entry. If entry is
+// already dropped, the browser is closed immediately.
+func (c *Cache) addWatcher(entry *cacheEntry, browser closer) {
+	c.lock.Lock()
+	dropped := entry.dropped
+	if !dropped {
+		entry.watchers = append(entry.watchers, browser)
+	}
+	c.lock.Unlock()
+
+	if dropped {
+		browser.Close()
+	}
+}
+
+// startTimer starts the max age timer of the entry.
+func (c *Cache) startTimer(entry *cacheEntry) {
+	if c.maxAge <= 0 {
+		return
+	}
+
+	c.lock.Lock()
+	if !entry.dropped {
+		entry.timer = time.AfterFunc(c.maxAge, func() {
+			c.invalidate(entry)
+		})
+	}
+	c.lock.Unlock()
+}
+
+// invalidate drops the entry from the Cache and stops watching it.
+// Double invalidate is safe.
+func (c *Cache) invalidate(entry *cacheEntry) {
+	c.lock.Lock()
+	if c.entries[entry.key] == entry {
+		delete(c.entries, entry.key)
+	}
+
+	entry.dropped = true
+	watchers := entry.watchers
+	entry.watchers = nil
+
+	if entry.timer != nil {
+		entry.timer.Stop()
+		entry.timer = nil
+	}
+	c.lock.Unlock()
+
+	for _, browser := range watchers {
+		browser.Close()
+	}
+}
+
+// cacheWatch runs in goroutine and reads events from the browser
+// channel. When stale returns true or channel is closed (browser
+// is closed, either by the Cache or by the Client), the entry is
+// invalidated.
+func cacheWatch[T any](c *Cache, entry *cacheEntry, ch <-chan T,
+	stale func(T) bool) {
+
+	for evnt := range ch {
+		if stale(evnt) {
+			break
+		}
+	}
+
+	c.invalidate(entry)
+}
+
+// cacheRecordRemoved reports if RecordBrowserEvent invalidates
+// the cached entry.
+func cacheRecordRemoved(evnt *RecordBrowserEvent) bool {
+	return evnt.Event == BrowserRemove || evnt.Event == BrowserFailure
+}
+
+// cacheHasAddr reports if addrs contains addr. IPv6 zones are
+// ignored, as addresses, decoded from the RData, don't have them.
+func cacheHasAddr(addrs []netip.Addr, addr netip.Addr) bool {
+	addr = addr.WithZone("")
+	for _, a := range addrs {
+		if a.WithZone("") == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheSameSRV reports if the SRV record RData matches the
+// cached service port and host name.
+func cacheSameSRV(rdata []byte, svc *dialerService) bool {
+	// SRV RData: priority(2), weight(2), port(2), target
+	if len(rdata) < 7 {
+		return true
+	}
+
+	port := uint16(rdata[4])<<8 | uint16(rdata[5])
+	target := DNSDecodePTR(rdata[6:])
+
+	return target == "" ||
+		(port == svc.port && DomainEqual(target, svc.hostname))
+}
+
+// cacheSameTxt reports if two TXT records contain the same
+// strings. Order is ignored, as Avahi doesn't preserve it.
+// Empty strings are ignored on both sides, so the empty TXT
+// record matches both [] and [""]. Invalid TXT record (nil)
+// is considered matching.
+func cacheSameTxt(txt, cached []string) bool {
+	if txt == nil {
+		return true
+	}
+
+	count := make(map[string]int)
+	for _, s := range cached {
+		if s != "" {
+			count[s]++
+		}
+	}
+
+	for _, s := range txt {
+		if s != "" {
+			count[s]--
+		}
+	}
+
+	for _, n := range count {
+		if n != 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,374 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Resolver results cache test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Capture fragments, used by the Cache tests
+const (
+	testCacheHeader = `{"time":"2024-01-01T00:00:00Z","client":{"state":"running","hostname":"host","domain":"local","fqdn":"host.local","version":"avahi 0.8"}}`
+
+	testCacheHost1 = `{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"HostNameResolver","created":{"Kind":"HostNameResolver","IfIdx":-1,"Proto":"unspec","Name":"printer.local","AddrProto":"ip4"}}
+{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"HostNameResolver","event":{"Event":"ResolverFound","IfIdx":2,"Proto":"ip4","Flags":"mdns","Hostname":"printer.local","Addr":"192.168.0.1"}}
+{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"HostNameResolver","created":{"Kind":"HostNameResolver","IfIdx":-1,"Proto":"unspec","Name":"printer.local","AddrProto":"ip6"}}
+{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"HostNameResolver","event":{"Event":"ResolverFailure","IfIdx":-1,"Proto":"unspec","Err":"avahi: Timeout reached","Hostname":"printer.local"}}`
+
+	testCacheHost2 = `{"time":"2024-01-01T00:00:00Z","obj":3,"kind":"HostNameResolver","created":{"Kind":"HostNameResolver","IfIdx":-1,"Proto":"unspec","Name":"printer.local","AddrProto":"ip4"}}
+{"time":"2024-01-01T00:00:00Z","obj":3,"kind":"HostNameResolver","event":{"Event":"ResolverFound","IfIdx":2,"Proto":"ip4","Flags":"mdns","Hostname":"printer.local","Addr":"192.168.0.2"}}
+{"time":"2024-01-01T00:00:00Z","obj":4,"kind":"HostNameResolver","created":{"Kind":"HostNameResolver","IfIdx":-1,"Proto":"unspec","Name":"printer.local","AddrProto":"ip6"}}
+{"time":"2024-01-01T00:00:00Z","obj":4,"kind":"HostNameResolver","event":{"Event":"ResolverFailure","IfIdx":-1,"Proto":"unspec","Err":"avahi: Timeout reached","Hostname":"printer.local"}}`
+
+	testCacheRemoveAAAA = `{"time":"2024-01-01T00:00:00Z","obj":5,"kind":"RecordBrowser","created":{"Kind":"RecordBrowser","IfIdx":-1,"Proto":"unspec","Name":"printer.local","RClass":"IN","RType":"AAAA"}}
+{"time":"2024-01-01T00:00:00Z","obj":5,"kind":"RecordBrowser","event":{"Event":"BrowserRemove","IfIdx":2,"Proto":"ip6","Name":"printer.local","RClass":"IN","RType":"AAAA","RData":"/oAAAAAAAAAAAAAAAAAAAQ=="}}`
+
+	testCacheService = `{"time":"2024-01-01T00:00:00Z","obj":6,"kind":"ServiceResolver","created":{"Kind":"ServiceResolver","IfIdx":-1,"Proto":"unspec","Name":"Printer","SvcType":"_ipp._tcp","Domain":"local","AddrProto":"ip4"}}
+{"time":"2024-01-01T00:00:00Z","obj":6,"kind":"ServiceResolver","event":{"Event":"ResolverFound","IfIdx":2,"Proto":"ip4","Flags":"mdns","InstanceName":"Printer","SvcType":"_ipp._tcp","Domain":"local","Hostname":"printer.local","Port":631,"Addr":"192.168.0.1","Txt":["rp=ipp/print"]}}
+{"time":"2024-01-01T00:00:00Z","obj":7,"kind":"ServiceResolver","created":{"Kind":"ServiceResolver","IfIdx":-1,"Proto":"unspec","Name":"Printer","SvcType":"_ipp._tcp","Domain":"local","AddrProto":"ip6"}}
+{"time":"2024-01-01T00:00:00Z","obj":7,"kind":"ServiceResolver","event":{"Event":"ResolverFailure","IfIdx":-1,"Proto":"unspec","Err":"avahi: Timeout reached","InstanceName":"Printer","SvcType":"_ipp._tcp","Domain":"local"}}`
+
+	testCacheLateAAAA = `{"time":"2024-01-01T00:00:00Z","obj":9,"kind":"RecordBrowser","created":{"Kind":"RecordBrowser","IfIdx":-1,"Proto":"unspec","Name":"printer.local","RClass":"IN","RType":"A"}}
+{"time":"2024-01-01T00:00:00Z","obj":9,"kind":"RecordBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Flags":"mdns","Name":"printer.local","RClass":"IN","RType":"A","RData":"wKgAAQ=="}}
+{"time":"2024-01-01T00:00:00Z","obj":10,"kind":"RecordBrowser","created":{"Kind":"RecordBrowser","IfIdx":-1,"Proto":"unspec","Name":"printer.local","RClass":"IN","RType":"AAAA"}}
+{"time":"2024-01-01T00:00:00Z","obj":10,"kind":"RecordBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip6","Flags":"mdns","Name":"printer.local","RClass":"IN","RType":"AAAA","RData":"IAENuAAAAAAAAAAAAAAAAQ=="}}`
+
+	testCacheServiceIP6 = `{"time":"2024-01-01T00:00:00Z","obj":11,"kind":"ServiceResolver","created":{"Kind":"ServiceResolver","IfIdx":-1,"Proto":"unspec","Name":"Printer","SvcType":"_ipp._tcp","Domain":"local","AddrProto":"ip4"}}
+{"time":"2024-01-01T00:00:00Z","obj":11,"kind":"ServiceResolver","event":{"Event":"ResolverFound","IfIdx":2,"Proto":"ip4","Flags":"mdns","InstanceName":"Printer","SvcType":"_ipp._tcp","Domain":"local","Hostname":"printer.local","Port":631,"Addr":"192.168.0.1","Txt":["rp=ipp/print"]}}
+{"time":"2024-01-01T00:00:00Z","obj":12,"kind":"ServiceResolver","created":{"Kind":"ServiceResolver","IfIdx":-1,"Proto":"unspec","Name":"Printer","SvcType":"_ipp._tcp","Domain":"local","AddrProto":"ip6"}}
+{"time":"2024-01-01T00:00:00Z","obj":12,"kind":"ServiceResolver","event":{"Event":"ResolverFound","IfIdx":2,"Proto":"ip6","Flags":"mdns","InstanceName":"Printer","SvcType":"_ipp._tcp","Domain":"local","Hostname":"printer.local","Port":631,"Addr":"2001:db8::1","Txt":["rp=ipp/print"]}}`
+
+	testCacheRemoveService = `{"time":"2024-01-01T00:00:00Z","obj":8,"kind":"ServiceBrowser","created":{"Kind":"ServiceBrowser","IfIdx":-1,"Proto":"unspec","SvcType":"_ipp._tcp","Domain":"local"}}
+{"time":"2024-01-01T00:00:00Z","obj":8,"kind":"ServiceBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","InstanceName":"Scanner","SvcType":"_ipp._tcp","Domain":"local"}}
+{"time":"2024-01-01T00:00:00Z","obj":8,"kind":"ServiceBrowser","event":{"Event":"BrowserRemove","IfIdx":2,"Proto":"ip4","InstanceName":"Scanner","SvcType":"_ipp._tcp","Domain":"local"}}
+{"time":"2024-01-01T00:00:00Z","obj":8,"kind":"ServiceBrowser","event":{"Event":"BrowserRemove","IfIdx":2,"Proto":"ip4","InstanceName":"printer","SvcType":"_ipp._tcp","Domain":"local"}}`
+)
+
+// testCacheClient creates the replay Client for the Cache tests
+func testCacheClient(t *testing.T, fragments ...string) *Client {
+	capture := testCacheHeader + "\n" + strings.Join(fragments, "\n") + "\n"
+	clnt, err := NewReplayClient(strings.NewReader(capture), 0)
+	if err != nil {
+		t.Fatalf("NewReplayClient: %s", err)
+	}
+
+	return clnt
+}
+
+// testCacheWaitEmpty waits until all Cache entries are invalidated
+func testCacheWaitEmpty(t *testing.T, c *Cache) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		c.lock.Lock()
+		n := len(c.entries)
+		c.lock.Unlock()
+
+		if n == 0 {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("Cache: entries not invalidated")
+}
+
+// TestCacheLookupHost tests Cache.LookupHost
+func TestCacheLookupHost(t *testing.T) {
+	clnt := testCacheClient(t, testCacheHost1)
+	defer clnt.Close()
+
+	c := NewCache(clnt, 0)
+	expected := []netip.Addr{netip.MustParseAddr("192.168.0.1")}
+
+	// The capture contains only one pair of resolvers, so lookups
+	// that are not served from the cache will time out.
+	for _, name := range []string{"printer.local", "Printer.Local"} {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			time.Second)
+		addrs, err := c.LookupHost(ctx, name)
+		cancel()
+
+		if err != nil || !reflect.DeepEqual(addrs, expected) {
+			t.Errorf("Cache.LookupHost(%q):\n"+
+				"expected: %v\n"+
+				"present:  %v (%v)\n",
+				name, expected, addrs, err)
+		}
+	}
+
+	// After Flush, name is looked up again
+	c.Flush()
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.LookupHost(ctx, "printer.local")
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Cache.LookupHost after Flush: %v", err)
+	}
+
+	// Error is wrapped only once, with the Cache operation name
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "Cache.LookupHost" ||
+		errors.As(e.Err, new(*Error)) {
+		t.Errorf("Cache.LookupHost: bad error wrapping: %#v", err)
+	}
+}
+
+// TestCacheInvalidate tests invalidation of the cached results
+// by the network events and by the max age
+func TestCacheInvalidate(t *testing.T) {
+	type testData struct {
+		name      string        // Test name
+		fragments []string      // Capture fragments
+		maxAge    time.Duration // Cache max age
+	}
+
+	tests := []testData{
+		{
+			name: "RecordBrowser",
+			fragments: []string{testCacheHost1,
+				testCacheRemoveAAAA, testCacheHost2},
+		},
+
+		{
+			name:      "max age",
+			fragments: []string{testCacheHost1, testCacheHost2},
+			maxAge:    10 * time.Millisecond,
+		},
+	}
+
+	for _, test := range tests {
+		clnt := testCacheClient(t, test.fragments...)
+		c := NewCache(clnt, test.maxAge)
+
+		ctx, cancel := context.WithTimeout(context.Background(),
+			time.Second)
+
+		addrs1, err1 := c.LookupHost(ctx, "printer.local")
+		testCacheWaitEmpty(t, c)
+		addrs2, err2 := c.LookupHost(ctx, "printer.local")
+
+		cancel()
+		clnt.Close()
+
+		if err1 != nil || err2 != nil ||
+			len(addrs1) != 1 || len(addrs2) != 1 ||
+			addrs1[0] == addrs2[0] {
+			t.Errorf("%s: %v %v, %v %v",
+				test.name, addrs1, err1, addrs2, err2)
+		}
+	}
+}
+
+// TestCacheResolveService tests Cache.ResolveService
+func TestCacheResolveService(t *testing.T) {
+	clnt := testCacheClient(t, testCacheService, testCacheRemoveService)
+	defer clnt.Close()
+
+	c := NewCache(clnt, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	svc, err := c.ResolveService(ctx, "Printer", "_ipp._tcp", "")
+	expected := &ResolvedService{
+		InstanceName: "Printer",
+		SvcType:      "_ipp._tcp",
+		Domain:       "local",
+		Hostname:     "printer.local",
+		Port:         631,
+		Addrs:        []netip.Addr{netip.MustParseAddr("192.168.0.1")},
+		Txt:          []string{"rp=ipp/print"},
+	}
+
+	if err != nil || !reflect.DeepEqual(svc, expected) {
+		t.Errorf("Cache.ResolveService:\n"+
+			"expected: %#v\n"+
+			"present:  %#v (%v)\n",
+			expected, svc, err)
+	}
+
+	// Removal of the service instance invalidates the entry
+	testCacheWaitEmpty(t, c)
+}
+
+// TestCacheLateAddress tests that the address, reported after the
+// lookup has completed (i.e., the IPv6 answer that came after the
+// resolution delay), invalidates the cached result
+func TestCacheLateAddress(t *testing.T) {
+	clnt := testCacheClient(t, testCacheService, testCacheLateAAAA,
+		testCacheServiceIP6)
+	defer clnt.Close()
+
+	d := &Dialer{Client: clnt, Cache: NewCache(clnt, 0)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The first lookup sees only IPv4 address
+	_, err := d.resolveService(ctx, "Printer", "_ipp._tcp", "local",
+		false, true)
+	if err != ErrNotFound {
+		t.Errorf("Dialer.resolveService (before): %v", err)
+	}
+
+	// The late AAAA record invalidates the entry
+	testCacheWaitEmpty(t, d.Cache)
+
+	svc, err := d.resolveService(ctx, "Printer", "_ipp._tcp", "local",
+		false, true)
+	expected := []netip.Addr{netip.MustParseAddr("2001:db8::1")}
+	if err != nil || !reflect.DeepEqual(svc.addrs, expected) {
+		t.Errorf("Dialer.resolveService (after):\n"+
+			"expected: %v\n"+
+			"present:  %v (%v)\n",
+			expected, svc, err)
+	}
+}
+
+// TestCacheSameRecord tests matching of the RecordBrowser events
+// against the cached result
+func TestCacheSameRecord(t *testing.T) {
+	svc := &dialerService{
+		hostname: "printer.local",
+		port:     631,
+		txt:      []string{"rp=ipp/print", "ty=Printer"},
+	}
+
+	srv := func(port uint16, target string) []byte {
+		rdata := []byte{0, 0, 0, 0, byte(port >> 8), byte(port)}
+		for _, label := range strings.Split(target, ".") {
+			rdata = append(rdata, byte(len(label)))
+			rdata = append(rdata, label...)
+		}
+		return append(rdata, 0)
+	}
+
+	if !cacheSameSRV(srv(631, "Printer.local"), svc) {
+		t.Errorf("cacheSameSRV: same record reported as changed")
+	}
+
+	if cacheSameSRV(srv(632, "printer.local"), svc) {
+		t.Errorf("cacheSameSRV: port change not detected")
+	}
+
+	if cacheSameSRV(srv(631, "printer2.local"), svc) {
+		t.Errorf("cacheSameSRV: target change not detected")
+	}
+
+	txt := DNSEncodeTXT([]string{"ty=Printer", "rp=ipp/print"})
+	if !cacheSameTxt(DNSDecodeTXT(txt), svc.txt) {
+		t.Errorf("cacheSameTxt: same record reported as changed")
+	}
+
+	txt = DNSEncodeTXT([]string{"ty=Printer", "rp=ipp/fax"})
+	if cacheSameTxt(DNSDecodeTXT(txt), svc.txt) {
+		t.Errorf("cacheSameTxt: change not detected")
+	}
+
+	// Empty TXT record
+	empty := [][]string{nil, {}, {""}}
+	for _, cached := range empty {
+		for _, txt := range empty[1:] {
+			if !cacheSameTxt(txt, cached) {
+				t.Errorf("cacheSameTxt(%q, %q): "+
+					"empty record reported as changed",
+					txt, cached)
+			}
+		}
+
+		txt := DNSDecodeTXT(DNSEncodeTXT([]string{""}))
+		if !cacheSameTxt(txt, cached) {
+			t.Errorf("cacheSameTxt(%q, %q): "+
+				"empty record reported as changed",
+				txt, cached)
+		}
+	}
+}
+
+// TestCacheCoalesce tests coalescing of the concurrent lookups
+func TestCacheCoalesce(t *testing.T) {
+	clnt := testCacheClient(t)
+	defer clnt.Close()
+
+	c := NewCache(clnt, 0)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	lookup := func(ctx context.Context, entry *cacheEntry) error {
+		if calls.Add(1) == 1 {
+			// The first call is canceled by its initiator
+			// while others are waiting for it
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		<-release
+		entry.addrs = []netip.Addr{netip.MustParseAddr("::1")}
+		return nil
+	}
+
+	watch := func(*cacheEntry) error { return nil }
+
+	// Start the initiator and wait until it's in progress
+	ctx, cancel := context.WithCancel(context.Background())
+	initiator := make(chan error)
+	go func() {
+		_, err := c.lookup(ctx, "key", lookup, watch)
+		initiator <- err
+	}()
+
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Start waiters
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = c.lookup(context.Background(), "key",
+				lookup, watch)
+		}(i)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-initiator; !errors.Is(err, context.Canceled) {
+		t.Errorf("initiator: %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("waiter %d: %v", i, err)
+		}
+	}
+
+	if n := calls.Load(); n != 2 {
+		t.Errorf("lookup: %d calls, expected 2", n)
+	}
+}
@@ -11,7 +11,6 @@ package avahi
 
 import (
 	"context"
-	"fmt"
 	"runtime/cgo"
 	"sync/atomic"
 	"unsafe"
@@ -34,13 +33,13 @@ import "C"
 // closes its event notifications channel, effectively unblocking
 // pending readers.
 type Client struct {
-	flags        ClientFlags              // Client creation flags
-	handle       cgo.Handle               // Handle to self
-	avahiClient  *C.AvahiClient           // Underlying AvahiClient
-	threadedPoll *C.AvahiThreadedPoll     // Avahi event loop
-	queue        eventqueue[*ClientEvent] // Event queue
-	children     closers                  // Children objects
-	closed       atomic.Bool              // Client is closed
+	flags    ClientFlags                 // Client creation flags
+	handle   cgo.Handle                  // Handle to self
+	backend  clientBackend               // Native or replay backend
+	queue    eventqueue[*ClientEvent]    // Event queue
+	children closers                     // Children objects
+	hooksPtr atomic.Pointer[clientHooks] // Logger and Observer
+	closed   atomic.Bool                 // Client is closed
 }
 
 // ClientFlags modify certain aspects of the Client behavior.
@@ -69,6 +68,36 @@ const (
 	ClientLoopbackWorkarounds ClientFlags = 1 << iota
 )
 
+// clientFlagsNames contains names of ClientFlags bits.
+var clientFlagsNames = []flagName[ClientFlags]{
+	{ClientLoopbackWorkarounds, "loopback-workarounds"},
+}
+
+// String returns ClientFlags as string, for debugging
+func (flags ClientFlags) String() string {
+	return flagsString(flags, clientFlagsNames, ",")
+}
+
+// MarshalText returns ClientFlags as "|"-separated list of names.
+// It implements the [encoding.TextMarshaler] interface.
+func (flags ClientFlags) MarshalText() ([]byte, error) {
+	return flagsMarshalText(flags, clientFlagsNames)
+}
+
+// UnmarshalText decodes ClientFlags from "|"-separated list of names.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (flags *ClientFlags) UnmarshalText(text []byte) error {
+	return flagsUnmarshalText("ClientFlags.UnmarshalText", flags, text,
+		clientFlagsNames)
+}
+
+// UnmarshalJSON decodes ClientFlags from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (flags *ClientFlags) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("ClientFlags.UnmarshalJSON", flags, data,
+		flags.UnmarshalText)
+}
+
 // ClientEvent represents events, generated by the [Client].
 type ClientEvent struct {
 	State ClientState // New client state
@@ -81,29 +110,30 @@ func NewClient(flags ClientFlags) (*Client, error) {
 	// each client to simplify things.
 	threadedPoll := C.avahi_threaded_poll_new()
 	if threadedPoll == nil {
-		return nil, ErrNoMemory
+		return nil, newError("NewClient", ErrNoMemory)
 	}
 
 	// Create Avahi client
-	clnt := &Client{flags: flags, threadedPoll: threadedPoll}
+	backend := &nativeBackend{threadedPoll: threadedPoll}
+	clnt := &Client{flags: flags, backend: backend}
 
 	clnt.handle = cgo.NewHandle(clnt)
 	clnt.queue.init()
 	clnt.children.init()
 
 	var rc C.int
-	clnt.avahiClient = C.avahi_client_new(
+	backend.avahiClient = C.avahi_client_new(
 		C.avahi_threaded_poll_get(threadedPoll),
 		C.AVAHI_CLIENT_NO_FAIL,
 		C.AvahiClientCallback(C.clientCallback),
 		unsafe.Pointer(&clnt.handle),
 		&rc)
 
-	if clnt.avahiClient == nil {
+	if backend.avahiClient == nil {
 		C.avahi_threaded_poll_free(threadedPoll)
 		clnt.queue.Close()
 		clnt.handle.Delete()
-		return nil, fmt.Errorf("avahi: error %d", rc)
+		return nil, newError("NewClient", ErrCode(rc))
 	}
 
 	// And now we finally ready to let AvahiClient run.
@@ -117,15 +147,11 @@ func NewClient(flags ClientFlags) (*Client, error) {
 // Note, double close is safe.
 func (clnt *Client) Close() {
 	if !clnt.closed.Swap(true) {
-		C.avahi_threaded_poll_stop(clnt.threadedPoll)
-
-		clnt.children.close()
-
-		C.avahi_client_free(clnt.avahiClient)
-		clnt.avahiClient = nil
+		if log := clnt.logger(); log != nil {
+			log.close("Client", clnt)
+		}
 
-		C.avahi_threaded_poll_free(clnt.threadedPoll)
-		clnt.threadedPoll = nil
+		clnt.backend.close(clnt.children.close)
 
 		clnt.queue.Close()
 		clnt.handle.Delete()
@@ -171,8 +197,7 @@ func (clnt *Client) GetVersionString() string {
 	clnt.begin()
 	defer clnt.end()
 
-	s := C.avahi_client_get_version_string(clnt.avahiClient)
-	return C.GoString(s)
+	return clnt.backend.versionString()
 }
 
 // GetHostName returns host name (e.g., "name")
@@ -180,8 +205,7 @@ func (clnt *Client) GetHostName() string {
 	clnt.begin()
 	defer clnt.end()
 
-	s := C.avahi_client_get_host_name(clnt.avahiClient)
-	return C.GoString(s)
+	return clnt.backend.hostName()
 }
 
 // GetDomainName returns domain name (e.g., "local")
@@ -189,8 +213,7 @@ func (clnt *Client) GetDomainName() string {
 	clnt.begin()
 	defer clnt.end()
 
-	s := C.avahi_client_get_domain_name(clnt.avahiClient)
-	return C.GoString(s)
+	return clnt.backend.domainName()
 }
 
 // GetHostFQDN returns FQDN host name (e.g., "name.local")
@@ -198,8 +221,15 @@ func (clnt *Client) GetHostFQDN() string {
 	clnt.begin()
 	defer clnt.end()
 
-	s := C.avahi_client_get_host_name_fqdn(clnt.avahiClient)
-	return C.GoString(s)
+	return clnt.backend.hostFQDN()
+}
+
+// getState returns the current Client state.
+func (clnt *Client) getState() ClientState {
+	clnt.begin()
+	defer clnt.end()
+
+	return clnt.backend.state()
 }
 
 // begin locks the Client event loop and returns *C.AvahiClient.
@@ -209,14 +239,13 @@ func (clnt *Client) GetHostFQDN() string {
 //
 // Caller MUST call Client.end after end of operation.
 func (clnt *Client) begin() *C.AvahiClient {
-	C.avahi_threaded_poll_lock(clnt.threadedPoll)
-	return clnt.avahiClient
+	return clnt.backend.begin()
 }
 
 // end must be called after completion of any operation, started
 // with Client.begin.
 func (clnt *Client) end() {
-	C.avahi_threaded_poll_unlock(clnt.threadedPoll)
+	clnt.backend.end()
 }
 
 // hasFlags checks if some of the specified flags were used during
@@ -227,7 +256,7 @@ func (clnt *Client) hasFlags(flags ClientFlags) bool {
 
 // errno returns an error code of latest failed operation.
 func (clnt *Client) errno() ErrCode {
-	return ErrCode(C.avahi_client_errno(clnt.avahiClient))
+	return clnt.backend.errno()
 }
 
 // clientCallback called by AvahiClient to report client state change
@@ -243,14 +272,9 @@ func clientCallback(avahiClient *C.AvahiClient,
 	evnt := &ClientEvent{State: state}
 
 	if state == ClientStateFailure {
-		// The very first callback may come too early, even
-		// before C.avahi_client_new returns, so Client.avahiClient
-		// may be not yet initialized at that time...
-		evnt.Err = ErrFailure
-		if clnt.avahiClient != nil {
-			evnt.Err = clnt.errno()
-		}
+		evnt.Err = clnt.errno()
 	}
 
+	clnt.notifyState("Client", clnt, evnt)
 	clnt.queue.Push(evnt)
 }
@@ -56,3 +56,23 @@ func (state ClientState) String() string {
 	}
 	return n
 }
+
+// MarshalText returns a name of the ClientState.
+// It implements the [encoding.TextMarshaler] interface.
+func (state ClientState) MarshalText() ([]byte, error) {
+	return enumMarshalText(state, clientStateNames)
+}
+
+// UnmarshalText decodes ClientState from its name.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (state *ClientState) UnmarshalText(text []byte) error {
+	return enumUnmarshalText("ClientState.UnmarshalText", state, text,
+		clientStateNames)
+}
+
+// UnmarshalJSON decodes ClientState from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (state *ClientState) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("ClientState.UnmarshalJSON", state, data,
+		state.UnmarshalText)
+}
@@ -0,0 +1,137 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Device-info and workstation records
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DeviceInfo contains information, decoded from the "_device-info._tcp"
+// record. See [EntryGroupDeviceInfo] for details.
+type DeviceInfo struct {
+	Model string   // Device model ("model=" key)
+	Txt   []string // Raw TXT record
+}
+
+// LookupDeviceInfo looks up the "_device-info._tcp" record for
+// the given service instance name and domain, as reported by the
+// [ServiceBrowser] or [ServiceResolver]. If domain is "", the default
+// domain is used.
+//
+// It can be used to enrich the discovered services with the device
+// model information, which can be published by the
+// [EntryGroup.AddDeviceInfo]. See also [NewDeviceInfoBrowser], which
+// does it automatically.
+//
+// Errors are reported the same way as by [LookupHost]. In particular,
+// if nobody has answered, [ErrNotFound] is returned.
+func LookupDeviceInfo(ctx context.Context, clnt *Client,
+	instance, domain string) (*DeviceInfo, error) {
+
+	if domain == "" {
+		domain = clnt.GetDomainName()
+	}
+
+	name := DomainServiceNameJoin(instance, entryGroupDeviceInfoType,
+		domain)
+	if name == "" {
+		return nil, newError("LookupDeviceInfo", ErrInvalidServiceName,
+			instance, domain)
+	}
+
+	// Create browser
+	browser, err := NewRecordBrowser(clnt, IfIndexUnspec, ProtocolUnspec,
+		name, DNSClassIN, DNSTypeTXT, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	defer browser.Close()
+
+	// Wait for result
+	for err == nil {
+		var evnt *RecordBrowserEvent
+		evnt, err = browser.Get(ctx)
+
+		switch {
+		case err != nil:
+			// Context canceled or expired; err will be
+			// mapped below
+
+		case evnt == nil:
+			err = ErrBadState
+
+		case evnt.Event == BrowserNew:
+			txt := DNSDecodeTXT(evnt.RData)
+			if txt != nil {
+				return deviceInfoDecode(txt), nil
+			}
+
+		case evnt.Event == BrowserAllForNow:
+			err = ErrNotFound
+
+		case evnt.Event == BrowserFailure:
+			err = evnt.Err
+		}
+	}
+
+	return nil, newError("LookupDeviceInfo", lookupErr(ctx, err),
+		instance, domain)
+}
+
+// WorkstationName returns the "_workstation._tcp" service instance
+// name for the given host name and MAC address, like
+// "myhost [00:11:22:33:44:55]".
+//
+// It returns "" if hostname or MAC address is empty.
+func WorkstationName(hostname string, mac net.HardwareAddr) string {
+	if hostname == "" || len(mac) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s [%s]", hostname, mac)
+}
+
+// ParseWorkstationName parses the "_workstation._tcp" service
+// instance name, as generated by the [WorkstationName] (and by
+// avahi-daemon), into the host name and MAC address.
+func ParseWorkstationName(instance string) (hostname string,
+	mac net.HardwareAddr, ok bool) {
+
+	i := strings.LastIndex(instance, " [")
+	if i <= 0 || !strings.HasSuffix(instance, "]") {
+		return "", nil, false
+	}
+
+	mac, err := net.ParseMAC(instance[i+2 : len(instance)-1])
+	if err != nil {
+		return "", nil, false
+	}
+
+	return instance[:i], mac, true
+}
+
+// deviceInfoDecode decodes DeviceInfo from the TXT record.
+func deviceInfoDecode(txt []string) *DeviceInfo {
+	info := &DeviceInfo{Txt: txt}
+
+	for _, kv := range txt {
+		k, v, _ := strings.Cut(kv, "=")
+		if strings.EqualFold(k, "model") {
+			info.Model = v
+			break
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,153 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Device-info and workstation records test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+// TestWorkstationName tests WorkstationName and ParseWorkstationName
+func TestWorkstationName(t *testing.T) {
+	type testData struct {
+		hostname string
+		mac      net.HardwareAddr
+		instance string
+	}
+
+	tests := []testData{
+		{
+			hostname: "myhost",
+			mac:      net.HardwareAddr{0x00, 0x11, 0x22, 0xaa, 0xbb, 0xcc},
+			instance: "myhost [00:11:22:aa:bb:cc]",
+		},
+
+		{
+			hostname: "my [host]",
+			mac:      net.HardwareAddr{0x00, 0x11, 0x22, 0xaa, 0xbb, 0xcc},
+			instance: "my [host] [00:11:22:aa:bb:cc]",
+		},
+
+		{
+			hostname: "",
+			mac:      net.HardwareAddr{0x00, 0x11, 0x22, 0xaa, 0xbb, 0xcc},
+			instance: "",
+		},
+
+		{
+			hostname: "myhost",
+			mac:      nil,
+			instance: "",
+		},
+	}
+
+	for _, test := range tests {
+		instance := WorkstationName(test.hostname, test.mac)
+		if instance != test.instance {
+			t.Errorf("WorkstationName(%q, %s):\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.hostname, test.mac, test.instance, instance)
+		}
+
+		if test.instance == "" {
+			continue
+		}
+
+		hostname, mac, ok := ParseWorkstationName(test.instance)
+		if !ok || hostname != test.hostname ||
+			!bytes.Equal(mac, test.mac) {
+			t.Errorf("ParseWorkstationName(%q):\n"+
+				"expected: %q %s\n"+
+				"present:  %q %s\n",
+				test.instance, test.hostname, test.mac,
+				hostname, mac)
+		}
+	}
+
+	// Malformed names
+	malformed := []string{
+		"myhost",
+		"[00:11:22:aa:bb:cc]",
+		"myhost [00:11:22:aa:bb]x",
+		"myhost [not a mac]",
+	}
+
+	for _, instance := range malformed {
+		_, _, ok := ParseWorkstationName(instance)
+		if ok {
+			t.Errorf("ParseWorkstationName(%q): error expected",
+				instance)
+		}
+	}
+}
+
+// TestDeviceInfoDecode tests deviceInfoDecode function
+func TestDeviceInfoDecode(t *testing.T) {
+	type testData struct {
+		txt   []string
+		model string
+	}
+
+	tests := []testData{
+		{[]string{"model=Macmini9,1", "osxvers=21"}, "Macmini9,1"},
+		{[]string{"osxvers=21", "Model=RackMac"}, "RackMac"},
+		{[]string{"model=A", "model=B"}, "A"},
+		{[]string{}, ""},
+	}
+
+	for _, test := range tests {
+		info := deviceInfoDecode(test.txt)
+		if info.Model != test.model {
+			t.Errorf("%q:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.txt, test.model, info.Model)
+		}
+	}
+}
+
+// TestWorkstationHost tests entryGroupWorkstationHost
+func TestWorkstationHost(t *testing.T) {
+	type testData struct {
+		hostname string // Input host name
+		short    string // Expected short name
+		target   string // Expected SRV target, "" if error
+	}
+
+	tests := []testData{
+		{"myhost", "myhost", "myhost.local"},
+		{"myhost.local", "myhost", "myhost.local"},
+		{"myhost.example.com.", "myhost", "myhost.example.com."},
+		{`my\.host`, "my.host", `my\.host.local`},
+		{"my..host", "", ""},
+	}
+
+	for _, test := range tests {
+		short, target, err := entryGroupWorkstationHost(
+			test.hostname, "local")
+
+		switch {
+		case test.target == "" && err == nil:
+			t.Errorf("%q: error expected", test.hostname)
+
+		case test.target != "" && err != nil:
+			t.Errorf("%q: %s", test.hostname, err)
+
+		case short != test.short || target != test.target:
+			t.Errorf("%q:\n"+
+				"expected: %q %q\n"+
+				"present:  %q %q\n",
+				test.hostname, test.short, test.target,
+				short, target)
+		}
+	}
+}
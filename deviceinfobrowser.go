@@ -0,0 +1,265 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Service browser with device-info lookup
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DeviceInfoBrowser reports available services of the specified type,
+// like [ServiceBrowser] does, and enriches each discovered service
+// instance with the [DeviceInfo], looked up by the [LookupDeviceInfo].
+//
+// Like [MultiDomainBrowser], DeviceInfoBrowser is not a native Avahi
+// object. It is the helper, that combines ServiceBrowser and
+// device-info lookups together.
+type DeviceInfoBrowser struct {
+	clnt    *Client                                   // Owning Client
+	browser *ServiceBrowser                           // Underlying browser
+	queue   eventqueue[*DeviceInfoBrowserEvent]       // Event queue
+	pending chan *deviceInfoPending                   // Events in progress
+	lock    sync.Mutex                                // Access lock
+	lookups map[deviceInfoInstance]*deviceInfoPending // Lookups in progress
+	ctx     context.Context                           // Canceled on Close
+	cancel  context.CancelFunc                        // Cancels ctx
+	done    sync.WaitGroup                            // Wait for goroutines
+	closed  atomic.Bool                               // Browser is closed
+}
+
+// DeviceInfoBrowserEvent represents events, generated by the
+// [DeviceInfoBrowser].
+type DeviceInfoBrowserEvent struct {
+	ServiceBrowserEvent             // Service browser event
+	DeviceInfo          *DeviceInfo // Device info, nil if not available
+}
+
+// deviceInfoPending represents the DeviceInfoBrowserEvent, that
+// waits for completion of the device-info lookup.
+type deviceInfoPending struct {
+	evnt   *DeviceInfoBrowserEvent // The event
+	inst   deviceInfoInstance      // Service instance
+	done   chan struct{}           // Closed when lookup is completed
+	cancel context.CancelFunc      // Cancels the lookup
+}
+
+// deviceInfoInstance identifies the discovered service instance.
+type deviceInfoInstance struct {
+	ifidx    IfIndex  // Network interface index
+	proto    Protocol // Network protocol
+	instname string   // Instance name
+	svctype  string   // Service type
+	domain   string   // Service domain
+}
+
+// DeviceInfoBrowser parameters:
+const (
+	// deviceInfoLookupTimeout limits duration of the single
+	// device-info lookup.
+	deviceInfoLookupTimeout = 5 * time.Second
+
+	// deviceInfoMaxPending limits count of events, waiting for
+	// completion of the device-info lookup.
+	deviceInfoMaxPending = 16
+)
+
+// NewDeviceInfoBrowser creates a new [DeviceInfoBrowser].
+//
+// Function parameters are the same as for the [NewServiceBrowser].
+//
+// For each [BrowserNew] event DeviceInfoBrowser looks up the
+// "_device-info._tcp" record of the service instance, and reports
+// the event after lookup completion, with the DeviceInfo field set
+// if lookup succeeded. Lookups of different instances run in
+// parallel, but events are reported in the same order, as they
+// come from the ServiceBrowser. Other events are passed through
+// with the nil DeviceInfo.
+//
+// If service instance disappears while its device-info is being
+// looked up, the lookup is canceled, and the [BrowserNew] event
+// is reported without DeviceInfo, followed by the [BrowserRemove]
+// event.
+//
+// DeviceInfoBrowser must be closed after use with the
+// [DeviceInfoBrowser.Close] function call.
+func NewDeviceInfoBrowser(
+	clnt *Client,
+	ifidx IfIndex,
+	proto Protocol,
+	svctype, domain string,
+	flags LookupFlags) (*DeviceInfoBrowser, error) {
+
+	// Create ServiceBrowser
+	sb, err := NewServiceBrowser(clnt, ifidx, proto, svctype, domain, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize DeviceInfoBrowser structure
+	browser := &DeviceInfoBrowser{
+		clnt:    clnt,
+		browser: sb,
+		pending: make(chan *deviceInfoPending, deviceInfoMaxPending),
+		lookups: make(map[deviceInfoInstance]*deviceInfoPending),
+	}
+
+	browser.ctx, browser.cancel = context.WithCancel(context.Background())
+	browser.queue.init()
+
+	browser.done.Add(2)
+	go browser.procServices()
+	go browser.procPending()
+
+	// Register self to be closed if Client is closed
+	clnt.begin()
+	clnt.addCloser(browser)
+	clnt.end()
+
+	// Report object creation
+	if hooks := clnt.hooks(); hooks != nil {
+		hooks.create(context.Background(), browser, &ObjectInfo{
+			Kind:     "DeviceInfoBrowser",
+			IfIdx:    ifidx,
+			Proto:    proto,
+			SvcType:  svctype,
+			Domain:   domain,
+			Flags:    flags,
+			QueueLen: browser.queue.Len,
+		})
+	}
+
+	return browser, nil
+}
+
+// Chan returns channel where [DeviceInfoBrowserEvent]s are sent.
+func (browser *DeviceInfoBrowser) Chan() <-chan *DeviceInfoBrowserEvent {
+	return browser.queue.Chan()
+}
+
+// Get waits for the next [DeviceInfoBrowserEvent].
+//
+// It returns:
+//   - event, nil - if event available
+//   - nil, error - if context is canceled
+//   - nil, nil   - if DeviceInfoBrowser was closed
+func (browser *DeviceInfoBrowser) Get(ctx context.Context) (
+	*DeviceInfoBrowserEvent, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case evnt := <-browser.Chan():
+		return evnt, nil
+	}
+}
+
+// Close closes the [DeviceInfoBrowser] and releases allocated resources.
+// It closes the event channel, effectively unblocking pending readers.
+//
+// Note, double close is safe.
+func (browser *DeviceInfoBrowser) Close() {
+	if !browser.closed.Swap(true) {
+		browser.clnt.notifyClose("DeviceInfoBrowser", browser)
+
+		browser.clnt.begin()
+		browser.clnt.delCloser(browser)
+		browser.clnt.end()
+
+		browser.cancel()
+		browser.browser.Close()
+
+		browser.done.Wait()
+		browser.queue.Close()
+	}
+}
+
+// procServices runs in its own goroutine, handles ServiceBrowser
+// events and starts device-info lookups.
+func (browser *DeviceInfoBrowser) procServices() {
+	defer browser.done.Done()
+	defer close(browser.pending)
+
+	for evnt := range browser.browser.Chan() {
+		item := &deviceInfoPending{
+			evnt: &DeviceInfoBrowserEvent{ServiceBrowserEvent: *evnt},
+			inst: deviceInfoInstance{
+				ifidx:    evnt.IfIdx,
+				proto:    evnt.Proto,
+				instname: evnt.InstanceName,
+				svctype:  evnt.SvcType,
+				domain:   evnt.Domain,
+			},
+			done: make(chan struct{}),
+		}
+
+		switch evnt.Event {
+		case BrowserNew:
+			var ctx context.Context
+			ctx, item.cancel = context.WithTimeout(browser.ctx,
+				deviceInfoLookupTimeout)
+
+			browser.lock.Lock()
+			browser.lookups[item.inst] = item
+			browser.lock.Unlock()
+
+			browser.done.Add(1)
+			go browser.lookup(ctx, item)
+
+		case BrowserRemove:
+			browser.lock.Lock()
+			if lookup := browser.lookups[item.inst]; lookup != nil {
+				lookup.cancel()
+				delete(browser.lookups, item.inst)
+			}
+			browser.lock.Unlock()
+			close(item.done)
+
+		default:
+			close(item.done)
+		}
+
+		browser.pending <- item
+	}
+}
+
+// procPending runs in its own goroutine and reports events,
+// in order, as their device-info lookups complete.
+func (browser *DeviceInfoBrowser) procPending() {
+	defer browser.done.Done()
+
+	for item := range browser.pending {
+		<-item.done
+		browser.queue.Push(item.evnt)
+	}
+}
+
+// lookup runs in its own goroutine and looks up the device-info
+// for the BrowserNew event. The lookup is canceled if service
+// instance disappears or DeviceInfoBrowser is closed.
+func (browser *DeviceInfoBrowser) lookup(ctx context.Context,
+	item *deviceInfoPending) {
+
+	defer browser.done.Done()
+	defer close(item.done)
+	defer item.cancel()
+
+	info, err := LookupDeviceInfo(ctx, browser.clnt,
+		item.evnt.InstanceName, item.evnt.Domain)
+	if err == nil {
+		item.evnt.DeviceInfo = info
+	}
+
+	browser.lock.Lock()
+	if browser.lookups[item.inst] == item {
+		delete(browser.lookups, item.inst)
+	}
+	browser.lock.Unlock()
+}
@@ -0,0 +1,123 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Service browser with device-info lookup test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDeviceInfoBrowser tests DeviceInfoBrowser with the replay Client
+//
+// The capture contains the ServiceBrowser, that reports three
+// instances:
+//   - "Printer" has the device-info record
+//   - "Fax" has no device-info record
+//   - "Scanner" disappears while its device-info is being looked up
+func TestDeviceInfoBrowser(t *testing.T) {
+	capture := strings.Join([]string{
+		`{"time":"2024-01-01T00:00:00Z","client":{"state":"running","hostname":"host","domain":"local","fqdn":"host.local","version":"avahi 0.8"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"ServiceBrowser","created":{"Kind":"ServiceBrowser","IfIdx":-1,"Proto":"unspec","SvcType":"_ipp._tcp"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"ServiceBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Flags":"mdns","InstanceName":"Printer","SvcType":"_ipp._tcp","Domain":"local"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"ServiceBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Flags":"mdns","InstanceName":"Scanner","SvcType":"_ipp._tcp","Domain":"local"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"ServiceBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Flags":"mdns","InstanceName":"Fax","SvcType":"_ipp._tcp","Domain":"local"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"ServiceBrowser","event":{"Event":"BrowserRemove","IfIdx":2,"Proto":"ip4","InstanceName":"Scanner","SvcType":"_ipp._tcp","Domain":"local"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"ServiceBrowser","event":{"Event":"BrowserAllForNow","IfIdx":-1,"Proto":"unspec"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"RecordBrowser","created":{"Kind":"RecordBrowser","IfIdx":-1,"Proto":"unspec","Name":"Printer._device-info._tcp.local","RClass":"IN","RType":"TXT"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"RecordBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Flags":"mdns","Name":"Printer._device-info._tcp.local","RClass":"IN","RType":"TXT","RData":"D21vZGVsPU1hY1BybzcsMQ=="}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":3,"kind":"RecordBrowser","created":{"Kind":"RecordBrowser","IfIdx":-1,"Proto":"unspec","Name":"Fax._device-info._tcp.local","RClass":"IN","RType":"TXT"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":3,"kind":"RecordBrowser","event":{"Event":"BrowserAllForNow","IfIdx":-1,"Proto":"unspec"}}`,
+	}, "\n") + "\n"
+
+	clnt, err := NewReplayClient(strings.NewReader(capture), 0)
+	if err != nil {
+		t.Fatalf("NewReplayClient: %s", err)
+	}
+
+	defer clnt.Close()
+
+	browser, err := NewDeviceInfoBrowser(clnt, IfIndexUnspec,
+		ProtocolUnspec, "_ipp._tcp", "", 0)
+	if err != nil {
+		t.Fatalf("NewDeviceInfoBrowser: %s", err)
+	}
+
+	defer browser.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	event := func(e BrowserEvent, instance string,
+		info *DeviceInfo) *DeviceInfoBrowserEvent {
+
+		evnt := &DeviceInfoBrowserEvent{
+			ServiceBrowserEvent: ServiceBrowserEvent{
+				Event:        e,
+				IfIdx:        2,
+				Proto:        ProtocolIP4,
+				InstanceName: instance,
+				SvcType:      "_ipp._tcp",
+				Domain:       "local",
+			},
+			DeviceInfo: info,
+		}
+
+		if e == BrowserNew {
+			evnt.Flags = LookupResultMulticast
+		}
+
+		return evnt
+	}
+
+	expected := []*DeviceInfoBrowserEvent{
+		event(BrowserNew, "Printer", &DeviceInfo{
+			Model: "MacPro7,1",
+			Txt:   []string{"model=MacPro7,1"},
+		}),
+		event(BrowserNew, "Scanner", nil),
+		event(BrowserNew, "Fax", nil),
+		event(BrowserRemove, "Scanner", nil),
+		{
+			ServiceBrowserEvent: ServiceBrowserEvent{
+				Event: BrowserAllForNow,
+				IfIdx: IfIndexUnspec,
+				Proto: ProtocolUnspec,
+			},
+		},
+	}
+
+	for _, exp := range expected {
+		evnt, err := browser.Get(ctx)
+		if !reflect.DeepEqual(evnt, exp) {
+			t.Errorf("DeviceInfoBrowser.Get:\n"+
+				"expected: %+v\n"+
+				"present:  %+v (%v)\n",
+				exp, evnt, err)
+		}
+	}
+
+	// Completed lookups must be forgotten
+	browser.lock.Lock()
+	n := len(browser.lookups)
+	browser.lock.Unlock()
+
+	if n != 0 {
+		t.Errorf("DeviceInfoBrowser: %d lookups not forgotten", n)
+	}
+
+	// Nothing must be reported after Close
+	browser.Close()
+	for evnt := range browser.Chan() {
+		t.Errorf("DeviceInfoBrowser.Get: unexpected %+v", evnt)
+	}
+}
@@ -0,0 +1,504 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Dialer for MDNS hosts and DNS-SD service instances
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Happy Eyeballs (RFC 8305) parameters
+const (
+	// Time to wait for the IPv6 addresses, after the first
+	// IPv4 address has been resolved (RFC 8305, 3).
+	dialerResolutionDelay = 50 * time.Millisecond
+
+	// Delay between connection attempts (RFC 8305, 5),
+	// unless overridden by the net.Dialer.FallbackDelay.
+	dialerAttemptDelay = 250 * time.Millisecond
+)
+
+// Dialer connects to the DNS-SD service instances and MDNS hosts
+// (i.e., "printer.local").
+//
+// Dialer resolves names using the [Client], and then attempts to
+// connect to all resolved addresses in the "Happy Eyeballs" order,
+// as defined by [RFC 8305]: addresses of different families are
+// interleaved, IPv6 goes first, and the next connection attempt
+// is started if previous attempt fails or doesn't complete in
+// 250 milliseconds.
+//
+// Link-local IPv6 addresses come with zone, as described in the
+// "IP addresses" section of the package Overview, so they can be
+// dialed without any additional effort.
+//
+// [RFC 8305]: https://datatracker.ietf.org/doc/html/rfc8305
+type Dialer struct {
+	// Client used for names resolving. Must not be nil.
+	Client *Client
+
+	// NetDialer is used to establish connections to the resolved
+	// addresses. Its zero value is perfectly usable.
+	//
+	// If NetDialer.FallbackDelay is positive, it is used as a delay
+	// between connection attempts, instead of the default 250
+	// milliseconds.
+	NetDialer net.Dialer
+
+	// Cache, if not nil, is used to cache resolved host names
+	// and service instances. It must use the same Client.
+	Cache *Cache
+}
+
+// dialerService contains the resolved service parameters.
+type dialerService struct {
+	hostname string       // Resolved hostname
+	port     uint16       // Resolved port
+	addrs    []netip.Addr // Resolved addresses
+	txt      []string     // TXT record, if requested
+}
+
+// dialerServiceKey is the context.Context key for the *dialerService,
+// already resolved by the caller of the Dialer.DialContext.
+type dialerServiceKey struct{}
+
+// dialerResult is the result of the single connection attempt.
+type dialerResult struct {
+	conn net.Conn // Connection on success
+	err  error    // Error on failure
+}
+
+// NewDialer creates a new [Dialer].
+func NewDialer(clnt *Client) *Dialer {
+	return &Dialer{Client: clnt}
+}
+
+// DialService resolves the DNS-SD service instance and connects
+// to it.
+//
+// Parameters:
+//   - network must be one of "tcp", "tcp4", "tcp6", "udp", "udp4"
+//     or "udp6". Network also defines the address families the
+//     instance will be resolved to.
+//   - instance, svctype and domain identify the service instance, the
+//     same way as in the [NewServiceResolver] function. Typically,
+//     they come from the [ServiceBrowserEvent].
+//
+// Service instances, registered with the zero port (placeholders, see
+// [NewServiceResolver] for details), cannot be connected; for them
+// [ErrInvalidPort] is returned.
+func (d *Dialer) DialService(ctx context.Context, network,
+	instance, svctype, domain string) (net.Conn, error) {
+
+	want4, want6, err := dialerNetwork(network)
+	if err != nil {
+		return nil, newError("Dialer.DialService", err,
+			instance, svctype, domain)
+	}
+
+	svc, err := d.resolveService(ctx, instance, svctype, domain,
+		want4, want6)
+	if err != nil {
+		return nil, newError("Dialer.DialService", err,
+			instance, svctype, domain)
+	}
+
+	return d.dialAddrs(ctx, network, svc.addrs, svc.port)
+}
+
+// DialContext connects to the address on the named network.
+//
+// This function has the same semantics, as the [net.Dialer.DialContext]
+// and can be used as a drop-in replacement of the later, for example,
+// as the DialContext hook of the [http.Transport].
+//
+// If the host part of the address is the MDNS name (i.e., belongs
+// to the "local" domain or to the domain returned by the
+// [Client.GetDomainName]), it is resolved, like [LookupHost] does
+// (or using the [Cache.LookupHost], if Dialer.Cache is set), and
+// resolved addresses are connected in the Happy Eyeballs order.
+// Like in the [Dialer.DialService], resolver doesn't wait for the
+// slow address family longer than RFC 8305 recommends.
+// Otherwise, the request is passed to the Dialer.NetDialer as is.
+func (d *Dialer) DialContext(ctx context.Context,
+	network, address string) (net.Conn, error) {
+
+	want4, want6, err := dialerNetwork(network)
+	if err != nil {
+		return d.NetDialer.DialContext(ctx, network, address)
+	}
+
+	host, service, err := net.SplitHostPort(address)
+	if err != nil {
+		return d.NetDialer.DialContext(ctx, network, address)
+	}
+
+	// The host may be already resolved by the ServiceResolver
+	svc, _ := ctx.Value(dialerServiceKey{}).(*dialerService)
+	if svc != nil && !strcaseequal(svc.hostname, host) {
+		svc = nil
+	}
+
+	if svc == nil && !d.isMDNSHost(host) {
+		return d.NetDialer.DialContext(ctx, network, address)
+	}
+
+	port, err := net.DefaultResolver.LookupPort(ctx, network, service)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []netip.Addr
+	if svc != nil {
+		addrs = svc.addrs
+	} else {
+		addrs, err = d.lookupHost(ctx, host)
+		if err != nil {
+			return nil, newError("Dialer.DialContext", err, address)
+		}
+	}
+
+	addrs = dialerFilterAddrs(addrs, want4, want6)
+	if len(addrs) == 0 {
+		return nil, newError("Dialer.DialContext", ErrNotFound,
+			address)
+	}
+
+	return d.dialAddrs(ctx, network, addrs, uint16(port))
+}
+
+// isMDNSHost reports if host is the MDNS name.
+func (d *Dialer) isMDNSHost(host string) bool {
+	if _, err := netip.ParseAddr(host); err == nil {
+		return false
+	}
+
+	host = strings.TrimSuffix(host, ".")
+
+	for _, domain := range []string{"local", d.Client.GetDomainName()} {
+		if domain == "" {
+			continue
+		}
+
+		if len(host) > len(domain)+1 &&
+			host[len(host)-len(domain)-1] == '.' &&
+			strcaseequal(host[len(host)-len(domain):], domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookupHost resolves MDNS host name, using the Dialer.Cache,
+// if available.
+//
+// Without Cache, once the first address is resolved, it waits for
+// other addresses for the RFC 8305 resolution delay only, so the
+// host without IPv6 (or IPv4) addresses is not resolved for
+// the whole avahi-daemon resolver timeout.
+func (d *Dialer) lookupHost(ctx context.Context,
+	host string) ([]netip.Addr, error) {
+
+	if d.Cache != nil {
+		return d.Cache.LookupHost(ctx, host)
+	}
+
+	return lookupHost(ctx, d.Client, host, dialerResolutionDelay)
+}
+
+// resolveService resolves service instance into the hostname,
+// port and set of addresses, using the Dialer.Cache, if available.
+//
+// want4 and want6 specify the address families of interest. If
+// no addresses of these families are known, [ErrNotFound] is returned.
+//
+// Like other internal helpers, it doesn't wrap errors; callers
+// wrap them with the name of the public operation.
+func (d *Dialer) resolveService(ctx context.Context,
+	instance, svctype, domain string,
+	want4, want6 bool) (*dialerService, error) {
+
+	if d.Cache == nil {
+		return resolveService(ctx, d.Client, instance, svctype,
+			domain, want4, want6, LookupNoTXT)
+	}
+
+	svc, err := d.Cache.resolveService(ctx, instance, svctype, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := dialerFilterAddrs(svc.addrs, want4, want6)
+	if len(addrs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &dialerService{
+		hostname: svc.hostname,
+		port:     svc.port,
+		addrs:    addrs,
+	}, nil
+}
+
+// resolveService resolves service instance into the hostname,
+// port, set of addresses and, unless flags contain LookupNoTXT,
+// the TXT record.
+//
+// want4 and want6 specify the address families of interest.
+func resolveService(ctx context.Context, clnt *Client,
+	instance, svctype, domain string,
+	want4, want6 bool, flags LookupFlags) (*dialerService, error) {
+
+	// Create resolvers, one per address family
+	var chan4, chan6 <-chan *ServiceResolverEvent
+	resolvers := make([]*ServiceResolver, 0, 2)
+	defer func() {
+		for _, resolver := range resolvers {
+			resolver.Close()
+		}
+	}()
+
+	for _, addrproto := range []Protocol{ProtocolIP4, ProtocolIP6} {
+		if (addrproto == ProtocolIP4 && !want4) ||
+			(addrproto == ProtocolIP6 && !want6) {
+			continue
+		}
+
+		resolver, err := NewServiceResolverContext(ctx, clnt,
+			IfIndexUnspec, ProtocolUnspec, instance, svctype,
+			domain, addrproto, flags)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resolvers = append(resolvers, resolver)
+		if addrproto == ProtocolIP4 {
+			chan4 = resolver.Chan()
+		} else {
+			chan6 = resolver.Chan()
+		}
+	}
+
+	// Wait for results. Once the first address is resolved, wait
+	// a bit for more addresses of the same and another address
+	// family, as RFC 8305 recommends, but don't wait for the slow
+	// resolver forever.
+	svc := &dialerService{}
+	var delay <-chan time.Time
+	var err error
+
+	for (chan4 != nil || chan6 != nil) && ctx.Err() == nil {
+		var evnt *ServiceResolverEvent
+		var from *<-chan *ServiceResolverEvent
+
+		select {
+		case <-ctx.Done():
+			continue
+		case <-delay:
+			chan4, chan6 = nil, nil
+			continue
+		case evnt = <-chan4:
+			from = &chan4
+		case evnt = <-chan6:
+			from = &chan6
+		}
+
+		// Multi-homed instances may have many addresses of the
+		// same family, so keep reading the resolver until the
+		// delay expires, unless it is done.
+		if evnt == nil || evnt.Event != ResolverFound || evnt.Port == 0 {
+			*from = nil
+		}
+
+		switch {
+		case evnt == nil:
+			err = ErrBadState
+
+		case evnt.Event == ResolverFound:
+			if evnt.Port == 0 {
+				err = ErrInvalidPort
+				break
+			}
+
+			if svc.hostname == "" {
+				svc.hostname = evnt.Hostname
+				svc.port = evnt.Port
+				svc.txt = evnt.Txt
+			}
+
+			svc.addrs = lookupAppendAddr(svc.addrs, evnt.Addr)
+			if delay == nil {
+				delay = time.After(dialerResolutionDelay)
+			}
+
+		case evnt.Event == ResolverFailure && err == nil:
+			err = evnt.Err
+		}
+	}
+
+	if len(svc.addrs) == 0 {
+		if err == ErrInvalidPort {
+			return nil, err
+		}
+		return nil, lookupErr(ctx, err)
+	}
+
+	svc.addrs = dialerSortAddrs(svc.addrs)
+	return svc, nil
+}
+
+// dialAddrs connects to the first reachable address, using the
+// Happy Eyeballs algorithm. Addresses must be already sorted.
+func (d *Dialer) dialAddrs(ctx context.Context, network string,
+	addrs []netip.Addr, port uint16) (net.Conn, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	delay := d.NetDialer.FallbackDelay
+	if delay <= 0 {
+		delay = dialerAttemptDelay
+	}
+
+	results := make(chan dialerResult, len(addrs))
+	next, pending := 0, 0
+
+	var timer *time.Timer
+	var timeout <-chan time.Time
+
+	start := func() {
+		addr := netip.AddrPortFrom(addrs[next], port).String()
+		next++
+		pending++
+
+		go func() {
+			conn, err := d.NetDialer.DialContext(ctx, network, addr)
+			results <- dialerResult{conn, err}
+		}()
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		timer = time.NewTimer(delay)
+		timeout = timer.C
+		if next == len(addrs) {
+			timeout = nil
+		}
+	}
+
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	start()
+
+	var err error
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				// Close connections that may still complete
+				go dialerDrain(results, pending)
+				return r.conn, nil
+			}
+
+			if err == nil {
+				err = r.err
+			}
+
+			if next < len(addrs) {
+				start()
+			}
+
+		case <-timeout:
+			start()
+		}
+	}
+
+	return nil, err
+}
+
+// dialerDrain receives n pending results of the losing connection
+// attempts and closes connections, if any.
+func dialerDrain(results <-chan dialerResult, n int) {
+	for ; n > 0; n-- {
+		r := <-results
+		if r.conn != nil {
+			r.conn.Close()
+		}
+	}
+}
+
+// dialerNetwork checks the network parameter and returns the
+// address families that the network permits.
+func dialerNetwork(network string) (want4, want6 bool, err error) {
+	switch network {
+	case "tcp", "udp":
+		return true, true, nil
+	case "tcp4", "udp4":
+		return true, false, nil
+	case "tcp6", "udp6":
+		return false, true, nil
+	}
+
+	return false, false, net.UnknownNetworkError(network)
+}
+
+// dialerFilterAddrs leaves only addresses of the specified families
+// and sorts them in the Happy Eyeballs order.
+func dialerFilterAddrs(addrs []netip.Addr, want4, want6 bool) []netip.Addr {
+	filtered := make([]netip.Addr, 0, len(addrs))
+	for _, addr := range addrs {
+		if (addr.Is4() && want4) || (addr.Is6() && want6) {
+			filtered = append(filtered, addr)
+		}
+	}
+
+	return dialerSortAddrs(filtered)
+}
+
+// dialerSortAddrs sorts addresses in the Happy Eyeballs order,
+// by interleaving address families, starting from IPv6
+// (RFC 8305, 4).
+//
+// Relative order of addresses of the same family is preserved.
+func dialerSortAddrs(addrs []netip.Addr) []netip.Addr {
+	var addrs4, addrs6 []netip.Addr
+	for _, addr := range addrs {
+		if addr.Is4() {
+			addrs4 = append(addrs4, addr)
+		} else {
+			addrs6 = append(addrs6, addr)
+		}
+	}
+
+	sorted := make([]netip.Addr, 0, len(addrs))
+	for len(addrs4) > 0 || len(addrs6) > 0 {
+		if len(addrs6) > 0 {
+			sorted = append(sorted, addrs6[0])
+			addrs6 = addrs6[1:]
+		}
+		if len(addrs4) > 0 {
+			sorted = append(sorted, addrs4[0])
+			addrs4 = addrs4[1:]
+		}
+	}
+
+	return sorted
+}
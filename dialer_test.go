@@ -0,0 +1,142 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Dialer test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDialerSortAddrs tests dialerSortAddrs function
+func TestDialerSortAddrs(t *testing.T) {
+	in := []netip.Addr{
+		netip.MustParseAddr("192.168.0.1"),
+		netip.MustParseAddr("192.168.0.2"),
+		netip.MustParseAddr("192.168.0.3"),
+		netip.MustParseAddr("fe80::1"),
+		netip.MustParseAddr("fe80::2"),
+	}
+
+	expected := []netip.Addr{
+		netip.MustParseAddr("fe80::1"),
+		netip.MustParseAddr("192.168.0.1"),
+		netip.MustParseAddr("fe80::2"),
+		netip.MustParseAddr("192.168.0.2"),
+		netip.MustParseAddr("192.168.0.3"),
+	}
+
+	out := dialerSortAddrs(in)
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("dialerSortAddrs:\n"+
+			"expected: %s\n"+
+			"present:  %s\n",
+			expected, out)
+	}
+}
+
+// TestDialerDialAddrs tests Dialer.dialAddrs
+func TestDialerDialAddrs(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("net.Listen: %s", err)
+	}
+	defer l.Close()
+
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+
+	// Obtain a port that nobody listens
+	dead, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("net.Listen: %s", err)
+	}
+	dead.Close()
+
+	// 127.0.0.2 is not listened, so the first attempt must
+	// fail and the second must succeed
+	addrs := []netip.Addr{
+		netip.MustParseAddr("127.0.0.2"),
+		netip.MustParseAddr("127.0.0.1"),
+	}
+
+	d := &Dialer{}
+	conn, err := d.dialAddrs(context.Background(), "tcp", addrs, port)
+	if err != nil {
+		t.Errorf("Dialer.dialAddrs: %s", err)
+		return
+	}
+	conn.Close()
+
+	// Now all attempts must fail
+	addrs = addrs[1:]
+	dport := uint16(dead.Addr().(*net.TCPAddr).Port)
+	_, err = d.dialAddrs(context.Background(), "tcp", addrs, dport)
+	if err == nil {
+		t.Errorf("Dialer.dialAddrs: error expected")
+	}
+}
+
+// TestDialerResolve tests names resolving by the Dialer: only
+// IPv4 addresses are resolved, IPv6 resolvers never answer, and
+// all addresses of the multi-homed host are collected.
+func TestDialerResolve(t *testing.T) {
+	capture := strings.Join([]string{
+		`{"time":"2024-01-01T00:00:00Z","client":{"state":"running","hostname":"host","domain":"local","fqdn":"host.local","version":"avahi 0.8"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"HostNameResolver","created":{"Kind":"HostNameResolver","IfIdx":-1,"Proto":"unspec","Name":"printer.local","AddrProto":"ip4"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"HostNameResolver","event":{"Event":"ResolverFound","IfIdx":2,"Proto":"ip4","Hostname":"printer.local","Addr":"192.168.0.1"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"HostNameResolver","event":{"Event":"ResolverFound","IfIdx":3,"Proto":"ip4","Hostname":"printer.local","Addr":"10.0.0.1"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"ServiceResolver","created":{"Kind":"ServiceResolver","IfIdx":-1,"Proto":"unspec","Name":"Printer","SvcType":"_ipp._tcp","Domain":"local","AddrProto":"ip4","Flags":"no-txt"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"ServiceResolver","event":{"Event":"ResolverFound","IfIdx":2,"Proto":"ip4","InstanceName":"Printer","SvcType":"_ipp._tcp","Domain":"local","Hostname":"printer.local","Port":631,"Addr":"192.168.0.1"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"ServiceResolver","event":{"Event":"ResolverFound","IfIdx":3,"Proto":"ip4","InstanceName":"Printer","SvcType":"_ipp._tcp","Domain":"local","Hostname":"printer.local","Port":631,"Addr":"10.0.0.1"}}`,
+	}, "\n") + "\n"
+
+	clnt, err := NewReplayClient(strings.NewReader(capture), 0)
+	if err != nil {
+		t.Fatalf("NewReplayClient: %s", err)
+	}
+
+	defer clnt.Close()
+
+	// Without delay, lookup must complete long before the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	d := NewDialer(clnt)
+	expected := []netip.Addr{
+		netip.MustParseAddr("192.168.0.1"),
+		netip.MustParseAddr("10.0.0.1"),
+	}
+
+	addrs, err := d.lookupHost(ctx, "printer.local")
+	if err != nil || !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("Dialer.lookupHost:\n"+
+			"expected: %v\n"+
+			"present:  %v (%v)\n",
+			expected, addrs, err)
+	}
+
+	svc, err := d.resolveService(ctx, "Printer", "_ipp._tcp", "local",
+		true, true)
+	if err != nil || !reflect.DeepEqual(svc.addrs, expected) ||
+		svc.port != 631 {
+		t.Errorf("Dialer.resolveService:\n"+
+			"expected: %v\n"+
+			"present:  %+v (%v)\n",
+			expected, svc, err)
+	}
+
+	if ctx.Err() != nil {
+		t.Errorf("Dialer: resolution delay not applied")
+	}
+}
@@ -9,7 +9,10 @@
 
 package avahi
 
-import "net/netip"
+import (
+	"fmt"
+	"net/netip"
+)
 
 // DNSClass represents a DNS record class. See [RFC1035, 3.2.4.] for details.
 //
@@ -21,6 +24,44 @@ const (
 	DNSClassIN DNSClass = 1
 )
 
+// dnsClassNames contains names for known DNSClass values.
+var dnsClassNames = map[DNSClass]string{
+	DNSClassIN: "IN",
+}
+
+// String returns a name of the DNSClass.
+//
+// Unknown classes are named as defined in [RFC3597, 5.] (i.e., "CLASS5").
+//
+// [RFC3597, 5.]: https://datatracker.ietf.org/doc/html/rfc3597#section-5
+func (class DNSClass) String() string {
+	n := dnsClassNames[class]
+	if n == "" {
+		n = fmt.Sprintf("CLASS%d", int(class))
+	}
+	return n
+}
+
+// MarshalText returns a name of the DNSClass.
+// It implements the [encoding.TextMarshaler] interface.
+func (class DNSClass) MarshalText() ([]byte, error) {
+	return enumMarshalText(class, dnsClassNames)
+}
+
+// UnmarshalText decodes DNSClass from its name.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (class *DNSClass) UnmarshalText(text []byte) error {
+	return enumUnmarshalText("DNSClass.UnmarshalText", class, text,
+		dnsClassNames)
+}
+
+// UnmarshalJSON decodes DNSClass from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (class *DNSClass) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("DNSClass.UnmarshalJSON", class, data,
+		class.UnmarshalText)
+}
+
 // DNSType represents a DNS record type.
 //
 // For details, see:
@@ -48,6 +89,53 @@ const (
 	DNSTypeSRV   DNSType = 33 // Service record (RFC2782)
 )
 
+// dnsTypeNames contains names for known DNSType values.
+var dnsTypeNames = map[DNSType]string{
+	DNSTypeA:     "A",
+	DNSTypeNS:    "NS",
+	DNSTypeCNAME: "CNAME",
+	DNSTypeSOA:   "SOA",
+	DNSTypePTR:   "PTR",
+	DNSTypeHINFO: "HINFO",
+	DNSTypeMX:    "MX",
+	DNSTypeTXT:   "TXT",
+	DNSTypeAAAA:  "AAAA",
+	DNSTypeSRV:   "SRV",
+}
+
+// String returns a name of the DNSType.
+//
+// Unknown types are named as defined in [RFC3597, 5.] (i.e., "TYPE65").
+//
+// [RFC3597, 5.]: https://datatracker.ietf.org/doc/html/rfc3597#section-5
+func (t DNSType) String() string {
+	n := dnsTypeNames[t]
+	if n == "" {
+		n = fmt.Sprintf("TYPE%d", int(t))
+	}
+	return n
+}
+
+// MarshalText returns a name of the DNSType.
+// It implements the [encoding.TextMarshaler] interface.
+func (t DNSType) MarshalText() ([]byte, error) {
+	return enumMarshalText(t, dnsTypeNames)
+}
+
+// UnmarshalText decodes DNSType from its name.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (t *DNSType) UnmarshalText(text []byte) error {
+	return enumUnmarshalText("DNSType.UnmarshalText", t, text,
+		dnsTypeNames)
+}
+
+// UnmarshalJSON decodes DNSType from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (t *DNSType) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("DNSType.UnmarshalJSON", t, data,
+		t.UnmarshalText)
+}
+
 // DNSDecodeA decodes A type resource record.
 //
 // It returns a real IPv4 (not IPv6-encoded IPv4) address.
@@ -102,3 +190,100 @@ func DNSDecodeTXT(rdata []byte) []string {
 
 	return txt
 }
+
+// DNSDecodePTR decodes PTR type resource record.
+//
+// The domain name is returned in the escaped form, suitable for
+// [DomainSlice]. Name compression is not supported, as RData,
+// reported by Avahi, is always uncompressed.
+//
+// [RecordBrowserEvent].RData can be used as input.
+// Errors reported by returning empty string.
+func DNSDecodePTR(rdata []byte) string {
+	labels := []string{}
+	total := 0
+
+	for len(rdata) > 0 {
+		// Extract size of the next label
+		sz := int(rdata[0])
+		rdata = rdata[1:]
+		total += sz + 1
+
+		switch {
+		case sz == 0:
+			// Root label must be the last one
+			if len(rdata) != 0 || len(labels) == 0 {
+				return ""
+			}
+			return DomainFrom(labels)
+
+		case sz > 63 || sz > len(rdata) || total > 255:
+			return ""
+		}
+
+		labels = append(labels, string(rdata[:sz]))
+		rdata = rdata[sz:]
+	}
+
+	// Missed root label
+	return ""
+}
+
+// DNSEncodePTR encodes PTR type resource record.
+//
+// The domain name is expected in the escaped form, as
+// returned by [DomainFrom].
+//
+// The returned data can be used as [EntryGroupRecord].RData.
+// Errors reported by returning nil slice.
+func DNSEncodePTR(name string) []byte {
+	labels := DomainSlice(name)
+	if len(labels) == 0 {
+		return nil
+	}
+
+	rdata := []byte{}
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 {
+			return nil
+		}
+
+		rdata = append(rdata, byte(len(label)))
+		rdata = append(rdata, label...)
+	}
+
+	rdata = append(rdata, 0)
+	if len(rdata) > 255 {
+		return nil
+	}
+
+	return rdata
+}
+
+// DNSEncodeTXT encodes TXT type resource record.
+//
+// According to [RFC6763, 6.1.], the empty TXT record is encoded
+// as a single empty string.
+//
+// The returned data can be used as [EntryGroupRecord].RData.
+// Errors (string longer that 255 bytes) reported by returning
+// nil slice.
+//
+// [RFC6763, 6.1.]: https://datatracker.ietf.org/doc/html/rfc6763#section-6.1
+func DNSEncodeTXT(txt []string) []byte {
+	if len(txt) == 0 {
+		return []byte{0}
+	}
+
+	rdata := []byte{}
+	for _, s := range txt {
+		if len(s) > 255 {
+			return nil
+		}
+
+		rdata = append(rdata, byte(len(s)))
+		rdata = append(rdata, s...)
+	}
+
+	return rdata
+}
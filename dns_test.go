@@ -0,0 +1,172 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// DNS records encoding/decoding test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// TestDNSPTR tests DNSEncodePTR and DNSDecodePTR functions
+func TestDNSPTR(t *testing.T) {
+	type testData struct {
+		name  string
+		rdata []byte
+	}
+
+	tests := []testData{
+		{
+			name:  `local`,
+			rdata: []byte("\x05local\x00"),
+		},
+
+		{
+			name:  `example.com`,
+			rdata: []byte("\x07example\x03com\x00"),
+		},
+
+		{
+			name:  `My\.Printer._ipp._tcp.local`,
+			rdata: []byte("\x0aMy.Printer\x04_ipp\x04_tcp\x05local\x00"),
+		},
+
+		{
+			name:  `ex\?ample.com`,
+			rdata: nil,
+		},
+
+		{
+			name:  ``,
+			rdata: nil,
+		},
+	}
+
+	for _, test := range tests {
+		rdata := DNSEncodePTR(test.name)
+		if !bytes.Equal(rdata, test.rdata) {
+			t.Errorf("DNSEncodePTR(%q):\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.name, test.rdata, rdata)
+		}
+
+		if test.rdata == nil {
+			continue
+		}
+
+		name := DNSDecodePTR(test.rdata)
+		if name != test.name {
+			t.Errorf("DNSDecodePTR(%q):\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.rdata, test.name, name)
+		}
+	}
+
+	// Malformed data
+	malformed := [][]byte{
+		[]byte("\x05local"),
+		[]byte("\x06local\x00"),
+		[]byte("\x05local\x00\x00"),
+		[]byte("\x00"),
+		{},
+	}
+
+	for _, rdata := range malformed {
+		name := DNSDecodePTR(rdata)
+		if name != "" {
+			t.Errorf("DNSDecodePTR(%q): error expected, got %q",
+				rdata, name)
+		}
+	}
+}
+
+// TestDNSTXT tests DNSEncodeTXT and DNSDecodeTXT functions
+func TestDNSTXT(t *testing.T) {
+	type testData struct {
+		txt   []string
+		rdata []byte
+	}
+
+	long := string(make([]byte, 256))
+
+	tests := []testData{
+		{
+			txt:   []string{},
+			rdata: []byte("\x00"),
+		},
+
+		{
+			txt:   []string{"model=Macmini9,1"},
+			rdata: []byte("\x10model=Macmini9,1"),
+		},
+
+		{
+			txt:   []string{"txtvers=1", "rp=ipp/print"},
+			rdata: []byte("\x09txtvers=1\x0crp=ipp/print"),
+		},
+
+		{
+			txt:   []string{long},
+			rdata: nil,
+		},
+	}
+
+	for _, test := range tests {
+		rdata := DNSEncodeTXT(test.txt)
+		if !bytes.Equal(rdata, test.rdata) {
+			t.Errorf("DNSEncodeTXT(%q):\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.txt, test.rdata, rdata)
+		}
+
+		if test.rdata == nil {
+			continue
+		}
+
+		txt := DNSDecodeTXT(test.rdata)
+		if !reflect.DeepEqual(txt, test.txt) {
+			t.Errorf("DNSDecodeTXT(%q):\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.rdata, test.txt, txt)
+		}
+	}
+}
+
+// TestEntryGroupBrowseDomainName tests entryGroupBrowseDomainName function
+func TestEntryGroupBrowseDomainName(t *testing.T) {
+	type testData struct {
+		btype  DomainBrowserType
+		parent string
+		name   string
+	}
+
+	tests := []testData{
+		{DomainBrowserBrowse, "local", "b._dns-sd._udp.local"},
+		{DomainBrowserBrowseDefault, "local", "db._dns-sd._udp.local"},
+		{DomainBrowserRegister, "example.com", "r._dns-sd._udp.example.com"},
+		{DomainBrowserRegisterDefault, "", "dr._dns-sd._udp"},
+		{DomainBrowserLegacy, "local", "lb._dns-sd._udp.local"},
+		{DomainBrowserType(-1), "local", ""},
+	}
+
+	for _, test := range tests {
+		name := entryGroupBrowseDomainName(test.btype, test.parent)
+		if name != test.name {
+			t.Errorf("%d %q:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.btype, test.parent, test.name, name)
+		}
+	}
+}
@@ -260,6 +260,29 @@ to close and re-create these objects.
 The Client manages underlying AvahiPoll object (Avahi event loop) automatically
 and doesn't expose it via its interface.
 
+For debugging, the structured logger (log/slog) can be attached to the
+Client with the [Client.SetLogger] call. When set, the Client logs
+creation and Close of all owned objects, all events they report and
+state changes. Without logger, the package is silent.
+
+For instrumentation (metrics, tracing), the [Observer] can be attached
+to the Client with the [Client.SetObserver] call. It is notified about
+the same things, as logger. The metrics and tracing subpackages, which
+live in their own modules, implement the Prometheus collector and the
+OpenTelemetry tracing on top of it. Use [MultiObserver] to attach both.
+
+For reproducible debugging, events of the Client and all its objects
+can be captured into the JSON lines file with the [Recorder] (which is
+the Observer as well) and later fed back into the application with the
+Client, created by [NewReplayClient] instead of [NewClient].
+
+All enum and bit-flag types implement [encoding.TextMarshaler] and
+[encoding.TextUnmarshaler], so events can be encoded into JSON in the
+human-readable form and flags can be named in configuration files.
+Flags are written as "|"-separated list of names (i.e., "cached|mdns").
+When decoding JSON, numbers are accepted as well, so captures, written
+by older versions of this package, can still be replayed.
+
 # Browsers
 
 Browser constantly monitors the network for newly discovered or removed
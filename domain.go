@@ -9,11 +9,10 @@
 
 package avahi
 
-// #include <stdlib.h>
-// #include <avahi-common/domain.h>
-import "C"
 import (
-	"unsafe"
+	"net/netip"
+	"strconv"
+	"strings"
 )
 
 // DomainFrom makes escaped domain name string from a sequence of unescaped
@@ -50,30 +49,86 @@ func DomainFrom(labels []string) string {
 //
 //	"Ex\.Ample.com" -> ["Ex.Ample", "com"]
 //
+// It follows the same rules as Avahi's avahi_unescape_label does,
+// including the "\DDD" decimal escapes, but implemented in pure Go.
+//
 // In a case of error it returns nil.
 func DomainSlice(d string) []string {
-	// Convert input from Go to C
-	in := C.CString(d)
-	defer C.free(unsafe.Pointer(in))
-
-	// Allocate decode buffer. len(d) must be enough.
-	buflen := C.size_t(len(d))
-	buf := C.malloc(buflen)
-
-	// Decode label by label
-	labels := []string{}
-
-	next := in
-	for *next != 0 {
-		clabel := C.avahi_unescape_label(&next, (*C.char)(buf), buflen)
-		if clabel == nil {
-			return nil
+	labels, _, _ := domainUnescape(d)
+	return labels
+}
+
+// domainUnescape splits escaped domain name into a sequence of
+// unescaped labels, following the same rules as Avahi does:
+//   - "\\" and "\." escapes are recognized
+//   - "\DDD" escapes are recognized, where DDD is the decimal byte
+//     value in range 1...255
+//   - any other escape is an error
+//   - each label must be the valid UTF-8 string
+//
+// On success, it returns labels and -1. On error, it returns nil
+// slice, the failed label index and the reason of failure.
+func domainUnescape(d string) (labels []string, bad int, reason string) {
+	labels = []string{}
+	buf := make([]byte, 0, len(d))
+
+	for len(d) > 0 {
+		buf = buf[:0]
+		bad = len(labels)
+
+		i := 0
+		for i < len(d) && d[i] != '.' {
+			c := d[i]
+			i++
+
+			switch {
+			case c == 0:
+				return nil, bad, "NUL character"
+
+			case c != '\\':
+				buf = append(buf, c)
+
+			case i == len(d):
+				return nil, bad, "dangling backslash"
+
+			case d[i] == '\\' || d[i] == '.':
+				buf = append(buf, d[i])
+				i++
+
+			case isdigit(d[i]):
+				if i+2 >= len(d) || !isdigit(d[i+1]) || !isdigit(d[i+2]) {
+					return nil, bad, "incomplete \\DDD escape"
+				}
+
+				n := int(d[i]-'0')*100 + int(d[i+1]-'0')*10 +
+					int(d[i+2]-'0')
+				if n == 0 || n > 255 {
+					return nil, bad, "\\DDD escape out of range"
+				}
+
+				buf = append(buf, byte(n))
+				i += 3
+
+			default:
+				return nil, bad, "invalid escape sequence"
+			}
 		}
 
-		labels = append(labels, C.GoString(clabel))
+		if !utf8valid(buf) {
+			return nil, bad, "invalid UTF-8"
+		}
+
+		labels = append(labels, string(buf))
+
+		// Skip the '.' separator
+		if i < len(d) {
+			i++
+		}
+
+		d = d[i:]
 	}
 
-	return labels
+	return labels, -1, ""
 }
 
 // DomainEqual reports if two domain names are equal.
@@ -154,6 +209,15 @@ func DomainToUpper(d string) string {
 //	"Kyocera ECOSYS M2040dn._ipp._tcp.local" -->
 //	    --> ["Kyocera ECOSYS M2040dn", "_ipp._tcp", "local"]
 //
+// Service type may include subtype, which is recognized by the "_sub"
+// label. In this case, the subtype label is not required to start
+// with the underscore character:
+//
+//	"Kyocera ECOSYS M2040dn.Color._sub._ipp._tcp.local" -->
+//	    --> ["Kyocera ECOSYS M2040dn", "Color._sub._ipp._tcp", "local"]
+//
+// Use [ParseServiceType] to parse the returned service type.
+//
 // In a case of error it returns empty strings
 func DomainServiceNameSplit(nm string) (instance, svctype, domain string) {
 	// Slice domain name into labels
@@ -172,14 +236,23 @@ func DomainServiceNameSplit(nm string) (instance, svctype, domain string) {
 	// So find range of labels that belong to the service type.
 	svcTypeBeg := 1
 	svcTypeEnd := 1
+	svcTypeMin := 2
+
+	// If service type includes subtype, subtype label may be
+	// anything, so just skip it. Subtype, "_sub" and service
+	// type itself require at least 4 labels.
+	if len(labels) > 2 && strcaseequal(labels[2], "_sub") {
+		svcTypeEnd++
+		svcTypeMin = 4
+	}
 
 	for svcTypeEnd < len(labels) &&
 		len(labels[svcTypeEnd]) > 1 && labels[svcTypeEnd][0] == '_' {
 		svcTypeEnd++
 	}
 
-	if svcTypeEnd-svcTypeBeg < 2 {
-		// At least 2 labels required
+	if svcTypeEnd-svcTypeBeg < svcTypeMin {
+		// At least 2 labels required (4 with subtype)
 		return
 	}
 
@@ -196,6 +269,7 @@ func DomainServiceNameSplit(nm string) (instance, svctype, domain string) {
 //
 //   - instance MUST be unescaped label
 //   - svctype and domain MUST be escaped domain names
+//   - svctype may include subtype (see [ServiceType])
 //   - instance and svctype MUST NOT be empty
 //
 // In a case of error it returns empty strings.
@@ -217,3 +291,251 @@ func DomainServiceNameJoin(instance, svctype, domain string) string {
 
 	return out
 }
+
+// DomainReverseAddr returns the reverse lookup domain name for
+// the IP address:
+//
+//	"192.168.0.1" -> "1.0.168.192.in-addr.arpa"
+//	"fe80::1"     -> "1.0.0.0.[...].8.e.f.ip6.arpa"
+//
+// IPv4 addresses use the in-addr.arpa domain, IPv6 addresses use
+// the ip6.arpa domain in the nibble format. The IPv6 zone, if any,
+// is ignored.
+//
+// See [RFC1035, 3.5.] and [RFC3596, 2.5.] for details.
+//
+// In a case of error it returns empty string.
+//
+// [RFC1035, 3.5.]: https://datatracker.ietf.org/doc/html/rfc1035#section-3.5
+// [RFC3596, 2.5.]: https://datatracker.ietf.org/doc/html/rfc3596#section-2.5
+func DomainReverseAddr(addr netip.Addr) string {
+	const hex = "0123456789abcdef"
+
+	buf := make([]byte, 0, 72)
+
+	switch {
+	case addr.Is4():
+		ip := addr.As4()
+		for i := len(ip) - 1; i >= 0; i-- {
+			buf = strconv.AppendUint(buf, uint64(ip[i]), 10)
+			buf = append(buf, '.')
+		}
+		buf = append(buf, "in-addr.arpa"...)
+
+	case addr.Is6():
+		ip := addr.As16()
+		for i := len(ip) - 1; i >= 0; i-- {
+			buf = append(buf, hex[ip[i]&0xf], '.', hex[ip[i]>>4], '.')
+		}
+		buf = append(buf, "ip6.arpa"...)
+
+	default:
+		return ""
+	}
+
+	return string(buf)
+}
+
+// DomainParseReverse parses the reverse lookup domain name, as
+// returned by the [DomainReverseAddr], back into the IP address.
+//
+// Domain name comparison is case-insensitive and the trailing dot
+// is allowed. The name must contain the full address, i.e., exactly
+// 4 labels for in-addr.arpa and exactly 32 labels for ip6.arpa.
+//
+// It returns the IP address and true on success, or zero
+// [netip.Addr] and false if name is not a valid reverse
+// lookup name.
+func DomainParseReverse(d string) (netip.Addr, bool) {
+	labels := DomainSlice(strings.TrimSuffix(d, "."))
+	n := len(labels)
+
+	switch {
+	case n == 4+2 &&
+		strcaseequal(labels[4], "in-addr") &&
+		strcaseequal(labels[5], "arpa"):
+
+		var ip [4]byte
+		for i := 0; i < 4; i++ {
+			label := labels[3-i]
+			if len(label) > 1 && label[0] == '0' {
+				return netip.Addr{}, false
+			}
+
+			v, err := strconv.ParseUint(label, 10, 8)
+			if err != nil {
+				return netip.Addr{}, false
+			}
+
+			ip[i] = byte(v)
+		}
+
+		return netip.AddrFrom4(ip), true
+
+	case n == 32+2 &&
+		strcaseequal(labels[32], "ip6") &&
+		strcaseequal(labels[33], "arpa"):
+
+		var ip [16]byte
+		for i := 0; i < 32; i++ {
+			label := labels[31-i]
+			if len(label) != 1 {
+				return netip.Addr{}, false
+			}
+
+			v, err := strconv.ParseUint(label, 16, 8)
+			if err != nil {
+				return netip.Addr{}, false
+			}
+
+			ip[i/2] |= byte(v) << (4 * (1 - i%2))
+		}
+
+		return netip.AddrFrom16(ip), true
+	}
+
+	return netip.Addr{}, false
+}
+
+// DomainCanonical returns the canonical form of the domain name,
+// suitable for use as a map key:
+//   - unneeded escaping removed, like [DomainNormalize] does
+//   - ASCII letters converted to lower case, like [DomainToLower] does
+//   - trailing dot removed
+//
+// Two valid domain names are [DomainEqual] if and only if their
+// canonical forms are equal.
+//
+// In a case of error it returns empty string. Note, all invalid
+// names share this canonical form, so unlike [DomainEqual], which
+// never considers invalid names equal, they collide as map keys.
+// Callers that may see invalid names should check for the empty
+// result.
+func DomainCanonical(d string) string {
+	labels := DomainSlice(d)
+	for i := range labels {
+		labels[i] = DomainToLower(labels[i])
+	}
+
+	return DomainFrom(labels)
+}
+
+// DomainCompare compares two domain names, using the DNSSEC canonical
+// ordering, as defined in [RFC4034, 6.1.]. It returns -1, 0 or +1,
+// if d1 is less, equal or greater than d2.
+//
+// Names are compared label by label, starting from the rightmost
+// (most significant) one. Labels are compared as byte strings,
+// ignoring ASCII case. Parent domain sorts before its subdomains.
+//
+// Invalid domain names are considered less than any valid name
+// and equal to each other.
+//
+// [RFC4034, 6.1.]: https://datatracker.ietf.org/doc/html/rfc4034#section-6.1
+func DomainCompare(d1, d2 string) int {
+	labels1 := DomainSlice(d1)
+	labels2 := DomainSlice(d2)
+
+	switch {
+	case labels1 == nil && labels2 == nil:
+		return 0
+	case labels1 == nil:
+		return -1
+	case labels2 == nil:
+		return 1
+	}
+
+	i1 := len(labels1) - 1
+	i2 := len(labels2) - 1
+	for i1 >= 0 && i2 >= 0 {
+		l1 := DomainToLower(labels1[i1])
+		l2 := DomainToLower(labels2[i2])
+
+		switch {
+		case l1 < l2:
+			return -1
+		case l1 > l2:
+			return 1
+		}
+
+		i1--
+		i2--
+	}
+
+	switch {
+	case i1 < i2:
+		return -1
+	case i1 > i2:
+		return 1
+	}
+
+	return 0
+}
+
+// DomainIsSubdomain reports if child is the subdomain of parent
+// or equal to parent:
+//
+//	"printer.example.com", "example.com" -> true
+//	"example.com", "example.com"         -> true
+//	"example.com", "printer.example.com" -> false
+//
+// The empty parent means the root domain, so any valid name
+// is its subdomain.
+//
+// Invalid domain names are never subdomains of anything.
+func DomainIsSubdomain(child, parent string) bool {
+	_, ok := domainTrimSuffix(child, parent)
+	return ok
+}
+
+// DomainParent returns the parent domain of d, i.e. d without
+// the first (leftmost) label:
+//
+//	"printer.example.com" -> "example.com"
+//	"local"               -> ""
+//
+// In a case of error it returns empty string.
+func DomainParent(d string) string {
+	labels := DomainSlice(d)
+	if len(labels) < 2 {
+		return ""
+	}
+
+	return DomainFrom(labels[1:])
+}
+
+// DomainTrimSuffix returns d without the provided trailing suffix
+// domain, which is compared label by label, ignoring ASCII case:
+//
+//	"printer.example.com", "Example.COM" -> "printer"
+//	"printer.example.com", "ample.com"   -> "printer.example.com"
+//
+// If d doesn't end with suffix, d is returned unchanged.
+// If d is equal to suffix, empty string is returned.
+func DomainTrimSuffix(d, suffix string) string {
+	if trimmed, ok := domainTrimSuffix(d, suffix); ok {
+		return trimmed
+	}
+
+	return d
+}
+
+// domainTrimSuffix returns d without the trailing suffix domain and
+// true, if d ends with suffix. Otherwise, it returns "" and false.
+func domainTrimSuffix(d, suffix string) (string, bool) {
+	labels := DomainSlice(d)
+	slabels := DomainSlice(suffix)
+
+	if labels == nil || slabels == nil || len(slabels) > len(labels) {
+		return "", false
+	}
+
+	n := len(labels) - len(slabels)
+	for i := range slabels {
+		if !strcaseequal(labels[n+i], slabels[i]) {
+			return "", false
+		}
+	}
+
+	return DomainFrom(labels[:n]), true
+}
@@ -10,7 +10,9 @@
 package avahi
 
 import (
+	"net/netip"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -62,10 +64,46 @@ func TestDomainSlice(t *testing.T) {
 			labels: []string{"example", "com"},
 		},
 
+		{
+			domain: `local`,
+			labels: []string{"local"},
+		},
+
 		{
 			domain: `ex\?ample.com`,
 			labels: nil,
 		},
+
+		// Corner cases below follow avahi_unescape_label behavior
+		{domain: ``, labels: []string{}},
+		{domain: `.`, labels: []string{""}},
+		{domain: `example.com.`, labels: []string{"example", "com"}},
+		{domain: `a..b`, labels: []string{"a", "", "b"}},
+		{domain: `.example.com`, labels: []string{"", "example", "com"}},
+		{domain: `Ex\.Ample.com`, labels: []string{"Ex.Ample", "com"}},
+		{
+			domain: `My\\Service.example.com`,
+			labels: []string{`My\Service`, "example", "com"},
+		},
+		{
+			domain: `My\032Printer._ipp._tcp.local`,
+			labels: []string{"My Printer", "_ipp", "_tcp", "local"},
+		},
+		{
+			domain: `Принтер._ipp._tcp.local`,
+			labels: []string{"Принтер", "_ipp", "_tcp", "local"},
+		},
+		{domain: `\000`, labels: nil},
+		{domain: `\256`, labels: nil},
+		{domain: `\25`, labels: nil},
+		{domain: `\1`, labels: nil},
+		{domain: `trailing\`, labels: nil},
+		{domain: `\255`, labels: nil},         // Invalid UTF-8
+		{domain: "bad\xffutf8", labels: nil},  // Invalid UTF-8
+		{domain: "\xef\xb7\x90", labels: nil}, // U+FDD0, noncharacter
+		{domain: "\xef\xbf\xbe", labels: nil}, // U+FFFE, noncharacter
+		{domain: "\xed\xa0\x80", labels: nil}, // U+D800, surrogate
+		{domain: `\239\183\144`, labels: nil}, // U+FDD0, escaped
 	}
 
 	for _, test := range tests {
@@ -264,6 +302,30 @@ func TestDomainServiceNameSplit(t *testing.T) {
 			domain:   "local",
 		},
 
+		{
+			// Service type with _sub subtype
+			input:    `Kyocera ECOSYS M2040dn._universal._sub._ipp._tcp.local`,
+			instance: "Kyocera ECOSYS M2040dn",
+			svctype:  "_universal._sub._ipp._tcp",
+			domain:   "local",
+		},
+
+		{
+			// Subtype without underscore
+			input:    `Kyocera ECOSYS M2040dn.Color._sub._ipp._tcp.local`,
+			instance: "Kyocera ECOSYS M2040dn",
+			svctype:  "Color._sub._ipp._tcp",
+			domain:   "local",
+		},
+
+		{
+			// Subtype with incomplete service type
+			input:    `Kyocera ECOSYS M2040dn.Color._sub._tcp.local`,
+			instance: "",
+			svctype:  "",
+			domain:   "",
+		},
+
 		{
 			// Invalid service type
 			input:    `Kyocera ECOSYS M2040dn._tcp.local`,
@@ -336,6 +398,14 @@ func TestDomainServiceNameJoin(t *testing.T) {
 			domain:   "",
 			output:   `Kyocera ECOSYS M2040dn._ipp._tcp`,
 		},
+
+		{
+			// Service type with subtype
+			instance: "Kyocera ECOSYS M2040dn",
+			svctype:  "Color._sub._ipp._tcp",
+			domain:   "local",
+			output:   `Kyocera ECOSYS M2040dn.Color._sub._ipp._tcp.local`,
+		},
 	}
 
 	for _, test := range tests {
@@ -351,3 +421,314 @@ func TestDomainServiceNameJoin(t *testing.T) {
 		}
 	}
 }
+
+// TestDomainReverseAddr tests DomainReverseAddr and DomainParseReverse
+// functions
+func TestDomainReverseAddr(t *testing.T) {
+	type testData struct {
+		addr   string
+		domain string
+	}
+
+	tests := []testData{
+		{
+			addr:   `192.168.0.1`,
+			domain: `1.0.168.192.in-addr.arpa`,
+		},
+
+		{
+			addr:   `0.0.0.0`,
+			domain: `0.0.0.0.in-addr.arpa`,
+		},
+
+		{
+			addr: `2001:db8::567:89ab`,
+			domain: `b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.` +
+				`0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa`,
+		},
+
+		{
+			addr: `::ffff:192.168.0.1`,
+			domain: `1.0.0.0.8.a.0.c.f.f.f.f.0.0.0.0.` +
+				`0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.arpa`,
+		},
+	}
+
+	for _, test := range tests {
+		addr := netip.MustParseAddr(test.addr)
+		domain := DomainReverseAddr(addr)
+		if domain != test.domain {
+			t.Errorf("DomainReverseAddr(%q):\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.addr, test.domain, domain)
+		}
+
+		addr2, ok := DomainParseReverse(test.domain)
+		if !ok || addr2 != addr {
+			t.Errorf("DomainParseReverse(%q):\n"+
+				"expected: %s\n"+
+				"present:  %s\n",
+				test.domain, addr, addr2)
+		}
+	}
+
+	// Zero address
+	if domain := DomainReverseAddr(netip.Addr{}); domain != "" {
+		t.Errorf("DomainReverseAddr(netip.Addr{}): %q", domain)
+	}
+
+	// Case-insensitive, with trailing dot
+	addr, ok := DomainParseReverse(`1.0.168.192.IN-ADDR.Arpa.`)
+	if !ok || addr != netip.MustParseAddr("192.168.0.1") {
+		t.Errorf("DomainParseReverse: case-insensitive match failed")
+	}
+
+	// Invalid names
+	invalid := []string{
+		``,
+		`example.com`,
+		`0.168.192.in-addr.arpa`,
+		`1.0.168.192.168.in-addr.arpa`,
+		`256.0.168.192.in-addr.arpa`,
+		`01.0.168.192.in-addr.arpa`,
+		`+1.0.168.192.in-addr.arpa`,
+		`1.0.168.192.ip6.arpa`,
+		`b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.` +
+			`0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.ip6.arpa`,
+		`b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.` +
+			`0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.02.ip6.arpa`,
+		`b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.` +
+			`0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.x.ip6.arpa`,
+	}
+
+	for _, d := range invalid {
+		addr, ok := DomainParseReverse(d)
+		if ok {
+			t.Errorf("DomainParseReverse(%q): error expected, got %s",
+				d, addr)
+		}
+	}
+}
+
+// FuzzDomainSlice compares pure-Go DomainSlice against the
+// C implementation, based on the avahi_unescape_label, and checks
+// that labels, returned by DomainSlice, are valid UTF-8 and round-trip
+// via DomainFrom.
+func FuzzDomainSlice(f *testing.F) {
+	seeds := []string{
+		``,
+		`.`,
+		`a..b`,
+		`example.com.`,
+		`Ex\.Ample.com`,
+		`My\\Service.example.com`,
+		`My\032Printer._ipp._tcp.local`,
+		`\255`,
+		`trailing\`,
+		`Принтер._ipp._tcp.local`,
+		"bad\xffutf8",
+		"\xef\xb7\x90", // U+FDD0, noncharacter
+		`\239\183\144`, // U+FDD0, escaped
+	}
+
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, d string) {
+		labels := DomainSlice(d)
+
+		// C strings can't contain NUL characters
+		if strings.IndexByte(d, 0) < 0 {
+			expected := domainSliceC(d)
+			if !reflect.DeepEqual(expected, labels) {
+				t.Errorf("%q:\n"+
+					"expected: %q (C)\n"+
+					"present:  %q\n",
+					d, expected, labels)
+			}
+		}
+
+		for _, label := range labels {
+			// Only non-empty labels can round-trip
+			if label == "" {
+				return
+			}
+
+			if !utf8valid([]byte(label)) {
+				t.Errorf("%q: invalid UTF-8 label %q", d, label)
+			}
+		}
+
+		if labels == nil {
+			return
+		}
+
+		present := DomainSlice(DomainFrom(labels))
+		if !reflect.DeepEqual(labels, present) {
+			t.Errorf("%q:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				d, labels, present)
+		}
+	})
+}
+
+// FuzzDomainFrom checks that DomainFrom and DomainSlice round-trip,
+// and escaped name is decoded the same way by the C implementation.
+func FuzzDomainFrom(f *testing.F) {
+	f.Add("example", "com")
+	f.Add("Ex.Ample", "com")
+	f.Add(`My\Service`, "local")
+	f.Add("Принтер", "local")
+
+	f.Fuzz(func(t *testing.T, l1, l2 string) {
+		labels := []string{l1, l2}
+		for _, label := range labels {
+			// Only non-empty valid labels can round-trip
+			if label == "" || strings.IndexByte(label, 0) >= 0 ||
+				!utf8valid([]byte(label)) {
+				return
+			}
+		}
+
+		d := DomainFrom(labels)
+		present := DomainSlice(d)
+		if !reflect.DeepEqual(labels, present) {
+			t.Errorf("%q -> %q:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				labels, d, labels, present)
+		}
+
+		if c := domainSliceC(d); !reflect.DeepEqual(present, c) {
+			t.Errorf("%q -> %q:\n"+
+				"expected: %q (C)\n"+
+				"present:  %q\n",
+				labels, d, c, present)
+		}
+	})
+}
+
+// TestDomainCanonical tests DomainCanonical function
+func TestDomainCanonical(t *testing.T) {
+	type testData struct {
+		in, out string
+	}
+
+	tests := []testData{
+		{`Example.COM`, `example.com`},
+		{`Example.COM.`, `example.com`},
+		{`My\032Printer._IPP._tcp.local`, `my printer._ipp._tcp.local`},
+		{`My\.Printer.local`, `my\.printer.local`},
+		{`Принтер.local`, `Принтер.local`},
+		{`ex\?ample.com`, ``},
+	}
+
+	for _, test := range tests {
+		out := DomainCanonical(test.in)
+		if out != test.out {
+			t.Errorf("%q:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.in, test.out, out)
+		}
+	}
+}
+
+// TestDomainCompare tests DomainCompare function
+func TestDomainCompare(t *testing.T) {
+	// Example from RFC4034, 6.1., in the canonical order. As Avahi
+	// requires labels to be valid UTF-8, \200 is replaced with \126
+	sorted := []string{
+		`example`,
+		`a.example`,
+		`yljkjljk.a.example`,
+		`Z.a.example`,
+		`zABC.a.EXAMPLE`,
+		`z.example`,
+		`\001.z.example`,
+		`*.z.example`,
+		`\126.z.example`,
+	}
+
+	for i := range sorted {
+		for j := range sorted {
+			expected := 0
+			switch {
+			case i < j:
+				expected = -1
+			case i > j:
+				expected = 1
+			}
+
+			present := DomainCompare(sorted[i], sorted[j])
+			if present != expected {
+				t.Errorf("%q %q:\n"+
+					"expected: %d\n"+
+					"present:  %d\n",
+					sorted[i], sorted[j], expected, present)
+			}
+		}
+	}
+
+	// Invalid names
+	if DomainCompare(`ex\?ample`, `example`) != -1 ||
+		DomainCompare(`example`, `ex\?ample`) != 1 ||
+		DomainCompare(`ex\?ample`, `ex\?ample`) != 0 {
+		t.Errorf("DomainCompare: invalid names handled incorrectly")
+	}
+}
+
+// TestDomainSuffix tests DomainIsSubdomain, DomainParent and
+// DomainTrimSuffix functions
+func TestDomainSuffix(t *testing.T) {
+	type testData struct {
+		d, suffix string
+		sub       bool
+		trimmed   string
+	}
+
+	tests := []testData{
+		{`printer.example.com`, `example.com`, true, `printer`},
+		{`printer.example.com`, `Example.COM.`, true, `printer`},
+		{`example.com`, `example.com`, true, ``},
+		{`example.com`, ``, true, `example.com`},
+		{`example.com`, `printer.example.com`, false, `example.com`},
+		{`printer.example.com`, `ample.com`, false, `printer.example.com`},
+		{`My\.Printer.local`, `Printer.local`, false, `My\.Printer.local`},
+		{`ex\?ample.com`, `com`, false, `ex\?ample.com`},
+	}
+
+	for _, test := range tests {
+		sub := DomainIsSubdomain(test.d, test.suffix)
+		trimmed := DomainTrimSuffix(test.d, test.suffix)
+
+		if sub != test.sub || trimmed != test.trimmed {
+			t.Errorf("%q %q:\n"+
+				"expected: %v %q\n"+
+				"present:  %v %q\n",
+				test.d, test.suffix,
+				test.sub, test.trimmed,
+				sub, trimmed)
+		}
+	}
+
+	parents := [][2]string{
+		{`printer.example.com`, `example.com`},
+		{`My\.Printer.local`, `local`},
+		{`local`, ``},
+		{``, ``},
+	}
+
+	for _, p := range parents {
+		parent := DomainParent(p[0])
+		if parent != p[1] {
+			t.Errorf("DomainParent(%q):\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				p[0], p[1], parent)
+		}
+	}
+}
@@ -11,6 +11,7 @@ package avahi
 
 import (
 	"context"
+	"fmt"
 	"runtime/cgo"
 	"sync/atomic"
 	"unsafe"
@@ -64,6 +65,44 @@ const (
 	DomainBrowserLegacy DomainBrowserType = C.AVAHI_DOMAIN_BROWSER_BROWSE_LEGACY
 )
 
+// domainBrowserTypeNames contains names for known DomainBrowserType values.
+var domainBrowserTypeNames = map[DomainBrowserType]string{
+	DomainBrowserBrowse:          "browse",
+	DomainBrowserBrowseDefault:   "browse-default",
+	DomainBrowserRegister:        "register",
+	DomainBrowserRegisterDefault: "register-default",
+	DomainBrowserLegacy:          "legacy",
+}
+
+// String returns a name of the DomainBrowserType.
+func (btype DomainBrowserType) String() string {
+	n := domainBrowserTypeNames[btype]
+	if n == "" {
+		n = fmt.Sprintf("UNKNOWN %d", int(btype))
+	}
+	return n
+}
+
+// MarshalText returns a name of the DomainBrowserType.
+// It implements the [encoding.TextMarshaler] interface.
+func (btype DomainBrowserType) MarshalText() ([]byte, error) {
+	return enumMarshalText(btype, domainBrowserTypeNames)
+}
+
+// UnmarshalText decodes DomainBrowserType from its name.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (btype *DomainBrowserType) UnmarshalText(text []byte) error {
+	return enumUnmarshalText("DomainBrowserType.UnmarshalText", btype, text,
+		domainBrowserTypeNames)
+}
+
+// UnmarshalJSON decodes DomainBrowserType from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (btype *DomainBrowserType) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("DomainBrowserType.UnmarshalJSON", btype, data,
+		btype.UnmarshalText)
+}
+
 // DomainBrowserEvent represents events, generated by the
 // [DomainBrowser].
 type DomainBrowserEvent struct {
@@ -143,29 +182,42 @@ func NewDomainBrowser(
 	}
 
 	// Create AvahiDomainBrowser
-	avahiClient := clnt.begin()
-	defer clnt.end()
-
-	browser.avahiBrowser = C.avahi_domain_browser_new(
-		avahiClient,
-		C.AvahiIfIndex(ifidx),
-		C.AvahiProtocol(proto),
-		cdomain,
-		C.AvahiDomainBrowserType(btype),
-		C.AvahiLookupFlags(flags),
-		C.AvahiDomainBrowserCallback(C.domainBrowserCallback),
-		unsafe.Pointer(&browser.handle),
-	)
-
-	if browser.avahiBrowser == nil {
+	info := &ObjectInfo{
+		Kind:        "DomainBrowser",
+		IfIdx:       ifidx,
+		Proto:       proto,
+		Domain:      domain,
+		BrowserType: btype,
+		Flags:       flags,
+	}
+
+	err := newObject(context.Background(), clnt, browser, &browser.queue,
+		info, func(avahiClient *C.AvahiClient) error {
+			browser.avahiBrowser = C.avahi_domain_browser_new(
+				avahiClient,
+				C.AvahiIfIndex(ifidx),
+				C.AvahiProtocol(proto),
+				cdomain,
+				C.AvahiDomainBrowserType(btype),
+				C.AvahiLookupFlags(flags),
+				C.AvahiDomainBrowserCallback(
+					C.domainBrowserCallback),
+				unsafe.Pointer(&browser.handle),
+			)
+
+			if browser.avahiBrowser == nil {
+				return clnt.errno()
+			}
+
+			return nil
+		})
+
+	if err != nil {
 		browser.queue.Close()
 		browser.handle.Delete()
-		return nil, clnt.errno()
+		return nil, newError("NewDomainBrowser", err, domain, btype)
 	}
 
-	// Register self to be closed if Client is closed
-	browser.clnt.addCloser(browser)
-
 	return browser, nil
 }
 
@@ -196,10 +248,14 @@ func (browser *DomainBrowser) Get(ctx context.Context) (*DomainBrowserEvent,
 // Note, double close is safe.
 func (browser *DomainBrowser) Close() {
 	if !browser.closed.Swap(true) {
+		browser.clnt.notifyClose("DomainBrowser", browser)
+
 		browser.clnt.begin()
 		browser.clnt.delCloser(browser)
-		C.avahi_domain_browser_free(browser.avahiBrowser)
-		browser.avahiBrowser = nil
+		if browser.avahiBrowser != nil {
+			C.avahi_domain_browser_free(browser.avahiBrowser)
+			browser.avahiBrowser = nil
+		}
 		browser.clnt.end()
 
 		browser.queue.Close()
@@ -234,5 +290,6 @@ func domainBrowserCallback(
 		evnt.Err = browser.clnt.errno()
 	}
 
+	browser.clnt.notifyEvent("DomainBrowser", browser, evnt)
 	browser.queue.Push(evnt)
 }
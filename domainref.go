@@ -0,0 +1,49 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Reference implementation of domain names parsing
+//
+//go:build linux || freebsd
+
+package avahi
+
+import "unsafe"
+
+// #include <stdlib.h>
+// #include <avahi-common/domain.h>
+import "C"
+
+// domainSliceC is the cgo version of the DomainSlice, implemented
+// on top of the avahi_unescape_label.
+//
+// It is not used by the package itself; tests use it to compare
+// the pure-Go DomainSlice against the reference implementation
+// (Go doesn't allow cgo in the _test.go files).
+func domainSliceC(d string) []string {
+	// Convert input from Go to C
+	in := C.CString(d)
+	defer C.free(unsafe.Pointer(in))
+
+	// Allocate decode buffer. len(d) plus terminating
+	// '\0' must be enough.
+	buflen := C.size_t(len(d) + 1)
+	buf := C.malloc(buflen)
+	defer C.free(buf)
+
+	// Decode label by label
+	labels := []string{}
+
+	next := in
+	for *next != 0 {
+		clabel := C.avahi_unescape_label(&next, (*C.char)(buf), buflen)
+		if clabel == nil {
+			return nil
+		}
+
+		labels = append(labels, C.GoString(clabel))
+	}
+
+	return labels
+}
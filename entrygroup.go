@@ -12,6 +12,7 @@ package avahi
 import (
 	"context"
 	"math"
+	"net"
 	"net/netip"
 	"runtime/cgo"
 	"sync/atomic"
@@ -96,6 +97,74 @@ type EntryGroupRecord struct {
 	RData  []byte        // Record data
 }
 
+// EntryGroupBrowseDomain represents a browsing or registration
+// domain advertisement, as consumed by the [DomainBrowser].
+//
+// It is published as the PTR record, named like
+// "b._dns-sd._udp.<Parent>", that points to the Domain.
+// The record name prefix depends on the Type:
+//
+//	DomainBrowserBrowse           "b"
+//	DomainBrowserBrowseDefault    "db"
+//	DomainBrowserRegister         "r"
+//	DomainBrowserRegisterDefault  "dr"
+//	DomainBrowserLegacy           "lb"
+//
+// See [RFC6763, 11. Discovery of Browsing and Registration Domains]
+// for details.
+//
+// [RFC6763, 11. Discovery of Browsing and Registration Domains]: https://datatracker.ietf.org/doc/html/rfc6763#section-11
+type EntryGroupBrowseDomain struct {
+	IfIdx  IfIndex           // Network interface index
+	Proto  Protocol          // Publishing network protocol
+	Type   DomainBrowserType // Domain type
+	Domain string            // Advertised domain (use "" for default)
+	Parent string            // Where to advertise (use "" for default)
+}
+
+// EntryGroupDeviceInfo represents the "_device-info._tcp" record,
+// that describes the device model of the service.
+//
+// It is published as the TXT record, named like
+// "<InstanceName>._device-info._tcp.<Domain>", which contains
+// the "model=<Model>" key, followed by Txt. The InstanceName
+// must be the same, as the instance name of the service, being
+// described. Clients (macOS Finder, GNOME) use Model to choose
+// the device icon.
+//
+// Following Apple, only the TXT record is published, without
+// PTR and SRV records, so the record doesn't appear in the
+// service browsing results. Use [LookupDeviceInfo] to look it up or
+// [NewDeviceInfoBrowser] to attach it to the discovered services.
+type EntryGroupDeviceInfo struct {
+	IfIdx        IfIndex  // Network interface index
+	Proto        Protocol // Publishing network protocol
+	InstanceName string   // Service instance name
+	Domain       string   // Service domain (use "" for default)
+	Model        string   // Device model ("model=" key)
+	Txt          []string // Additional TXT keys ("key=value"...)
+}
+
+// EntryGroupWorkstation represents the "_workstation._tcp" service,
+// that advertises the host as a workstation.
+//
+// The service instance name is generated from the host name and MAC
+// address, like "myhost [00:11:22:33:44:55]" (see [WorkstationName]),
+// the same way as avahi-daemon does. Port is 9 (discard), TXT
+// record is empty.
+//
+// Hostname may be either a single label ("myhost") or the fully
+// qualified domain name ("myhost.local"). The single label is
+// qualified with the [Client.GetDomainName] to make the SRV target,
+// and only the first label is used in the instance name.
+type EntryGroupWorkstation struct {
+	IfIdx    IfIndex          // Network interface index
+	Proto    Protocol         // Publishing network protocol
+	Hostname string           // Host name (use "" for default)
+	Domain   string           // Service domain (use "" for default)
+	MAC      net.HardwareAddr // Host MAC address
+}
+
 // NewEntryGroup creates a new [EntryGroup].
 func NewEntryGroup(clnt *Client) (*EntryGroup, error) {
 	// Initialize EntryGroup structure
@@ -105,24 +174,29 @@ func NewEntryGroup(clnt *Client) (*EntryGroup, error) {
 	egrp.empty.Store(true)
 
 	// Create AvahiEntryGroup
-	avahiClient := clnt.begin()
-	defer clnt.end()
+	info := &ObjectInfo{Kind: "EntryGroup"}
 
-	egrp.avahiEntryGroup = C.avahi_entry_group_new(
-		avahiClient,
-		C.AvahiEntryGroupCallback(C.entryGroupCallback),
-		unsafe.Pointer(&egrp.handle),
-	)
+	err := newObject(context.Background(), clnt, egrp, &egrp.queue,
+		info, func(avahiClient *C.AvahiClient) error {
+			egrp.avahiEntryGroup = C.avahi_entry_group_new(
+				avahiClient,
+				C.AvahiEntryGroupCallback(C.entryGroupCallback),
+				unsafe.Pointer(&egrp.handle),
+			)
+
+			if egrp.avahiEntryGroup == nil {
+				return clnt.errno()
+			}
+
+			return nil
+		})
 
-	if egrp.avahiEntryGroup == nil {
+	if err != nil {
 		egrp.queue.Close()
 		egrp.handle.Delete()
-		return nil, clnt.errno()
+		return nil, newError("NewEntryGroup", err)
 	}
 
-	// Register self to be closed if Client is closed
-	egrp.clnt.addCloser(egrp)
-
 	return egrp, nil
 }
 
@@ -151,6 +225,8 @@ func (egrp *EntryGroup) Get(ctx context.Context) (*EntryGroupEvent, error) {
 // Note, double close is safe
 func (egrp *EntryGroup) Close() {
 	if !egrp.closed.Swap(true) {
+		egrp.clnt.notifyClose("EntryGroup", egrp)
+
 		egrp.clnt.begin()
 		egrp.clnt.delCloser(egrp)
 		C.avahi_entry_group_free(egrp.avahiEntryGroup)
@@ -169,7 +245,7 @@ func (egrp *EntryGroup) Commit() error {
 
 	rc := C.avahi_entry_group_commit(egrp.avahiEntryGroup)
 	if rc < 0 {
-		return ErrCode(rc)
+		return newError("EntryGroup.Commit", ErrCode(rc))
 	}
 
 	return nil
@@ -183,7 +259,7 @@ func (egrp *EntryGroup) Reset() error {
 
 	rc := C.avahi_entry_group_reset(egrp.avahiEntryGroup)
 	if rc < 0 {
-		return ErrCode(rc)
+		return newError("EntryGroup.Reset", ErrCode(rc))
 	}
 
 	egrp.empty.Store(true)
@@ -197,10 +273,29 @@ func (egrp *EntryGroup) IsEmpty() bool {
 }
 
 // AddService adds a service registration
+//
+// Before calling Avahi, service parameters are validated using
+// [ValidateInstanceName], [DomainValidate] and [ValidateHostName],
+// so in a case of invalid parameter the returned [*Error] wraps
+// the detailed [*ValidationError]. Service type is validated as
+// strictly as Avahi does it: unlike [ValidateServiceType], service
+// names, that don't conform to RFC6335 (i.e., too long or with
+// underscores), are accepted.
 func (egrp *EntryGroup) AddService(
 	svc *EntryGroupService,
 	flags PublishFlags) error {
 
+	// Validate parameters
+	err := entryGroupValidate(svc.InstanceName, svc.SvcType, svc.Domain)
+	if err == nil && svc.Hostname != "" {
+		err = ValidateHostName(svc.Hostname)
+	}
+
+	if err != nil {
+		return newError("EntryGroup.AddService", err,
+			svc.InstanceName, svc.SvcType, svc.Domain)
+	}
+
 	// Convert strings from Go to C
 	cinstancename := C.CString(svc.InstanceName)
 	defer C.free(unsafe.Pointer(cinstancename))
@@ -223,7 +318,8 @@ func (egrp *EntryGroup) AddService(
 	// Convert TXT from Go to C
 	ctxt, err := makeAvahiStringList(svc.Txt)
 	if err != nil {
-		return err
+		return newError("EntryGroup.AddService", err,
+			svc.InstanceName, svc.SvcType, svc.Domain)
 	}
 	defer C.avahi_string_list_free(ctxt)
 
@@ -245,7 +341,8 @@ func (egrp *EntryGroup) AddService(
 	)
 
 	if rc < 0 {
-		return ErrCode(rc)
+		return newError("EntryGroup.AddService", ErrCode(rc),
+			svc.InstanceName, svc.SvcType, svc.Domain)
 	}
 
 	egrp.empty.Store(false)
@@ -259,6 +356,19 @@ func (egrp *EntryGroup) AddServiceSubtype(
 	subtype string,
 	flags PublishFlags) error {
 
+	// Validate parameters
+	err := entryGroupValidate(svcid.InstanceName, svcid.SvcType,
+		svcid.Domain)
+	if err == nil {
+		err = validateServiceSubtypeName(subtype, false)
+	}
+
+	if err != nil {
+		return newError("EntryGroup.AddServiceSubtype", err,
+			svcid.InstanceName, svcid.SvcType, svcid.Domain,
+			subtype)
+	}
+
 	// Convert strings from Go to C
 	cinstancename := C.CString(svcid.InstanceName)
 	defer C.free(unsafe.Pointer(cinstancename))
@@ -291,7 +401,9 @@ func (egrp *EntryGroup) AddServiceSubtype(
 	)
 
 	if rc < 0 {
-		return ErrCode(rc)
+		return newError("EntryGroup.AddServiceSubtype", ErrCode(rc),
+			svcid.InstanceName, svcid.SvcType, svcid.Domain,
+			subtype)
 	}
 
 	egrp.empty.Store(false)
@@ -321,7 +433,8 @@ func (egrp *EntryGroup) UpdateServiceTxt(
 	// Convert TXT from Go to C
 	ctxt, err := makeAvahiStringList(txt)
 	if err != nil {
-		return err
+		return newError("EntryGroup.UpdateServiceTxt", err,
+			svcid.InstanceName, svcid.SvcType, svcid.Domain)
 	}
 	defer C.avahi_string_list_free(ctxt)
 
@@ -341,7 +454,8 @@ func (egrp *EntryGroup) UpdateServiceTxt(
 	)
 
 	if rc < 0 {
-		return ErrCode(rc)
+		return newError("EntryGroup.UpdateServiceTxt", ErrCode(rc),
+			svcid.InstanceName, svcid.SvcType, svcid.Domain)
 	}
 
 	egrp.empty.Store(false)
@@ -357,7 +471,8 @@ func (egrp *EntryGroup) AddAddress(
 	// Convert address from Go to C
 	caddr, err := makeAvahiAddress(rec.Addr)
 	if err != nil {
-		return err
+		return newError("EntryGroup.AddAddress", err,
+			rec.Hostname, rec.Addr)
 	}
 
 	// Convert strings from Go to C
@@ -378,7 +493,8 @@ func (egrp *EntryGroup) AddAddress(
 	)
 
 	if rc < 0 {
-		return ErrCode(rc)
+		return newError("EntryGroup.AddAddress", ErrCode(rc),
+			rec.Hostname, rec.Addr)
 	}
 
 	egrp.empty.Store(false)
@@ -393,7 +509,8 @@ func (egrp *EntryGroup) AddRecord(
 
 	// Convert TTL from Go to C
 	if rec.TTL < 0 || rec.TTL > time.Second*math.MaxInt32 {
-		return ErrInvalidTTL
+		return newError("EntryGroup.AddRecord", ErrInvalidTTL,
+			rec.Name, rec.RClass, rec.RType)
 	}
 
 	cttl := C.uint32_t((rec.TTL + time.Second/2) / time.Second)
@@ -425,7 +542,8 @@ func (egrp *EntryGroup) AddRecord(
 	)
 
 	if rc < 0 {
-		return ErrCode(rc)
+		return newError("EntryGroup.AddRecord", ErrCode(rc),
+			rec.Name, rec.RClass, rec.RType)
 	}
 
 	egrp.empty.Store(false)
@@ -433,6 +551,206 @@ func (egrp *EntryGroup) AddRecord(
 	return nil
 }
 
+// AddBrowseDomain adds browsing or registration domain advertisement.
+func (egrp *EntryGroup) AddBrowseDomain(
+	rec *EntryGroupBrowseDomain,
+	flags PublishFlags) error {
+
+	name := entryGroupBrowseDomainName(rec.Type, rec.Parent)
+	if name == "" {
+		return newError("EntryGroup.AddBrowseDomain",
+			ErrInvalidArgument, rec.Domain, rec.Type, rec.Parent)
+	}
+
+	domain := rec.Domain
+	if domain == "" {
+		domain = egrp.clnt.GetDomainName()
+	}
+
+	if rec.Parent == "" {
+		name += "." + egrp.clnt.GetDomainName()
+	}
+
+	rdata := DNSEncodePTR(domain)
+	if rdata == nil {
+		return newError("EntryGroup.AddBrowseDomain",
+			ErrInvalidDomainName, rec.Domain, rec.Type, rec.Parent)
+	}
+
+	return egrp.AddRecord(&EntryGroupRecord{
+		IfIdx:  rec.IfIdx,
+		Proto:  rec.Proto,
+		Name:   name,
+		RClass: DNSClassIN,
+		RType:  DNSTypePTR,
+		TTL:    entryGroupBrowseDomainTTL,
+		RData:  rdata,
+	}, flags)
+}
+
+// AddDeviceInfo adds the "_device-info._tcp" record, that describes
+// the device model of the existent service.
+func (egrp *EntryGroup) AddDeviceInfo(
+	rec *EntryGroupDeviceInfo,
+	flags PublishFlags) error {
+
+	// Validate parameters
+	err := entryGroupValidate(rec.InstanceName, entryGroupDeviceInfoType,
+		rec.Domain)
+	if err != nil {
+		return newError("EntryGroup.AddDeviceInfo", err,
+			rec.InstanceName, rec.Domain)
+	}
+
+	// Build the record
+	domain := rec.Domain
+	if domain == "" {
+		domain = egrp.clnt.GetDomainName()
+	}
+
+	name := DomainServiceNameJoin(rec.InstanceName,
+		entryGroupDeviceInfoType, domain)
+
+	txt := append([]string{"model=" + rec.Model}, rec.Txt...)
+	rdata := DNSEncodeTXT(txt)
+	if rdata == nil {
+		return newError("EntryGroup.AddDeviceInfo", ErrInvalidRecord,
+			rec.InstanceName, rec.Domain)
+	}
+
+	return egrp.AddRecord(&EntryGroupRecord{
+		IfIdx:  rec.IfIdx,
+		Proto:  rec.Proto,
+		Name:   name,
+		RClass: DNSClassIN,
+		RType:  DNSTypeTXT,
+		TTL:    entryGroupDeviceInfoTTL,
+		RData:  rdata,
+	}, flags)
+}
+
+// AddWorkstation adds the "_workstation._tcp" service.
+func (egrp *EntryGroup) AddWorkstation(
+	rec *EntryGroupWorkstation,
+	flags PublishFlags) error {
+
+	// Obtain the short host name and the SRV target. If Hostname
+	// is not specified, avahi-daemon uses its own FQDN as target.
+	hostname := egrp.clnt.GetHostName()
+	target := ""
+
+	if rec.Hostname != "" {
+		var err error
+		hostname, target, err = entryGroupWorkstationHost(
+			rec.Hostname, egrp.clnt.GetDomainName())
+		if err != nil {
+			return newError("EntryGroup.AddWorkstation", err,
+				rec.Hostname, rec.MAC)
+		}
+	}
+
+	instance := WorkstationName(hostname, rec.MAC)
+	if instance == "" {
+		return newError("EntryGroup.AddWorkstation", ErrInvalidArgument,
+			rec.Hostname, rec.MAC)
+	}
+
+	return egrp.AddService(&EntryGroupService{
+		IfIdx:        rec.IfIdx,
+		Proto:        rec.Proto,
+		InstanceName: instance,
+		SvcType:      entryGroupWorkstationType,
+		Domain:       rec.Domain,
+		Hostname:     target,
+		Port:         entryGroupWorkstationPort,
+	}, flags)
+}
+
+// entryGroupWorkstationHost returns the short host name, used
+// in the "_workstation._tcp" instance name, and the SRV target FQDN
+// for the EntryGroupWorkstation.Hostname. The single-label hostname
+// is qualified with the domain.
+func entryGroupWorkstationHost(hostname, domain string) (
+	short, target string, err error) {
+
+	err = ValidateHostName(hostname)
+	if err != nil {
+		return "", "", err
+	}
+
+	labels := DomainSlice(hostname)
+	short = labels[0]
+	target = hostname
+	if len(labels) == 1 {
+		target = DomainFrom(labels) + "." + domain
+	}
+
+	return short, target, nil
+}
+
+// entryGroupValidate validates the service instance name,
+// service type and domain (if not "").
+//
+// Service type is validated only as strictly as Avahi does it,
+// so services, that Avahi accepts, are not rejected.
+func entryGroupValidate(instance, svctype, domain string) error {
+	err := ValidateInstanceName(instance)
+	if err == nil {
+		err = validateServiceTypeName(svctype, false)
+	}
+	if err == nil && domain != "" {
+		err = DomainValidate(domain)
+	}
+
+	return err
+}
+
+// entryGroupBrowseDomainTTL is the TTL of browsing domain
+// advertisements, the same as Avahi uses for PTR records.
+const entryGroupBrowseDomainTTL = 4500 * time.Second
+
+// Device-info and workstation parameters
+const (
+	entryGroupDeviceInfoType  = "_device-info._tcp"
+	entryGroupDeviceInfoTTL   = 4500 * time.Second
+	entryGroupWorkstationType = "_workstation._tcp"
+	entryGroupWorkstationPort = 9
+)
+
+// entryGroupBrowseDomainName returns the name of the PTR record,
+// used to advertise the browsing or registration domain of the
+// specified type.
+//
+// If parent is "", the returned name lacks the domain part.
+// For unknown btype, it returns "".
+func entryGroupBrowseDomainName(btype DomainBrowserType,
+	parent string) string {
+
+	var prefix string
+
+	switch btype {
+	case DomainBrowserBrowse:
+		prefix = "b"
+	case DomainBrowserBrowseDefault:
+		prefix = "db"
+	case DomainBrowserRegister:
+		prefix = "r"
+	case DomainBrowserRegisterDefault:
+		prefix = "dr"
+	case DomainBrowserLegacy:
+		prefix = "lb"
+	default:
+		return ""
+	}
+
+	name := prefix + "._dns-sd._udp"
+	if parent != "" {
+		name += "." + parent
+	}
+
+	return name
+}
+
 // entryGroupCallback called by AvahiClient to report client state change
 //
 //export entryGroupCallback
@@ -451,5 +769,6 @@ func entryGroupCallback(
 		evnt.Err = egrp.clnt.errno()
 	}
 
+	egrp.clnt.notifyState("EntryGroup", egrp, evnt)
 	egrp.queue.Push(evnt)
 }
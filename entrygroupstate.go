@@ -53,3 +53,23 @@ func (state EntryGroupState) String() string {
 	}
 	return n
 }
+
+// MarshalText returns a name of the EntryGroupState.
+// It implements the [encoding.TextMarshaler] interface.
+func (state EntryGroupState) MarshalText() ([]byte, error) {
+	return enumMarshalText(state, entryGroupStateNames)
+}
+
+// UnmarshalText decodes EntryGroupState from its name.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (state *EntryGroupState) UnmarshalText(text []byte) error {
+	return enumUnmarshalText("EntryGroupState.UnmarshalText", state, text,
+		entryGroupStateNames)
+}
+
+// UnmarshalJSON decodes EntryGroupState from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (state *EntryGroupState) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("EntryGroupState.UnmarshalJSON", state, data,
+		state.UnmarshalText)
+}
@@ -3,12 +3,19 @@
 // Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
 // See LICENSE for license terms and conditions
 //
-// Avahi error codes
+// Avahi error codes and errors
 //
 //go:build linux || freebsd
 
 package avahi
 
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
 // #include <avahi-common/error.h>
 import "C"
 
@@ -139,3 +146,176 @@ func (err ErrCode) Error() string {
 	s := C.avahi_strerror(C.int(err))
 	return "avahi: " + C.GoString(s)
 }
+
+// MarshalText returns ErrCode as text, the same as returned by
+// the [ErrCode.Error]. It implements the [encoding.TextMarshaler]
+// interface.
+func (err ErrCode) MarshalText() ([]byte, error) {
+	return []byte(err.Error()), nil
+}
+
+// UnmarshalText decodes ErrCode from text, returned by the
+// [ErrCode.Error] (with or without the "avahi: " prefix) or from
+// the integer error code. It implements the [encoding.TextUnmarshaler]
+// interface.
+func (err *ErrCode) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	s = strings.TrimPrefix(s, "avahi: ")
+
+	for code := NoError; code > C.AVAHI_ERR_MAX; code-- {
+		msg := strings.TrimPrefix(code.Error(), "avahi: ")
+		if strings.EqualFold(s, msg) {
+			*err = code
+			return nil
+		}
+	}
+
+	v, e := strconv.ParseInt(s, 0, 0)
+	if e != nil {
+		return newError("ErrCode.UnmarshalText", ErrInvalidArgument,
+			string(text))
+	}
+
+	*err = ErrCode(v)
+	return nil
+}
+
+// UnmarshalJSON decodes ErrCode from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (err *ErrCode) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("ErrCode.UnmarshalJSON", err, data,
+		err.UnmarshalText)
+}
+
+// Is reports if ErrCode matches the target. In addition to the
+// exact match, it maps some error codes to the generic errors
+// of the standard library, so the generic code can interoperate:
+//
+//	ErrNotFound -> os.ErrNotExist
+//	ErrTimeout  -> os.ErrDeadlineExceeded
+//
+// It is used by [errors.Is] and not intended to be called directly.
+func (err ErrCode) Is(target error) bool {
+	switch target {
+	case os.ErrNotExist:
+		return err == ErrNotFound
+	case os.ErrDeadlineExceeded:
+		return err == ErrTimeout
+	}
+
+	code, ok := target.(ErrCode)
+	return ok && err == code
+}
+
+// Temporary reports if error is temporary, so the failed operation
+// is worth retrying later. These are the following errors:
+//
+//	ErrNoDaemon         - avahi-daemon not running (yet)
+//	ErrDisconnected     - connection to avahi-daemon lost
+//	ErrTimeout          - operation timed out
+//	ErrTooManyClients   - avahi-daemon client limit reached
+//
+// Note, ErrCode intentionally has no Timeout method, so it doesn't
+// implement the [net.Error] interface and avahi errors are not
+// mistaken for network I/O errors. Use errors.Is(err,
+// os.ErrDeadlineExceeded) to check for timeout (see [ErrCode.Is]).
+func (err ErrCode) Temporary() bool {
+	switch err {
+	case ErrNoDaemon, ErrDisconnected, ErrTimeout, ErrTooManyClients:
+		return true
+	}
+	return false
+}
+
+// IsInvalidArgument reports if error is caused by invalid argument,
+// passed by the caller (invalid name, address, port, flags and
+// so on). Retrying the failed operation with the same arguments
+// will fail again.
+func (err ErrCode) IsInvalidArgument() bool {
+	switch err {
+	case ErrInvalidHostName, ErrInvalidDomainName, ErrInvalidTTL,
+		ErrIsPattern, ErrInvalidRecord, ErrInvalidServiceName,
+		ErrInvalidServiceType, ErrInvalidPort, ErrInvalidKey,
+		ErrInvalidAddress, ErrInvalidInterface, ErrInvalidProtocol,
+		ErrInvalidFlags, ErrInvalidServiceSubtype, ErrInvalidRDATA,
+		ErrInvalidDNSClass, ErrInvalidDNSType, ErrInvalidArgument:
+		return true
+	}
+	return false
+}
+
+// IsDNS reports if error is the DNS error, returned by the
+// (unicast) DNS server, i.e., ErrDNSFormerr, ErrDNSSERVFAIL,
+// ErrDNSNXDOMAIN and so on.
+func (err ErrCode) IsDNS() bool {
+	switch err {
+	case ErrDNSFormerr, ErrDNSSERVFAIL, ErrDNSNXDOMAIN, ErrDNSNotimp,
+		ErrDNSREFUSED, ErrDNSYXDOMAIN, ErrDNSYXRRSET, ErrDNSNXRRSET,
+		ErrDNSNOTAUTH, ErrDNSNOTZONE:
+		return true
+	}
+	return false
+}
+
+// Error is returned by constructors, methods and functions of this
+// package. It wraps the underlying error (typically, [ErrCode] or
+// [*ValidationError]) with the name of the failed operation and its
+// arguments, for example:
+//
+//	avahi: EntryGroup.AddService("My Printer", "_ipp.tcp", ""): Invalid service type: "_ipp.tcp": label 1: must be "_tcp" or "_udp"
+//
+// The underlying error is available via [errors.Is] and [errors.As],
+// so the following works as expected:
+//
+//	errors.Is(err, avahi.ErrInvalidServiceType)
+//
+//	var code avahi.ErrCode
+//	errors.As(err, &code)
+//
+// Note, errors, delivered via events (for example,
+// [ServiceBrowserEvent].Err) are plain ErrCode values.
+type Error struct {
+	Op   string // Operation, e.g., "EntryGroup.AddService"
+	Args []any  // Operation arguments (names, addresses, ...)
+	Err  error  // Underlying error
+}
+
+// Error returns the error string.
+// It implements the error interface.
+func (err *Error) Error() string {
+	args := make([]string, len(err.Args))
+	for i, arg := range err.Args {
+		switch arg := arg.(type) {
+		case string:
+			args[i] = fmt.Sprintf("%q", arg)
+		default:
+			args[i] = fmt.Sprintf("%v", arg)
+		}
+	}
+
+	msg := strings.TrimPrefix(err.Err.Error(), "avahi: ")
+	return fmt.Sprintf("avahi: %s(%s): %s",
+		err.Op, strings.Join(args, ", "), msg)
+}
+
+// Unwrap returns the underlying error.
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+// newError wraps err into the [*Error] with the operation name
+// and arguments.
+//
+// If err is nil, it returns nil. If err is already *Error, it is
+// returned as is: the inner operation is more specific.
+func newError(op string, err error, args ...any) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+
+	return &Error{Op: op, Args: args, Err: err}
+}
@@ -0,0 +1,166 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Avahi errors test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"net/netip"
+	"os"
+	"testing"
+)
+
+// TestError tests Error type
+func TestError(t *testing.T) {
+	type testData struct {
+		err error  // Error to test
+		s   string // Expected error string
+	}
+
+	tests := []testData{
+		{
+			err: newError("EntryGroup.AddService",
+				ErrInvalidServiceType,
+				"My Printer", "_ipp.tcp", ""),
+			s: `avahi: EntryGroup.AddService("My Printer", "_ipp.tcp", ""): Invalid service type`,
+		},
+
+		{
+			err: newError("NewAddressResolver", ErrInvalidAddress,
+				netip.Addr{}),
+			s: `avahi: NewAddressResolver(invalid IP): Invalid address`,
+		},
+
+		{
+			err: newError("NewClient", ErrNoDaemon),
+			s:   `avahi: NewClient(): Daemon not running`,
+		},
+	}
+
+	for _, test := range tests {
+		s := test.err.Error()
+		if s != test.s {
+			t.Errorf("%#v:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.err, test.s, s)
+		}
+	}
+
+	// Test errors.Is and errors.As
+	err := newError("EntryGroup.AddService",
+		ValidateServiceType("_ipp.tcp"), "My Printer", "_ipp.tcp", "")
+
+	if !errors.Is(err, ErrInvalidServiceType) {
+		t.Errorf("errors.Is(%q, ErrInvalidServiceType) failed", err)
+	}
+
+	var code ErrCode
+	if !errors.As(err, &code) || code != ErrInvalidServiceType {
+		t.Errorf("errors.As(%q, *ErrCode) failed", err)
+	}
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Name != "_ipp.tcp" {
+		t.Errorf("errors.As(%q, **ValidationError) failed", err)
+	}
+
+	// Nested errors are not wrapped twice
+	err2 := newError("Outer", err)
+	if err2 != err {
+		t.Errorf("newError: *Error must not be wrapped twice")
+	}
+
+	// nil error is passed as is
+	if newError("Nil", nil) != nil {
+		t.Errorf("newError: nil must be returned for nil error")
+	}
+}
+
+// TestErrCodeClassify tests ErrCode classification methods
+func TestErrCodeClassify(t *testing.T) {
+	type testData struct {
+		err         ErrCode
+		temporary   bool
+		invalidArgs bool
+		dns         bool
+	}
+
+	tests := []testData{
+		{err: NoError},
+		{err: ErrFailure},
+		{err: ErrNoDaemon, temporary: true},
+		{err: ErrDisconnected, temporary: true},
+		{err: ErrTooManyClients, temporary: true},
+		{err: ErrTimeout, temporary: true},
+		{err: ErrInvalidServiceType, invalidArgs: true},
+		{err: ErrInvalidArgument, invalidArgs: true},
+		{err: ErrInvalidAddress, invalidArgs: true},
+		{err: ErrInvalidPacket},
+		{err: ErrDNSFormerr, dns: true},
+		{err: ErrDNSNXDOMAIN, dns: true},
+		{err: ErrDNSNOTZONE, dns: true},
+		{err: ErrInvalidDNSError},
+	}
+
+	for _, test := range tests {
+		present := testData{
+			err:         test.err,
+			temporary:   test.err.Temporary(),
+			invalidArgs: test.err.IsInvalidArgument(),
+			dns:         test.err.IsDNS(),
+		}
+
+		if present != test {
+			t.Errorf("%s:\n"+
+				"expected: %+v\n"+
+				"present:  %+v\n",
+				test.err, test, present)
+		}
+	}
+
+	// ErrCode must not be mistaken for the network I/O error
+	var err error = ErrTimeout
+	if _, ok := err.(net.Error); ok {
+		t.Errorf("ErrCode must not implement net.Error")
+	}
+}
+
+// TestErrCodeIs tests mapping of ErrCode to the standard errors
+func TestErrCodeIs(t *testing.T) {
+	type testData struct {
+		err    error
+		target error
+		match  bool
+	}
+
+	wrapped := newError("LookupHost", ErrNotFound, "printer.local")
+
+	tests := []testData{
+		{ErrNotFound, os.ErrNotExist, true},
+		{ErrNotFound, fs.ErrNotExist, true},
+		{ErrTimeout, os.ErrDeadlineExceeded, true},
+		{ErrTimeout, os.ErrNotExist, false},
+		{ErrFailure, os.ErrNotExist, false},
+		{ErrNotFound, ErrNotFound, true},
+		{ErrNotFound, ErrTimeout, false},
+		{wrapped, os.ErrNotExist, true},
+		{wrapped, ErrNotFound, true},
+	}
+
+	for _, test := range tests {
+		match := errors.Is(test.err, test.target)
+		if match != test.match {
+			t.Errorf("errors.Is(%q, %q): expected %v, present %v",
+				test.err, test.target, test.match, match)
+		}
+	}
+}
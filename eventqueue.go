@@ -47,6 +47,13 @@ func (q *eventqueue[T]) Chan() <-chan T {
 	return q.outchan
 }
 
+// Len returns count of values, pending in the eventqueue.
+func (q *eventqueue[T]) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.buf)
+}
+
 // Close closes the eventqueue. It purges all values still pending in
 // the eventqueue and closes the eventqueue's read channel.
 func (q *eventqueue[T]) Close() {
@@ -12,6 +12,7 @@ package avahi
 import (
 	"net/netip"
 	"strconv"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -143,3 +144,31 @@ func toupper(c byte) byte {
 	}
 	return c
 }
+
+// isdigit reports if ASCII character is a decimal digit
+func isdigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// utf8valid reports if s is a valid UTF-8 string, as Avahi
+// understands it.
+//
+// In addition to the Go rules, Avahi rejects Unicode noncharacters,
+// (U+FDD0...U+FDEF and U+xxFFFE/U+xxFFFF).
+func utf8valid(s []byte) bool {
+	for len(s) > 0 {
+		r, sz := utf8.DecodeRune(s)
+		switch {
+		case r == utf8.RuneError && sz <= 1:
+			return false
+		case 0xFDD0 <= r && r <= 0xFDEF:
+			return false
+		case r&0xFFFE == 0xFFFE:
+			return false
+		}
+
+		s = s[sz:]
+	}
+
+	return true
+}
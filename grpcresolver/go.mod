@@ -0,0 +1,16 @@
+module github.com/OpenPrinting/go-avahi/grpcresolver
+
+go 1.25.0
+
+require (
+	github.com/OpenPrinting/go-avahi v0.0.0-00010101000000-000000000000
+	google.golang.org/grpc v1.84.0
+)
+
+require (
+	golang.org/x/sys v0.47.0 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+)
+
+// Use the go-avahi from the enclosing directory
+replace github.com/OpenPrinting/go-avahi => ../
@@ -0,0 +1,389 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// gRPC name resolver
+//
+//go:build linux || freebsd
+
+// Package grpcresolver implements the gRPC name resolver on top
+// of the DNS-SD service discovery, provided by the avahi package.
+//
+// It lives in a separate module, so the core avahi package doesn't
+// depend on gRPC.
+//
+// Usage:
+//
+//	clnt, err := avahi.NewClient(0)
+//	...
+//	conn, err := grpc.NewClient("dnssd:///_myrpc._tcp",
+//		grpc.WithResolvers(grpcresolver.NewBuilder(clnt)),
+//		...)
+//
+// The target endpoint is the DNS-SD service type, optionally
+// followed by the domain (e.g., "dnssd:///_myrpc._tcp.example.com").
+// If domain is omitted, the default domain (usually, "local") is used.
+//
+// Each discovered service instance is resolved, and resolved addresses
+// are reported to gRPC. The address list is kept updated, as service
+// instances come and go. The TXT record and the instance name of each
+// service are passed as the address attributes, see [TXT] and
+// [InstanceName].
+package grpcresolver
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"sync"
+
+	"github.com/OpenPrinting/go-avahi"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+// Scheme is the gRPC target URL scheme, handled by the resolver.
+const Scheme = "dnssd"
+
+// Builder implements the [resolver.Builder] interface.
+type Builder struct {
+	clnt *avahi.Client // Avahi Client
+}
+
+// NewBuilder creates a new [Builder].
+//
+// The [avahi.Client] must remain open while resolvers,
+// created by the Builder, are in use.
+func NewBuilder(clnt *avahi.Client) *Builder {
+	return &Builder{clnt: clnt}
+}
+
+// Register creates a new [Builder] and registers it in the gRPC
+// global resolvers registry, so targets with the "dnssd" scheme
+// can be used without the grpc.WithResolvers option.
+//
+// Like [resolver.Register], it must be called only during
+// initialization time.
+func Register(clnt *avahi.Client) {
+	resolver.Register(NewBuilder(clnt))
+}
+
+// Scheme returns the scheme, handled by the [Builder].
+// It implements the [resolver.Builder] interface.
+func (b *Builder) Scheme() string {
+	return Scheme
+}
+
+// Build creates a new resolver for the given target.
+// It implements the [resolver.Builder] interface.
+func (b *Builder) Build(target resolver.Target, cc resolver.ClientConn,
+	opts resolver.BuildOptions) (resolver.Resolver, error) {
+
+	svctype, domain, err := parseTarget(target.Endpoint())
+	if err != nil {
+		return nil, err
+	}
+
+	browser, err := avahi.NewServiceBrowser(
+		b.clnt,
+		avahi.IfIndexUnspec,
+		avahi.ProtocolUnspec,
+		svctype, domain,
+		0)
+
+	if err != nil {
+		return nil, fmt.Errorf("dnssd: browse %q: %w", svctype, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &dnssdResolver{
+		clnt:      b.clnt,
+		cc:        cc,
+		browser:   browser,
+		poller:    avahi.NewPoller(),
+		instances: make(map[instanceKey]*instance),
+		cancel:    cancel,
+	}
+
+	r.poller.AddServiceBrowser(browser)
+
+	r.done.Add(1)
+	go r.proc(ctx)
+
+	return r, nil
+}
+
+// dnssdResolver implements the resolver.Resolver interface.
+type dnssdResolver struct {
+	clnt      *avahi.Client             // Avahi Client
+	cc        resolver.ClientConn       // gRPC ClientConn
+	browser   *avahi.ServiceBrowser     // Service browser
+	poller    *avahi.Poller             // Events poller
+	instances map[instanceKey]*instance // Discovered instances
+	allForNow bool                      // BrowserAllForNow received
+	reported  []resolver.Address        // Last reported addresses
+	cancel    context.CancelFunc        // Cancels proc goroutine
+	done      sync.WaitGroup            // Wait for proc goroutine
+}
+
+// instanceKey identifies the discovered service instance.
+//
+// Avahi reports the same service instance, discovered on a different
+// network interfaces and via different protocols, as separate events.
+type instanceKey struct {
+	ifidx   avahi.IfIndex  // Network interface index
+	proto   avahi.Protocol // Network protocol
+	name    string         // Instance name
+	svctype string         // Service type
+	domain  string         // Service domain
+}
+
+// instance represents the discovered service instance.
+type instance struct {
+	resolver *avahi.ServiceResolver // Service resolver
+	addr     string                 // Resolved "host:port", "" if none
+	txt      []string               // Resolved TXT record
+}
+
+// ResolveNow is a hint to resolve the target again.
+// It implements the [resolver.Resolver] interface.
+//
+// As the address list is kept updated all the time,
+// it does nothing.
+func (r *dnssdResolver) ResolveNow(resolver.ResolveNowOptions) {
+}
+
+// Close closes the resolver.
+// It implements the [resolver.Resolver] interface.
+func (r *dnssdResolver) Close() {
+	r.cancel()
+	r.done.Wait()
+
+	r.browser.Close()
+	for _, inst := range r.instances {
+		inst.resolver.Close()
+	}
+}
+
+// proc runs in its own goroutine and handles Avahi events.
+func (r *dnssdResolver) proc(ctx context.Context) {
+	defer r.done.Done()
+
+	for {
+		evnt, err := r.poller.Poll(ctx)
+		if err != nil {
+			return
+		}
+
+		switch evnt := evnt.(type) {
+		case *avahi.ServiceBrowserEvent:
+			r.handleBrowserEvent(evnt)
+		case *avahi.ServiceResolverEvent:
+			r.handleResolverEvent(evnt)
+		}
+	}
+}
+
+// handleBrowserEvent handles the ServiceBrowserEvent.
+func (r *dnssdResolver) handleBrowserEvent(evnt *avahi.ServiceBrowserEvent) {
+	key := instanceKey{
+		ifidx:   evnt.IfIdx,
+		proto:   evnt.Proto,
+		name:    evnt.InstanceName,
+		svctype: evnt.SvcType,
+		domain:  evnt.Domain,
+	}
+
+	switch evnt.Event {
+	case avahi.BrowserNew:
+		if r.instances[key] != nil {
+			return
+		}
+
+		resolver, err := avahi.NewServiceResolver(
+			r.clnt,
+			evnt.IfIdx,
+			evnt.Proto,
+			evnt.InstanceName,
+			evnt.SvcType,
+			evnt.Domain,
+			evnt.Proto,
+			0)
+
+		if err != nil {
+			r.cc.ReportError(fmt.Errorf("dnssd: resolve %q: %w",
+				evnt.InstanceName, err))
+			return
+		}
+
+		r.instances[key] = &instance{resolver: resolver}
+		r.poller.AddServiceResolver(resolver)
+
+	case avahi.BrowserRemove:
+		if inst := r.instances[key]; inst != nil {
+			inst.resolver.Close()
+			delete(r.instances, key)
+			r.update()
+		}
+
+	case avahi.BrowserAllForNow:
+		r.allForNow = true
+		r.update()
+
+	case avahi.BrowserFailure:
+		r.cc.ReportError(fmt.Errorf("dnssd: browse %q: %w",
+			evnt.SvcType, evnt.Err))
+	}
+}
+
+// handleResolverEvent handles the ServiceResolverEvent.
+func (r *dnssdResolver) handleResolverEvent(evnt *avahi.ServiceResolverEvent) {
+	key := instanceKey{
+		ifidx:   evnt.IfIdx,
+		proto:   evnt.Proto,
+		name:    evnt.InstanceName,
+		svctype: evnt.SvcType,
+		domain:  evnt.Domain,
+	}
+
+	inst := r.instances[key]
+	if inst == nil {
+		return
+	}
+
+	switch evnt.Event {
+	case avahi.ResolverFound:
+		// Zero port means the service is a placeholder
+		inst.addr = ""
+		if evnt.Port != 0 && evnt.Addr.IsValid() {
+			addrport := netip.AddrPortFrom(evnt.Addr, evnt.Port)
+			inst.addr = addrport.String()
+		}
+
+		inst.txt = evnt.Txt
+
+	case avahi.ResolverFailure:
+		inst.addr = ""
+	}
+
+	r.update()
+}
+
+// update reports the current address list to gRPC, if it has
+// been changed since the last update.
+//
+// Until BrowserAllForNow is received, an empty address list is not
+// reported, so gRPC will wait for the initial discovery to complete.
+func (r *dnssdResolver) update() {
+	addrs := []resolver.Address{}
+	for key, inst := range r.instances {
+		if inst.addr == "" {
+			continue
+		}
+
+		attrs := attributes.New(instanceNameKey{}, key.name).
+			WithValue(txtKey{}, TXT(inst.txt))
+
+		addrs = append(addrs, resolver.Address{
+			Addr:       inst.addr,
+			Attributes: attrs,
+		})
+	}
+
+	if len(addrs) == 0 && !r.allForNow {
+		return
+	}
+
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].Addr < addrs[j].Addr
+	})
+
+	if addressesEqual(addrs, r.reported) && r.reported != nil {
+		return
+	}
+
+	r.reported = addrs
+	r.cc.UpdateState(resolver.State{Addresses: addrs})
+}
+
+// addressesEqual compares two slices of resolver.Address.
+func addressesEqual(a1, a2 []resolver.Address) bool {
+	if len(a1) != len(a2) {
+		return false
+	}
+
+	for i := range a1 {
+		if !a1[i].Equal(a2[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseTarget parses the target endpoint into the service type
+// and domain.
+//
+// Service type consist of the leading labels, starting with the
+// underscore character, and the rest is domain.
+func parseTarget(endpoint string) (svctype, domain string, err error) {
+	labels := avahi.DomainSlice(endpoint)
+
+	n := 0
+	for n < len(labels) && len(labels[n]) > 1 && labels[n][0] == '_' {
+		n++
+	}
+
+	if n < 2 {
+		err = fmt.Errorf("dnssd: %q: invalid service type", endpoint)
+		return
+	}
+
+	svctype = avahi.DomainFrom(labels[:n])
+	domain = avahi.DomainFrom(labels[n:])
+
+	return
+}
+
+// TXT is the TXT record of the service instance, attached to
+// the resolver.Address as attribute.
+type TXT []string
+
+// Equal compares two TXT records. It is required for the
+// attributes comparison.
+func (txt TXT) Equal(o any) bool {
+	txt2, ok := o.(TXT)
+	if !ok || len(txt) != len(txt2) {
+		return false
+	}
+
+	for i := range txt {
+		if txt[i] != txt2[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Attribute keys
+type (
+	txtKey          struct{}
+	instanceNameKey struct{}
+)
+
+// TXTFromAddress returns the TXT record of the service instance,
+// the address belongs to.
+func TXTFromAddress(addr resolver.Address) TXT {
+	txt, _ := addr.Attributes.Value(txtKey{}).(TXT)
+	return txt
+}
+
+// InstanceName returns the name of the service instance,
+// the address belongs to.
+func InstanceName(addr resolver.Address) string {
+	name, _ := addr.Attributes.Value(instanceNameKey{}).(string)
+	return name
+}
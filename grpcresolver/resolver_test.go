@@ -0,0 +1,80 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// gRPC name resolver test
+//
+//go:build linux || freebsd
+
+package grpcresolver
+
+import "testing"
+
+// TestParseTarget tests parseTarget function
+func TestParseTarget(t *testing.T) {
+	type testData struct {
+		endpoint        string
+		svctype, domain string
+		err             bool
+	}
+
+	tests := []testData{
+		{
+			endpoint: `_myrpc._tcp`,
+			svctype:  `_myrpc._tcp`,
+			domain:   ``,
+		},
+
+		{
+			endpoint: `_myrpc._tcp.example.com`,
+			svctype:  `_myrpc._tcp`,
+			domain:   `example.com`,
+		},
+
+		{
+			endpoint: `_myrpc.example.com`,
+			err:      true,
+		},
+
+		{
+			endpoint: ``,
+			err:      true,
+		},
+	}
+
+	for _, test := range tests {
+		svctype, domain, err := parseTarget(test.endpoint)
+		switch {
+		case test.err && err == nil:
+			t.Errorf("%q: error expected", test.endpoint)
+
+		case !test.err && err != nil:
+			t.Errorf("%q: %s", test.endpoint, err)
+
+		case svctype != test.svctype || domain != test.domain:
+			t.Errorf("%q:\n"+
+				"expected: %q %q\n"+
+				"present:  %q %q\n",
+				test.endpoint, test.svctype, test.domain,
+				svctype, domain)
+		}
+	}
+}
+
+// TestTXTEqual tests TXT.Equal
+func TestTXTEqual(t *testing.T) {
+	txt := TXT{"a=1", "b=2"}
+
+	if !txt.Equal(TXT{"a=1", "b=2"}) {
+		t.Errorf("TXT.Equal: equal records reported as different")
+	}
+
+	if txt.Equal(TXT{"a=1"}) || txt.Equal(TXT{"a=1", "b=3"}) {
+		t.Errorf("TXT.Equal: different records reported as equal")
+	}
+
+	if txt.Equal([]string{"a=1", "b=2"}) {
+		t.Errorf("TXT.Equal: values of different type reported as equal")
+	}
+}
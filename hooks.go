@@ -0,0 +1,132 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Internal instrumentation hooks
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"net/netip"
+)
+
+// ObjectInfo describes the object, created by the [Client], as
+// reported to the [Observer].
+//
+// Fields, not applicable to the object kind, are left zero.
+type ObjectInfo struct {
+	Kind        string            // "ServiceBrowser", "ServiceResolver", ...
+	IfIdx       IfIndex           // Network interface index
+	Proto       Protocol          // Network protocol
+	Name        string            // Instance, host or record name
+	SvcType     string            // Service type
+	Domain      string            // Domain
+	Addr        netip.Addr        // Address, for AddressResolver
+	AddrProto   Protocol          // Address protocol, for resolvers
+	RClass      DNSClass          // Record DNS class, for RecordBrowser
+	RType       DNSType           // Record DNS type, for RecordBrowser
+	BrowserType DomainBrowserType // Domain type, for DomainBrowser
+	Flags       LookupFlags       // Lookup flags
+
+	// QueueLen returns count of events, queued by the object
+	// and not consumed yet.
+	QueueLen func() int `json:"-"`
+}
+
+// clientHooks contains instrumentation hooks, attached to the Client.
+//
+// Once published via Client.hooks, clientHooks is never modified.
+type clientHooks struct {
+	logger   clientLogger // Logger, if set
+	observer Observer     // Observer, if set
+}
+
+// hooks returns Client's hooks or nil, if no hooks are set.
+//
+// Object creation must be reported as follows:
+//
+//	if hooks := clnt.hooks(); hooks != nil {
+//		hooks.create(ctx, browser, &ObjectInfo{...})
+//	}
+//
+// So, if no hooks are set, ObjectInfo is not even constructed.
+func (clnt *Client) hooks() *clientHooks {
+	return clnt.hooksPtr.Load()
+}
+
+// setHooks modifies Client's hooks using the update function.
+func (clnt *Client) setHooks(update func(hooks *clientHooks)) {
+	for {
+		old := clnt.hooksPtr.Load()
+
+		hooks := &clientHooks{}
+		if old != nil {
+			*hooks = *old
+		}
+
+		update(hooks)
+		if hooks.logger == nil && hooks.observer == nil {
+			hooks = nil
+		}
+
+		if clnt.hooksPtr.CompareAndSwap(old, hooks) {
+			return
+		}
+	}
+}
+
+// create reports object creation.
+func (hooks *clientHooks) create(ctx context.Context,
+	obj any, info *ObjectInfo) {
+
+	if hooks.logger != nil {
+		hooks.logger.create(obj, info)
+	}
+
+	if hooks.observer != nil {
+		hooks.observer.Created(ctx, obj, info)
+	}
+}
+
+// notifyClose reports object Close.
+func (clnt *Client) notifyClose(kind string, obj any) {
+	if hooks := clnt.hooks(); hooks != nil {
+		if hooks.logger != nil {
+			hooks.logger.close(kind, obj)
+		}
+
+		if hooks.observer != nil {
+			hooks.observer.Closed(obj)
+		}
+	}
+}
+
+// notifyEvent reports event, generated by object.
+func (clnt *Client) notifyEvent(kind string, obj, evnt any) {
+	if hooks := clnt.hooks(); hooks != nil {
+		if hooks.logger != nil {
+			hooks.logger.event(kind, obj, evnt)
+		}
+
+		if hooks.observer != nil {
+			hooks.observer.Event(obj, evnt)
+		}
+	}
+}
+
+// notifyState reports state change of the Client or EntryGroup.
+func (clnt *Client) notifyState(kind string, obj, evnt any) {
+	if hooks := clnt.hooks(); hooks != nil {
+		if hooks.logger != nil {
+			hooks.logger.state(kind, obj, evnt)
+		}
+
+		if hooks.observer != nil {
+			hooks.observer.Event(obj, evnt)
+		}
+	}
+}
@@ -83,6 +83,45 @@ func NewHostNameResolver(
 	addrproto Protocol,
 	flags LookupFlags) (*HostNameResolver, error) {
 
+	return newHostNameResolver(context.Background(),
+		"NewHostNameResolver", clnt, ifidx, proto, hostname,
+		addrproto, flags)
+}
+
+// NewHostNameResolverContext creates a new [HostNameResolver], like the
+// [NewHostNameResolver] does, and associates it with the context.
+//
+// The ctx doesn't limit the HostNameResolver lifetime, it lives until
+// closed. Instead, ctx is passed to the [Observer], so instrumentation
+// (i.e., tracing) may link the HostNameResolver with the operation it
+// was created for.
+func NewHostNameResolverContext(
+	ctx context.Context,
+	clnt *Client,
+	ifidx IfIndex,
+	proto Protocol,
+	hostname string,
+	addrproto Protocol,
+	flags LookupFlags) (*HostNameResolver, error) {
+
+	return newHostNameResolver(ctx, "NewHostNameResolverContext",
+		clnt, ifidx, proto, hostname,
+		addrproto, flags)
+}
+
+// newHostNameResolver creates a new [HostNameResolver]. It is the common
+// implementation of the NewHostNameResolver and NewHostNameResolverContext.
+// op is the operation name, used for errors reporting.
+func newHostNameResolver(
+	ctx context.Context,
+	op string,
+	clnt *Client,
+	ifidx IfIndex,
+	proto Protocol,
+	hostname string,
+	addrproto Protocol,
+	flags LookupFlags) (*HostNameResolver, error) {
+
 	// Initialize HostNameResolver structure
 	resolver := &HostNameResolver{clnt: clnt}
 	resolver.handle = cgo.NewHandle(resolver)
@@ -103,13 +142,13 @@ func NewHostNameResolver(
 		if use == LookupUseMulticast && isLocalhost(hostname) {
 			loopback, err := Loopback()
 			if err != nil {
-				return nil, err
+				return nil, newError(op, err, hostname)
 			}
 
 			if ifidx == loopback {
 				// Avahi can't resolve "localhost".
 				// So just do its work for it.
-				flags := LookupResultCached |
+				rflags := LookupResultCached |
 					LookupResultMulticast |
 					LookupResultLocal
 
@@ -123,21 +162,37 @@ func NewHostNameResolver(
 					addr = loopbackIP6
 
 				default:
-					return nil, ErrInvalidProtocol
+					return nil, newError(op,
+						ErrInvalidProtocol, hostname)
 				}
 
 				evnt := &HostNameResolverEvent{
 					Event:    ResolverFound,
 					IfIdx:    ifidx,
 					Proto:    proto,
-					Flags:    flags,
+					Flags:    rflags,
 					Hostname: hostname,
 					Addr:     addr,
 				}
 
-				resolver.queue.Push(evnt)
 				resolver.clnt.addCloser(resolver)
 
+				if hooks := clnt.hooks(); hooks != nil {
+					hooks.create(ctx, resolver, &ObjectInfo{
+						Kind:      "HostNameResolver",
+						IfIdx:     ifidx,
+						Proto:     proto,
+						Name:      hostname,
+						AddrProto: addrproto,
+						Flags:     flags,
+						QueueLen:  resolver.queue.Len,
+					})
+				}
+
+				resolver.clnt.notifyEvent("HostNameResolver",
+					resolver, evnt)
+				resolver.queue.Push(evnt)
+
 				return resolver, nil
 			}
 		}
@@ -148,29 +203,42 @@ func NewHostNameResolver(
 	defer C.free(unsafe.Pointer(chostname))
 
 	// Create AvahiHostNameResolver
-	avahiClient := clnt.begin()
-	defer clnt.end()
-
-	resolver.avahiResolver = C.avahi_host_name_resolver_new(
-		avahiClient,
-		C.AvahiIfIndex(ifidx),
-		C.AvahiProtocol(proto),
-		chostname,
-		C.AvahiProtocol(addrproto),
-		C.AvahiLookupFlags(flags),
-		C.AvahiHostNameResolverCallback(C.hostnameResolverCallback),
-		unsafe.Pointer(&resolver.handle),
-	)
-
-	if resolver.avahiResolver == nil {
+	info := &ObjectInfo{
+		Kind:      "HostNameResolver",
+		IfIdx:     ifidx,
+		Proto:     proto,
+		Name:      hostname,
+		AddrProto: addrproto,
+		Flags:     flags,
+	}
+
+	err := newObject(ctx, clnt, resolver, &resolver.queue,
+		info, func(avahiClient *C.AvahiClient) error {
+			resolver.avahiResolver = C.avahi_host_name_resolver_new(
+				avahiClient,
+				C.AvahiIfIndex(ifidx),
+				C.AvahiProtocol(proto),
+				chostname,
+				C.AvahiProtocol(addrproto),
+				C.AvahiLookupFlags(flags),
+				C.AvahiHostNameResolverCallback(
+					C.hostnameResolverCallback),
+				unsafe.Pointer(&resolver.handle),
+			)
+
+			if resolver.avahiResolver == nil {
+				return clnt.errno()
+			}
+
+			return nil
+		})
+
+	if err != nil {
 		resolver.queue.Close()
 		resolver.handle.Delete()
-		return nil, clnt.errno()
+		return nil, newError(op, err, hostname)
 	}
 
-	// Register self to be closed if Client is closed
-	resolver.clnt.addCloser(resolver)
-
 	return resolver, nil
 }
 
@@ -201,6 +269,8 @@ func (resolver *HostNameResolver) Get(ctx context.Context) (
 // Note, double close is safe
 func (resolver *HostNameResolver) Close() {
 	if !resolver.closed.Swap(true) {
+		resolver.clnt.notifyClose("HostNameResolver", resolver)
+
 		resolver.clnt.begin()
 		resolver.clnt.delCloser(resolver)
 		if resolver.avahiResolver != nil {
@@ -245,5 +315,6 @@ func hostnameResolverCallback(
 		evnt.Err = resolver.clnt.errno()
 	}
 
+	resolver.clnt.notifyEvent("HostNameResolver", resolver, evnt)
 	resolver.queue.Push(evnt)
 }
@@ -0,0 +1,173 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// HTTP transport for MDNS hosts and DNS-SD service instances
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HTTPTransport is the [http.RoundTripper] that understands MDNS host
+// names and DNS-SD service instance names in the request URL.
+//
+// The following kinds of URL hosts are handled specially:
+//   - MDNS host names, like "http://printer.local/". These names are
+//     resolved by the [Dialer.DialContext]
+//   - DNS-SD service instance names, like
+//     "http://Printer Name._ipp._tcp.local/ipp/print". These names are
+//     first resolved by the [ServiceResolver] into the host name and
+//     port, and then the request is sent to that host name.
+//
+// If URL of the DNS-SD service instance has no explicit port, port
+// from the service's SRV record is used.
+//
+// For DNS-SD service instances, the request's URL host is replaced
+// with the resolved host name. So both the Host header and the TLS
+// SNI (Server Name Indication) contain the real host name of the
+// service, as the target server expects. If the Host header was
+// explicitly set by the caller ([http.Request.Host] differs from the
+// URL host), it is preserved.
+//
+// All other requests are passed to the underlying [http.Transport]
+// as is.
+//
+// Note, the Go [url.Parse] function doesn't accept percent-encoded
+// spaces in the URL host, so use [ParseServiceURL] to parse URLs,
+// like "http://Printer%20Name._ipp._tcp.local/".
+type HTTPTransport struct {
+	// Dialer used to resolve names and to connect.
+	// Must not be nil.
+	Dialer *Dialer
+
+	// Base is the template for the underlying http.Transport.
+	// If nil, http.DefaultTransport is used. HTTPTransport
+	// uses a clone of the Base with the DialContext hook
+	// replaced.
+	Base *http.Transport
+
+	transport *http.Transport // Underlying transport
+	once      sync.Once       // For transport initialization
+}
+
+// NewHTTPTransport creates a new [HTTPTransport].
+func NewHTTPTransport(clnt *Client) *HTTPTransport {
+	return &HTTPTransport{Dialer: NewDialer(clnt)}
+}
+
+// RoundTrip executes a single HTTP transaction.
+// It implements the [http.RoundTripper] interface.
+func (t *HTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.once.Do(t.init)
+
+	instance, svctype, domain := DomainServiceNameSplit(req.URL.Hostname())
+	if instance == "" {
+		return t.transport.RoundTrip(req)
+	}
+
+	// Resolve DNS-SD service instance
+	ctx := req.Context()
+	svc, err := t.Dialer.resolveService(ctx, instance, svctype, domain,
+		true, true)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, newError("HTTPTransport.RoundTrip", err,
+			instance, svctype, domain)
+	}
+
+	// Rewrite the request
+	port := req.URL.Port()
+	if port == "" {
+		port = strconv.Itoa(int(svc.port))
+	}
+
+	ctx = context.WithValue(ctx, dialerServiceKey{}, svc)
+	req2 := req.Clone(ctx)
+	req2.URL.Host = net.JoinHostPort(svc.hostname, port)
+	if req.Host == "" || req.Host == req.URL.Host {
+		req2.Host = ""
+	}
+
+	return t.transport.RoundTrip(req2)
+}
+
+// CloseIdleConnections closes idle connections of the
+// underlying http.Transport.
+func (t *HTTPTransport) CloseIdleConnections() {
+	t.once.Do(t.init)
+	t.transport.CloseIdleConnections()
+}
+
+// init initializes the underlying http.Transport.
+func (t *HTTPTransport) init() {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+
+	t.transport = base.Clone()
+	t.transport.DialContext = t.Dialer.DialContext
+}
+
+// ParseServiceURL parses the URL, like [url.Parse] does, but accepts
+// percent-encoded characters in the URL host, which is necessary for
+// URLs with the DNS-SD service instance names, like
+// "http://Printer%20Name._ipp._tcp.local/".
+//
+// The host is stored in the returned [url.URL] in its decoded form.
+func ParseServiceURL(rawurl string) (*url.URL, error) {
+	u, err := url.Parse(rawurl)
+	if err == nil {
+		return u, nil
+	}
+
+	// Split URL into parts
+	scheme, rest, found := strings.Cut(rawurl, "://")
+	if !found {
+		return nil, err
+	}
+
+	authority, path := rest, ""
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		authority, path = rest[:i], rest[i:]
+	}
+
+	userinfo, hostport := "", authority
+	if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+		userinfo, hostport = authority[:i+1], authority[i+1:]
+	}
+
+	host, port := hostport, ""
+	if i := strings.LastIndexByte(hostport, ':'); i >= 0 {
+		if _, err2 := strconv.ParseUint(hostport[i+1:], 10, 16); err2 == nil {
+			host, port = hostport[:i], hostport[i:]
+		}
+	}
+
+	// Decode the host and parse the URL with placeholder host
+	host, err2 := url.PathUnescape(host)
+	if err2 != nil {
+		return nil, err
+	}
+
+	u, err2 = url.Parse(scheme + "://" + userinfo + "host" + port + path)
+	if err2 != nil {
+		return nil, err
+	}
+
+	u.Host = host + port
+	return u, nil
+}
@@ -0,0 +1,104 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// HTTP transport test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseServiceURL tests ParseServiceURL function
+func TestParseServiceURL(t *testing.T) {
+	type testData struct {
+		in         string
+		host, path string
+		err        bool
+	}
+
+	tests := []testData{
+		{
+			in:   `http://printer.local/ipp/print`,
+			host: `printer.local`,
+			path: `/ipp/print`,
+		},
+
+		{
+			in:   `http://Printer%20Name._ipp._tcp.local/ipp/print`,
+			host: `Printer Name._ipp._tcp.local`,
+			path: `/ipp/print`,
+		},
+
+		{
+			in:   `https://user@Printer%20Name._ipps._tcp.local:443`,
+			host: `Printer Name._ipps._tcp.local:443`,
+			path: ``,
+		},
+
+		{
+			in:  `http://Printer%2`,
+			err: true,
+		},
+	}
+
+	for _, test := range tests {
+		u, err := ParseServiceURL(test.in)
+		switch {
+		case test.err && err == nil:
+			t.Errorf("%q: error expected", test.in)
+
+		case !test.err && err != nil:
+			t.Errorf("%q: %s", test.in, err)
+
+		case err == nil && (u.Host != test.host || u.Path != test.path):
+			t.Errorf("%q:\n"+
+				"expected: %q %q\n"+
+				"present:  %q %q\n",
+				test.in, test.host, test.path, u.Host, u.Path)
+		}
+	}
+}
+
+// TestHTTPTransportError tests errors, returned by HTTPTransport.RoundTrip
+func TestHTTPTransportError(t *testing.T) {
+	// Nobody answers in the empty capture
+	capture := `{"time":"2024-01-01T00:00:00Z","client":{"state":"running","hostname":"host","domain":"local","fqdn":"host.local","version":"avahi 0.8"}}` + "\n"
+
+	clnt, err := NewReplayClient(strings.NewReader(capture), 0)
+	if err != nil {
+		t.Fatalf("NewReplayClient: %s", err)
+	}
+
+	defer clnt.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		100*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		"http://Printer._ipp._tcp.local/", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest: %s", err)
+	}
+
+	_, err = NewHTTPTransport(clnt).RoundTrip(req)
+
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "HTTPTransport.RoundTrip" ||
+		!errors.Is(err, ErrTimeout) {
+		t.Errorf("HTTPTransport.RoundTrip:\n"+
+			"expected: %v\n"+
+			"present:  %v\n",
+			ErrTimeout, err)
+	}
+}
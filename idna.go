@@ -0,0 +1,416 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Internationalized domain names (IDNA)
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// idnaPrefix is the ACE prefix of IDNA A-labels
+const idnaPrefix = "xn--"
+
+// DomainToASCII converts the escaped domain name into the ASCII
+// form, replacing each U-label (label with non-ASCII characters)
+// with the corresponding A-label ("xn--..."), as IDNA requires:
+//
+//	"bücher.example" -> "xn--bcher-kva.example"
+//
+// Before conversion, non-ASCII labels are mapped according to
+// the simplified [UTS #46] rules:
+//   - characters are converted to lower case
+//   - the ideographic full stop (U+3002), fullwidth full stop
+//     (U+FF0E) and halfwidth ideographic full stop (U+FF61) are
+//     treated as label separators. The escaped ASCII dot ("\.")
+//     is not a separator and remains within its label
+//
+// Note, full UTS #46 mapping and Unicode normalization are not
+// performed here, so input is expected to be in the NFC form.
+//
+// ASCII labels are returned unchanged, so it is safe to use this
+// function with names, already in the ASCII form.
+//
+// In a case of error it returns [*ValidationError] that wraps
+// [ErrInvalidDomainName].
+//
+// [UTS #46]: https://www.unicode.org/reports/tr46/
+func DomainToASCII(d string) (string, error) {
+	labels, bad, reason := domainUnescape(d)
+	if labels == nil {
+		return "", validateError(ErrInvalidDomainName, d, bad, reason)
+	}
+
+	out := make([]string, 0, len(labels))
+	for i, label := range labels {
+		if idnaIsASCII(label) {
+			out = append(out, label)
+			continue
+		}
+
+		label = strings.Map(unicode.ToLower, label)
+		for _, part := range idnaSplit(label) {
+			if part == "" {
+				return "", validateError(ErrInvalidDomainName,
+					d, i, "empty label")
+			}
+
+			if !idnaIsASCII(part) {
+				part = idnaPrefix + punycodeEncode(part)
+			}
+
+			if len(part) > validateLabelMax {
+				return "", validateError(ErrInvalidDomainName,
+					d, i, "A-label too long")
+			}
+
+			out = append(out, part)
+		}
+	}
+
+	return DomainFrom(out), nil
+}
+
+// DomainToUnicode converts the escaped domain name into the Unicode
+// form, replacing each A-label ("xn--...") with the corresponding
+// U-label:
+//
+//	"xn--bcher-kva.example" -> "bücher.example"
+//
+// All other labels are returned unchanged.
+//
+// In a case of error (i.e., malformed A-label) it returns
+// [*ValidationError] that wraps [ErrInvalidDomainName].
+func DomainToUnicode(d string) (string, error) {
+	labels, bad, reason := domainUnescape(d)
+	if labels == nil {
+		return "", validateError(ErrInvalidDomainName, d, bad, reason)
+	}
+
+	for i, label := range labels {
+		ulabel, ok := idnaToUnicode(label)
+		if !ok {
+			return "", validateError(ErrInvalidDomainName, d, i,
+				"invalid A-label")
+		}
+
+		labels[i] = ulabel
+	}
+
+	return DomainFrom(labels), nil
+}
+
+// DomainEqualFold reports if two domain names are equal, like
+// [DomainEqual] does, but with the Unicode-aware case-insensitive
+// comparison of labels.
+//
+// A-labels are converted into U-labels before comparison, so
+// "xn--bcher-kva.example" and "BÜCHER.example" are considered equal.
+//
+// Note, invalid domain names are never equal to anything
+// else, including itself.
+func DomainEqualFold(d1, d2 string) bool {
+	labels1, _, _ := domainUnescape(d1)
+	labels2, _, _ := domainUnescape(d2)
+
+	if labels1 == nil || labels2 == nil {
+		return false
+	}
+
+	if len(labels1) != len(labels2) {
+		return false
+	}
+
+	for i := range labels1 {
+		l1, ok1 := idnaToUnicode(labels1[i])
+		l2, ok2 := idnaToUnicode(labels2[i])
+
+		if !ok1 || !ok2 || !strings.EqualFold(l1, l2) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// idnaToUnicode converts label into U-label, if label is A-label.
+// Other labels are returned as is.
+//
+// The A-label must round-trip, i.e. encoding of the decoded
+// label must return the original label (up to the ASCII case).
+func idnaToUnicode(label string) (string, bool) {
+	if len(label) < len(idnaPrefix) ||
+		!strcaseequal(label[:len(idnaPrefix)], idnaPrefix) {
+		return label, true
+	}
+
+	encoded := label[len(idnaPrefix):]
+	ulabel, err := punycodeDecode(encoded)
+	if err != nil || idnaIsASCII(ulabel) ||
+		!strcaseequal(punycodeEncode(ulabel), encoded) {
+		return "", false
+	}
+
+	return ulabel, true
+}
+
+// idnaIsASCII reports if s consist of ASCII characters only.
+func idnaIsASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// idnaSplit splits the unescaped label by the Unicode label
+// separators (U+3002, U+FF0E, U+FF61), that UTS #46 maps to '.'.
+// Empty parts are preserved.
+//
+// The ASCII '.' is not a separator here: the raw dots are already
+// handled by the domainUnescape, and the escaped dot belongs to
+// its label.
+func idnaSplit(label string) []string {
+	parts := []string{}
+	start := 0
+
+	for i, r := range label {
+		switch r {
+		case '。', '．', '｡':
+			parts = append(parts, label[start:i])
+			start = i + utf8.RuneLen(r)
+		}
+	}
+
+	return append(parts, label[start:])
+}
+
+// Punycode parameters. See [RFC3492, 5.] for details.
+//
+// [RFC3492, 5.]: https://datatracker.ietf.org/doc/html/rfc3492#section-5
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+	punycodeMaxInt      = 1<<31 - 1
+)
+
+// punycodeEncode encodes Unicode string, using the Punycode
+// algorithm, as defined in [RFC3492].
+//
+// The input is expected to be the valid UTF-8 string, taken
+// from the domain label, so overflow is impossible here.
+//
+// [RFC3492]: https://datatracker.ietf.org/doc/html/rfc3492
+func punycodeEncode(s string) string {
+	input := []rune(s)
+	output := make([]byte, 0, 2*len(s))
+
+	// Copy basic code points
+	for _, r := range input {
+		if r < utf8.RuneSelf {
+			output = append(output, byte(r))
+		}
+	}
+
+	b := len(output)
+	h := b
+	if b > 0 {
+		output = append(output, '-')
+	}
+
+	// Encode the rest
+	n := punycodeInitialN
+	delta := 0
+	bias := punycodeInitialBias
+
+	for h < len(input) {
+		// Find the smallest code point >= n
+		m := punycodeMaxInt
+		for _, r := range input {
+			if int(r) >= n && int(r) < m {
+				m = int(r)
+			}
+		}
+
+		delta += (m - n) * (h + 1)
+		n = m
+
+		for _, r := range input {
+			if int(r) < n {
+				delta++
+			}
+
+			if int(r) == n {
+				q := delta
+				for k := punycodeBase; ; k += punycodeBase {
+					t := punycodeThreshold(k, bias)
+					if q < t {
+						break
+					}
+
+					digit := t + (q-t)%(punycodeBase-t)
+					output = append(output, punycodeDigit(digit))
+					q = (q - t) / (punycodeBase - t)
+				}
+
+				output = append(output, punycodeDigit(q))
+				bias = punycodeAdapt(delta, h+1, h == b)
+				delta = 0
+				h++
+			}
+		}
+
+		delta++
+		n++
+	}
+
+	return string(output)
+}
+
+// punycodeDecode decodes Punycode-encoded string, as defined
+// in [RFC3492].
+//
+// [RFC3492]: https://datatracker.ietf.org/doc/html/rfc3492
+func punycodeDecode(s string) (string, error) {
+	output := []rune{}
+
+	// Copy basic code points
+	pos := 0
+	if b := strings.LastIndexByte(s, '-'); b >= 0 {
+		for i := 0; i < b; i++ {
+			if s[i] >= utf8.RuneSelf {
+				return "", fmt.Errorf("punycode: non-basic code point")
+			}
+			output = append(output, rune(s[i]))
+		}
+		pos = b + 1
+	}
+
+	// Decode the rest
+	n := punycodeInitialN
+	i := 0
+	bias := punycodeInitialBias
+
+	for pos < len(s) {
+		oldi := i
+		w := 1
+
+		for k := punycodeBase; ; k += punycodeBase {
+			if pos == len(s) {
+				return "", fmt.Errorf("punycode: truncated input")
+			}
+
+			digit := punycodeDigitValue(s[pos])
+			pos++
+
+			if digit < 0 {
+				return "", fmt.Errorf("punycode: invalid digit")
+			}
+
+			if digit > (punycodeMaxInt-i)/w {
+				return "", fmt.Errorf("punycode: overflow")
+			}
+
+			i += digit * w
+
+			t := punycodeThreshold(k, bias)
+			if digit < t {
+				break
+			}
+
+			if w > punycodeMaxInt/(punycodeBase-t) {
+				return "", fmt.Errorf("punycode: overflow")
+			}
+
+			w *= punycodeBase - t
+		}
+
+		l := len(output) + 1
+		bias = punycodeAdapt(i-oldi, l, oldi == 0)
+
+		if i/l > punycodeMaxInt-n {
+			return "", fmt.Errorf("punycode: overflow")
+		}
+
+		n += i / l
+		i %= l
+
+		if n > utf8.MaxRune || (0xD800 <= n && n <= 0xDFFF) {
+			return "", fmt.Errorf("punycode: invalid code point")
+		}
+
+		output = append(output, 0)
+		copy(output[i+1:], output[i:])
+		output[i] = rune(n)
+		i++
+	}
+
+	return string(output), nil
+}
+
+// punycodeThreshold computes the threshold value t for the
+// position k.
+func punycodeThreshold(k, bias int) int {
+	switch {
+	case k <= bias:
+		return punycodeTMin
+	case k >= bias+punycodeTMax:
+		return punycodeTMax
+	}
+	return k - bias
+}
+
+// punycodeAdapt is the Punycode bias adaptation function.
+func punycodeAdapt(delta, numpoints int, first bool) int {
+	if first {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+
+	delta += delta / numpoints
+
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
+
+// punycodeDigit returns the basic code point for the digit value.
+func punycodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+// punycodeDigitValue returns the digit value of the basic code point,
+// or -1, if code point is not a valid digit.
+func punycodeDigitValue(c byte) int {
+	switch {
+	case 'a' <= c && c <= 'z':
+		return int(c - 'a')
+	case 'A' <= c && c <= 'Z':
+		return int(c - 'A')
+	case '0' <= c && c <= '9':
+		return int(c-'0') + 26
+	}
+	return -1
+}
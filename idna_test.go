@@ -0,0 +1,168 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Internationalized domain names (IDNA) test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import "testing"
+
+// TestPunycode tests punycodeEncode and punycodeDecode functions
+func TestPunycode(t *testing.T) {
+	type testData struct {
+		decoded, encoded string
+	}
+
+	tests := []testData{
+		{"bücher", "bcher-kva"},
+		{"münchen", "mnchen-3ya"},
+		{"пример", "e1afmkfd"},
+		{"испытание", "80akhbyknj4f"},
+		{"中国", "fiqs8s"},
+		{"abc", "abc-"},
+
+		// RFC3492, 7.1. Sample strings: (L) Japanese
+		{"3年B組金八先生", "3B-ww4c5e180e575a65lsy2b"},
+
+		// RFC3492, 7.1. Sample strings: (B) Chinese (simplified)
+		{"他们为什么不说中文", "ihqwcrb4cv8a8dqg056pqjye"},
+	}
+
+	for _, test := range tests {
+		encoded := punycodeEncode(test.decoded)
+		if encoded != test.encoded {
+			t.Errorf("punycodeEncode(%q):\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.decoded, test.encoded, encoded)
+		}
+
+		decoded, err := punycodeDecode(test.encoded)
+		if err != nil || decoded != test.decoded {
+			t.Errorf("punycodeDecode(%q):\n"+
+				"expected: %q\n"+
+				"present:  %q (%v)\n",
+				test.encoded, test.decoded, decoded, err)
+		}
+	}
+
+	// Malformed input
+	for _, s := range []string{"bcher-kv", "bcher-k!a", "ü-kva", "99999999999"} {
+		decoded, err := punycodeDecode(s)
+		if err == nil {
+			t.Errorf("punycodeDecode(%q): error expected, got %q",
+				s, decoded)
+		}
+	}
+}
+
+// TestDomainIDNA tests DomainToASCII and DomainToUnicode functions
+func TestDomainIDNA(t *testing.T) {
+	type testData struct {
+		in    string // Input name
+		ascii string // Expected ASCII form
+		uni   string // Expected Unicode form
+	}
+
+	tests := []testData{
+		{
+			in:    `bücher.example`,
+			ascii: `xn--bcher-kva.example`,
+			uni:   `bücher.example`,
+		},
+
+		{
+			in:    `BÜCHER.Example`,
+			ascii: `xn--bcher-kva.Example`,
+			uni:   `BÜCHER.Example`,
+		},
+
+		{
+			in:    `xn--bcher-kva.example`,
+			ascii: `xn--bcher-kva.example`,
+			uni:   `bücher.example`,
+		},
+
+		{
+			in:    `пример。испытание`,
+			ascii: `xn--e1afmkfd.xn--80akhbyknj4f`,
+			uni:   `пример。испытание`,
+		},
+
+		{
+			in:    `My\.Host.local`,
+			ascii: `My\.Host.local`,
+			uni:   `My\.Host.local`,
+		},
+
+		{
+			in:    `bü\.cher.example`,
+			ascii: `xn--b\.cher-3ya.example`,
+			uni:   `bü\.cher.example`,
+		},
+	}
+
+	for _, test := range tests {
+		ascii, err := DomainToASCII(test.in)
+		if err != nil || ascii != test.ascii {
+			t.Errorf("DomainToASCII(%q):\n"+
+				"expected: %q\n"+
+				"present:  %q (%v)\n",
+				test.in, test.ascii, ascii, err)
+		}
+
+		uni, err := DomainToUnicode(test.in)
+		if err != nil || uni != test.uni {
+			t.Errorf("DomainToUnicode(%q):\n"+
+				"expected: %q\n"+
+				"present:  %q (%v)\n",
+				test.in, test.uni, uni, err)
+		}
+	}
+
+	// Errors
+	for _, d := range []string{`ex\?ample.com`, `xn--bcher-k!a.example`,
+		`xn--abc-.example`} {
+		if _, err := DomainToUnicode(d); err == nil {
+			t.Errorf("DomainToUnicode(%q): error expected", d)
+		}
+	}
+
+	for _, d := range []string{`ex\?ample.com`, `a。。b`} {
+		if _, err := DomainToASCII(d); err == nil {
+			t.Errorf("DomainToASCII(%q): error expected", d)
+		}
+	}
+}
+
+// TestDomainEqualFold tests DomainEqualFold function
+func TestDomainEqualFold(t *testing.T) {
+	type testData struct {
+		d1, d2 string
+		equal  bool
+	}
+
+	tests := []testData{
+		{`example.com`, `EXAMPLE.com`, true},
+		{`bücher.example`, `BÜCHER.example`, true},
+		{`xn--bcher-kva.example`, `BÜCHER.example`, true},
+		{`XN--BCHER-KVA.example`, `bücher.example`, true},
+		{`bücher.example`, `bucher.example`, false},
+		{`bücher.example`, `bücher`, false},
+		{`ex\?ample.com`, `ex\?ample.com`, false},
+	}
+
+	for _, test := range tests {
+		equal := DomainEqualFold(test.d1, test.d2)
+		if equal != test.equal {
+			t.Errorf("%q %q:\n"+
+				"expected: %v\n"+
+				"present:  %v\n",
+				test.d1, test.d2, test.equal, equal)
+		}
+	}
+}
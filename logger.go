@@ -0,0 +1,50 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Internal logging interface
+//
+//go:build linux || freebsd
+
+package avahi
+
+// clientLogger is the internal logging interface of the [Client].
+//
+// It is implemented on top of the log/slog in the logger_slog.go,
+// so this file doesn't depend on Go 1.21.
+//
+// Logger is one of the Client's hooks (see clientHooks). If
+// hooks are not set, logging calls cost a single atomic load:
+// objects and events are passed as pointers, so no allocations
+// are involved.
+type clientLogger interface {
+	// create logs object creation.
+	create(obj any, info *ObjectInfo)
+
+	// close logs object Close.
+	close(kind string, obj any)
+
+	// event logs event, generated by object. evnt is the pointer
+	// to the event structure (i.e., *ServiceBrowserEvent).
+	event(kind string, obj, evnt any)
+
+	// state logs state change of the Client or EntryGroup.
+	// evnt is *ClientEvent or *EntryGroupEvent.
+	state(kind string, obj, evnt any)
+}
+
+// logger returns the Client's logger or nil, if logging is disabled.
+func (clnt *Client) logger() clientLogger {
+	if hooks := clnt.hooks(); hooks != nil {
+		return hooks.logger
+	}
+	return nil
+}
+
+// setLogger sets or resets (if logger is nil) the Client's logger.
+func (clnt *Client) setLogger(logger clientLogger) {
+	clnt.setHooks(func(hooks *clientHooks) {
+		hooks.logger = logger
+	})
+}
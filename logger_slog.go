@@ -0,0 +1,191 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Structured logging via log/slog
+//
+//go:build (linux || freebsd) && go1.21
+
+package avahi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+)
+
+// LogLevels defines logging levels for different kinds of messages,
+// generated by the [Client], when logger is set with the
+// [Client.SetLogger].
+type LogLevels struct {
+	Objects  slog.Level // Objects creation and Close
+	Events   slog.Level // Events, reported by browsers and resolvers
+	States   slog.Level // Client and EntryGroup state changes
+	Failures slog.Level // Failure events and states
+}
+
+// DefaultLogLevels are the default logging levels, used if
+// [Client.SetLogger] is called with nil levels.
+var DefaultLogLevels = LogLevels{
+	Objects:  slog.LevelDebug,
+	Events:   slog.LevelDebug,
+	States:   slog.LevelInfo,
+	Failures: slog.LevelWarn,
+}
+
+// SetLogger sets the structured logger for the [Client] and all
+// objects (browsers, resolvers, entry groups), created by it.
+//
+// The following is logged:
+//   - creation (with parameters) and Close of objects
+//   - every event, reported by objects, with all its fields
+//   - Client and EntryGroup state changes
+//
+// Each message has the "obj" attribute, that identifies the object
+// and allows to correlate its events. Events and states that report
+// failures (with non-zero Err field) are logged with the levels.Failures
+// level. If levels is nil, [DefaultLogLevels] are used.
+//
+// Note, Client state changes, reported before SetLogger is called
+// (typically, the initial state), are not logged.
+//
+// If logger is nil, logging is disabled. This is the default, and
+// it costs virtually nothing.
+//
+// SetLogger requires Go 1.21 or newer.
+func (clnt *Client) SetLogger(logger *slog.Logger, levels *LogLevels) {
+	if logger == nil {
+		clnt.setLogger(nil)
+		return
+	}
+
+	if levels == nil {
+		levels = &DefaultLogLevels
+	}
+
+	clnt.setLogger(&slogLogger{logger: logger, levels: *levels})
+}
+
+// slogLogger implements clientLogger on top of the *slog.Logger.
+type slogLogger struct {
+	logger *slog.Logger // Underlying logger
+	levels LogLevels    // Logging levels
+}
+
+// create logs object creation.
+func (l *slogLogger) create(obj any, info *ObjectInfo) {
+	if !l.logger.Enabled(context.Background(), l.levels.Objects) {
+		return
+	}
+
+	attrs := []slog.Attr{slogObj(obj)}
+	if info.Kind != "EntryGroup" {
+		attrs = append(attrs,
+			slog.Any("ifidx", info.IfIdx),
+			slog.Any("proto", info.Proto))
+	}
+
+	switch info.Kind {
+	case "ServiceBrowser":
+		attrs = append(attrs,
+			slog.String("svctype", info.SvcType),
+			slog.String("domain", info.Domain))
+
+	case "MultiDomainBrowser":
+		attrs = append(attrs, slog.String("svctype", info.SvcType))
+
+	case "ServiceTypeBrowser":
+		attrs = append(attrs, slog.String("domain", info.Domain))
+
+	case "DomainBrowser":
+		attrs = append(attrs,
+			slog.String("domain", info.Domain),
+			slog.Any("btype", info.BrowserType))
+
+	case "RecordBrowser":
+		attrs = append(attrs,
+			slog.String("name", info.Name),
+			slog.Any("dnsclass", info.RClass),
+			slog.Any("dnstype", info.RType))
+
+	case "ServiceResolver":
+		attrs = append(attrs,
+			slog.String("instname", info.Name),
+			slog.String("svctype", info.SvcType),
+			slog.String("domain", info.Domain),
+			slog.Any("addrproto", info.AddrProto))
+
+	case "HostNameResolver":
+		attrs = append(attrs,
+			slog.String("hostname", info.Name),
+			slog.Any("addrproto", info.AddrProto))
+
+	case "AddressResolver":
+		attrs = append(attrs, slog.Any("addr", info.Addr))
+	}
+
+	if info.Kind != "EntryGroup" {
+		attrs = append(attrs, slog.Any("flags", info.Flags))
+	}
+
+	l.logger.LogAttrs(context.Background(), l.levels.Objects,
+		info.Kind+" created", attrs...)
+}
+
+// close logs object Close.
+func (l *slogLogger) close(kind string, obj any) {
+	l.logger.Log(context.Background(), l.levels.Objects,
+		kind+" closed", slogObj(obj))
+}
+
+// event logs event, generated by object.
+func (l *slogLogger) event(kind string, obj, evnt any) {
+	l.logEvent(l.levels.Events, kind+" event", obj, evnt)
+}
+
+// state logs state change of the Client or EntryGroup.
+func (l *slogLogger) state(kind string, obj, evnt any) {
+	l.logEvent(l.levels.States, kind+" state", obj, evnt)
+}
+
+// logEvent logs event structure, field by field.
+//
+// If event has non-zero Err field, the Failures level is used
+// instead of the supplied level.
+func (l *slogLogger) logEvent(level slog.Level, msg string, obj, evnt any) {
+	v := reflect.Indirect(reflect.ValueOf(evnt))
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	if f := v.FieldByName("Err"); f.IsValid() && !f.IsZero() {
+		level = l.levels.Failures
+	}
+
+	if !l.logger.Enabled(context.Background(), level) {
+		return
+	}
+
+	attrs := []slog.Attr{slogObj(obj)}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+
+		if !field.IsExported() ||
+			(field.Name == "Err" && value.IsZero()) {
+			continue
+		}
+
+		attrs = append(attrs, slog.Any(field.Name, value.Interface()))
+	}
+
+	l.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+// slogObj returns the "obj" attribute that identifies the object.
+func slogObj(obj any) slog.Attr {
+	return slog.String("obj", fmt.Sprintf("%p", obj))
+}
@@ -0,0 +1,150 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Structured logging test
+//
+//go:build (linux || freebsd) && go1.21
+
+package avahi
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// testLogger creates Client with logger, writing into the buffer.
+// Time and object pointers are removed from the output, to make
+// it predictable.
+func testLogger(levels *LogLevels) (*Client, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	opts := &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch a.Key {
+			case slog.TimeKey:
+				return slog.Attr{}
+			case "obj":
+				a.Value = slog.StringValue("X")
+			}
+			return a
+		},
+	}
+
+	clnt := &Client{}
+	clnt.SetLogger(slog.New(slog.NewTextHandler(buf, opts)), levels)
+
+	return clnt, buf
+}
+
+// TestLogger tests logging via log/slog
+func TestLogger(t *testing.T) {
+	clnt, buf := testLogger(nil)
+	obj := &ServiceBrowser{}
+
+	if hooks := clnt.hooks(); hooks != nil {
+		hooks.create(context.Background(), obj, &ObjectInfo{
+			Kind:    "ServiceBrowser",
+			IfIdx:   IfIndexUnspec,
+			Proto:   ProtocolUnspec,
+			SvcType: "_ipp._tcp",
+			Domain:  "local",
+		})
+	}
+
+	clnt.notifyEvent("ServiceBrowser", obj, &ServiceBrowserEvent{
+		Event:        BrowserNew,
+		IfIdx:        2,
+		Proto:        ProtocolIP4,
+		InstanceName: "Printer",
+		SvcType:      "_ipp._tcp",
+		Domain:       "local",
+	})
+
+	clnt.notifyEvent("HostNameResolver", obj, &HostNameResolverEvent{
+		Event:    ResolverFound,
+		Hostname: "printer.local",
+		Addr:     netip.MustParseAddr("192.168.0.1"),
+	})
+
+	clnt.notifyEvent("ServiceBrowser", obj, &ServiceBrowserEvent{
+		Event: BrowserFailure,
+		Err:   ErrTimeout,
+	})
+
+	clnt.notifyState("EntryGroup", obj, &EntryGroupEvent{
+		State: EntryGroupStateEstablished,
+	})
+
+	clnt.notifyClose("ServiceBrowser", obj)
+
+	expected := []string{
+		`level=DEBUG msg="ServiceBrowser created" obj=X ifidx=-1 proto=unspec svctype=_ipp._tcp domain=local flags=""`,
+		`level=DEBUG msg="ServiceBrowser event" obj=X Event=BrowserNew IfIdx=2 Proto=ip4 Flags="" InstanceName=Printer SvcType=_ipp._tcp Domain=local`,
+		`level=DEBUG msg="HostNameResolver event" obj=X Event=ResolverFound IfIdx=0 Proto=ip4 Flags="" Hostname=printer.local Addr=192.168.0.1`,
+		`level=WARN msg="ServiceBrowser event" obj=X Event=BrowserFailure IfIdx=0 Proto=ip4 Err="avahi: Timeout reached" Flags="" InstanceName="" SvcType="" Domain=""`,
+		`level=INFO msg="EntryGroup state" obj=X State=established`,
+		`level=DEBUG msg="ServiceBrowser closed" obj=X`,
+	}
+
+	present := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i := range expected {
+		var line string
+		if i < len(present) {
+			line = present[i]
+		}
+
+		if line != expected[i] {
+			t.Errorf("line %d:\n"+
+				"expected: %s\n"+
+				"present:  %s\n",
+				i, expected[i], line)
+		}
+	}
+
+	// Test levels filtering
+	levels := DefaultLogLevels
+	levels.Events = slog.LevelDebug - 1
+	levels.Failures = slog.LevelDebug - 1
+
+	clnt, buf = testLogger(&levels)
+	clnt.notifyEvent("ServiceBrowser", obj, &ServiceBrowserEvent{
+		Event: BrowserFailure,
+		Err:   ErrTimeout,
+	})
+
+	if buf.Len() != 0 {
+		t.Errorf("levels filtering failed: %q", buf.String())
+	}
+
+	// Test that logger can be reset
+	clnt.SetLogger(nil, nil)
+	if clnt.logger() != nil {
+		t.Errorf("SetLogger(nil) failed")
+	}
+}
+
+// TestLoggerDisabled tests that disabled logging doesn't allocate
+func TestLoggerDisabled(t *testing.T) {
+	clnt := &Client{}
+	obj := &ServiceBrowser{}
+	evnt := &ServiceBrowserEvent{Event: BrowserNew}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		clnt.notifyEvent("ServiceBrowser", obj, evnt)
+		clnt.notifyClose("ServiceBrowser", obj)
+		if hooks := clnt.hooks(); hooks != nil {
+			hooks.create(context.Background(), obj,
+				&ObjectInfo{Kind: "ServiceBrowser"})
+		}
+	})
+
+	if allocs != 0 {
+		t.Errorf("disabled logger: %v allocations per call", allocs)
+	}
+}
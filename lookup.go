@@ -0,0 +1,236 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// net.Resolver-style lookup functions
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+// LookupHost looks up the given host (e.g., "printer.local") using
+// the [HostNameResolver] and returns a slice of its addresses.
+//
+// This is the MDNS analogue of the [net.Resolver.LookupHost].
+// Both IPv4 and IPv6 addresses are queried simultaneously, and
+// the function returns when both queries are completed, or
+// context is canceled or expired, whichever occurs first.
+//
+// If the [Client] was created with the [ClientLoopbackWorkarounds]
+// flag, "localhost" and "localhost.localdomain" are resolved without
+// contacting avahi-daemon into 127.0.0.1 and ::1.
+//
+// On success, at least one address is returned. Errors are
+// reported as [*Error], that wraps:
+//   - [ErrTimeout] - context deadline expired before any address
+//     was resolved
+//   - [ErrNotFound] - Avahi was unable to resolve the name (i.e.,
+//     nobody on the network has answered)
+//   - context error - if context was canceled
+//   - any other [ErrCode] - in a case of other Avahi errors
+func LookupHost(ctx context.Context, clnt *Client,
+	name string) ([]netip.Addr, error) {
+
+	addrs, err := lookupHost(ctx, clnt, name, 0)
+	if err != nil {
+		return nil, newError("LookupHost", err, name)
+	}
+
+	return addrs, nil
+}
+
+// lookupHost resolves host name into a slice of addresses.
+//
+// If delay is zero, it waits for the first answer of both IPv4 and
+// IPv6 resolvers. Otherwise, once the first address is resolved,
+// it collects addresses of both families during the delay, as
+// RFC 8305 recommends, and then returns.
+//
+// Errors are returned unwrapped.
+func lookupHost(ctx context.Context, clnt *Client, name string,
+	delay time.Duration) ([]netip.Addr, error) {
+
+	// Handle ClientLoopbackWorkarounds
+	if clnt.hasFlags(ClientLoopbackWorkarounds) && isLocalhost(name) {
+		return []netip.Addr{loopbackIP4, loopbackIP6}, nil
+	}
+
+	// Create resolvers, one per address family
+	resolvers := make([]*HostNameResolver, 0, 2)
+	defer func() {
+		for _, resolver := range resolvers {
+			resolver.Close()
+		}
+	}()
+
+	for _, addrproto := range []Protocol{ProtocolIP4, ProtocolIP6} {
+		resolver, err := NewHostNameResolverContext(ctx, clnt,
+			IfIndexUnspec, ProtocolUnspec, name, addrproto, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resolvers = append(resolvers, resolver)
+	}
+
+	// Wait for results
+	chan4 := resolvers[0].Chan()
+	chan6 := resolvers[1].Chan()
+
+	var addrs []netip.Addr
+	var timer <-chan time.Time
+	var err error
+
+	for (chan4 != nil || chan6 != nil) && ctx.Err() == nil {
+		var evnt *HostNameResolverEvent
+		var from *<-chan *HostNameResolverEvent
+
+		select {
+		case <-ctx.Done():
+			continue
+		case <-timer:
+			chan4, chan6 = nil, nil
+			continue
+		case evnt = <-chan4:
+			from = &chan4
+		case evnt = <-chan6:
+			from = &chan6
+		}
+
+		// With delay, keep reading the resolver until
+		// the delay expires, unless it is done.
+		if delay == 0 || evnt == nil || evnt.Event != ResolverFound {
+			*from = nil
+		}
+
+		switch {
+		case evnt == nil:
+			// Resolver was closed; it only happens
+			// when the Client is closed.
+			err = ErrBadState
+
+		case evnt.Event == ResolverFound:
+			addrs = lookupAppendAddr(addrs, evnt.Addr)
+			if delay != 0 && timer == nil {
+				timer = time.After(delay)
+			}
+
+		case evnt.Event == ResolverFailure && err == nil:
+			err = evnt.Err
+		}
+	}
+
+	if len(addrs) != 0 {
+		return addrs, nil
+	}
+
+	return nil, lookupErr(ctx, err)
+}
+
+// LookupAddr performs a reverse lookup for the given address using
+// the [AddressResolver], returning a list of names mapping to that
+// address.
+//
+// This is the MDNS analogue of the [net.Resolver.LookupAddr]. Unlike
+// its stdlib counterpart, returned names don't have a trailing dot,
+// i.e., the names are returned exactly as Avahi reports them.
+//
+// If IPv6 address contains a zone, resolving is performed on the
+// corresponding network interface only.
+//
+// Errors are reported the same way as by [LookupHost].
+func LookupAddr(ctx context.Context, clnt *Client,
+	addr netip.Addr) ([]string, error) {
+
+	// Obtain network interface index from the zone
+	ifidx := IfIndexUnspec
+	if zone := addr.Zone(); zone != "" {
+		ifidx = lookupZone(zone)
+	}
+
+	// Create resolver
+	resolver, err := NewAddressResolverContext(ctx, clnt, ifidx,
+		ProtocolUnspec, addr, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resolver.Close()
+
+	// Wait for result
+	evnt, err := resolver.Get(ctx)
+	switch {
+	case err != nil:
+		// Context canceled or expired; err will be
+		// mapped below
+
+	case evnt == nil:
+		err = ErrBadState
+
+	case evnt.Event == ResolverFound:
+		return []string{evnt.Hostname}, nil
+
+	default:
+		err = evnt.Err
+	}
+
+	return nil, newError("LookupAddr", lookupErr(ctx, err), addr)
+}
+
+// lookupAppendAddr appends address to the slice of addresses,
+// if it is not already there.
+func lookupAppendAddr(addrs []netip.Addr, addr netip.Addr) []netip.Addr {
+	if !addr.IsValid() {
+		return addrs
+	}
+
+	for _, a := range addrs {
+		if a == addr {
+			return addrs
+		}
+	}
+
+	return append(addrs, addr)
+}
+
+// lookupZone returns network interface index by the IPv6 zone name.
+//
+// It returns IfIndexUnspec if zone cannot be resolved.
+func lookupZone(zone string) IfIndex {
+	if ifi, err := net.InterfaceByName(zone); err == nil {
+		return IfIndex(ifi.Index)
+	}
+
+	if idx, err := strconv.Atoi(zone); err == nil && idx > 0 {
+		return IfIndex(idx)
+	}
+
+	return IfIndexUnspec
+}
+
+// lookupErr maps errors, returned by the lookup functions.
+//
+// err is the error, reported by the resolver, if any.
+func lookupErr(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return ErrTimeout
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case err == nil, err == ErrTimeout:
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,191 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// net.Resolver-style lookup functions test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLookupErr tests lookupErr
+func TestLookupErr(t *testing.T) {
+	expired, cancel1 := context.WithDeadline(context.Background(),
+		time.Now().Add(-time.Second))
+	defer cancel1()
+
+	canceled, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+
+	alive := context.Background()
+
+	type testData struct {
+		name     string          // Test name
+		ctx      context.Context // Lookup context
+		err      error           // Error, reported by resolver
+		expected error           // Expected error
+	}
+
+	tests := []testData{
+		{"deadline", expired, nil, ErrTimeout},
+		{"deadline+err", expired, ErrNoDaemon, ErrTimeout},
+		{"canceled", canceled, nil, context.Canceled},
+		{"timeout", alive, ErrTimeout, ErrNotFound},
+		{"nil", alive, nil, ErrNotFound},
+		{"other", alive, ErrNoDaemon, ErrNoDaemon},
+	}
+
+	for _, test := range tests {
+		err := lookupErr(test.ctx, test.err)
+		if err != test.expected {
+			t.Errorf("%s:\n"+
+				"expected: %v\n"+
+				"present:  %v\n",
+				test.name, test.expected, err)
+		}
+	}
+}
+
+// TestLookupAppendAddr tests lookupAppendAddr
+func TestLookupAppendAddr(t *testing.T) {
+	ip4 := netip.MustParseAddr("192.168.0.1")
+	ip6 := netip.MustParseAddr("fe80::1%2")
+
+	type testData struct {
+		addrs    []netip.Addr // Initial slice
+		addr     netip.Addr   // Appended address
+		expected []netip.Addr // Expected result
+	}
+
+	tests := []testData{
+		{nil, ip4, []netip.Addr{ip4}},
+		{[]netip.Addr{ip4}, ip6, []netip.Addr{ip4, ip6}},
+		{[]netip.Addr{ip4, ip6}, ip4, []netip.Addr{ip4, ip6}},
+		{
+			[]netip.Addr{ip6},
+			ip6.WithZone(""),
+			[]netip.Addr{ip6, ip6.WithZone("")},
+		},
+		{[]netip.Addr{ip4}, netip.Addr{}, []netip.Addr{ip4}},
+	}
+
+	for _, test := range tests {
+		addrs := lookupAppendAddr(test.addrs, test.addr)
+		if !reflect.DeepEqual(addrs, test.expected) {
+			t.Errorf("lookupAppendAddr(%v, %v):\n"+
+				"expected: %v\n"+
+				"present:  %v\n",
+				test.addrs, test.addr, test.expected, addrs)
+		}
+	}
+}
+
+// TestLookupZone tests lookupZone
+func TestLookupZone(t *testing.T) {
+	type testData struct {
+		zone  string  // Input zone
+		ifidx IfIndex // Expected result
+	}
+
+	tests := []testData{
+		{"3", 3},
+		{"0", IfIndexUnspec},
+		{"-1", IfIndexUnspec},
+		{"no-such-interface", IfIndexUnspec},
+	}
+
+	if ifi, err := net.InterfaceByName("lo"); err == nil {
+		tests = append(tests, testData{"lo", IfIndex(ifi.Index)})
+	}
+
+	for _, test := range tests {
+		ifidx := lookupZone(test.zone)
+		if ifidx != test.ifidx {
+			t.Errorf("lookupZone(%q):\n"+
+				"expected: %d\n"+
+				"present:  %d\n",
+				test.zone, test.ifidx, ifidx)
+		}
+	}
+}
+
+// TestLookupHost tests LookupHost with the replay Client
+func TestLookupHost(t *testing.T) {
+	capture := strings.Join([]string{
+		`{"time":"2024-01-01T00:00:00Z","client":{"state":"running","hostname":"host","domain":"local","fqdn":"host.local","version":"avahi 0.8"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"HostNameResolver","created":{"Kind":"HostNameResolver","IfIdx":-1,"Proto":"unspec","Name":"printer.local","AddrProto":"ip4"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"HostNameResolver","event":{"Event":"ResolverFound","IfIdx":2,"Proto":"ip4","Flags":"mdns","Hostname":"printer.local","Addr":"192.168.0.1"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"HostNameResolver","created":{"Kind":"HostNameResolver","IfIdx":-1,"Proto":"unspec","Name":"printer.local","AddrProto":"ip6"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"HostNameResolver","event":{"Event":"ResolverFound","IfIdx":2,"Proto":"ip6","Flags":"mdns","Hostname":"printer.local","Addr":"fe80::1%2"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":3,"kind":"HostNameResolver","created":{"Kind":"HostNameResolver","IfIdx":-1,"Proto":"unspec","Name":"scanner.local","AddrProto":"ip4"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":3,"kind":"HostNameResolver","event":{"Event":"ResolverFailure","IfIdx":-1,"Proto":"unspec","Err":"avahi: Timeout reached","Hostname":"scanner.local"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":4,"kind":"HostNameResolver","created":{"Kind":"HostNameResolver","IfIdx":-1,"Proto":"unspec","Name":"scanner.local","AddrProto":"ip6"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":4,"kind":"HostNameResolver","event":{"Event":"ResolverFailure","IfIdx":-1,"Proto":"unspec","Err":"avahi: Timeout reached","Hostname":"scanner.local"}}`,
+	}, "\n") + "\n"
+
+	clnt, err := NewReplayClient(strings.NewReader(capture),
+		ClientLoopbackWorkarounds)
+	if err != nil {
+		t.Fatalf("NewReplayClient: %s", err)
+	}
+
+	defer clnt.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	type testData struct {
+		name  string       // Host name
+		addrs []netip.Addr // Expected addresses
+		err   error        // Expected error
+	}
+
+	tests := []testData{
+		{
+			name: "printer.local",
+			addrs: []netip.Addr{
+				netip.MustParseAddr("192.168.0.1"),
+				netip.MustParseAddr("fe80::1%2"),
+			},
+		},
+
+		{
+			name:  "localhost",
+			addrs: []netip.Addr{loopbackIP4, loopbackIP6},
+		},
+
+		{
+			name: "scanner.local",
+			err:  ErrNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		// Order of addresses of different families is not defined
+		addrs, err := LookupHost(ctx, clnt, test.name)
+		sort.Slice(addrs, func(i, j int) bool {
+			return addrs[i].Less(addrs[j])
+		})
+
+		if !reflect.DeepEqual(addrs, test.addrs) ||
+			!errors.Is(err, test.err) {
+			t.Errorf("LookupHost(%q):\n"+
+				"expected: %v (%v)\n"+
+				"present:  %v (%v)\n",
+				test.name, test.addrs, test.err, addrs, err)
+		}
+	}
+}
@@ -9,10 +9,6 @@
 
 package avahi
 
-import (
-	"strings"
-)
-
 // #include <avahi-common/defs.h>
 import "C"
 
@@ -51,24 +47,37 @@ const (
 	LookupNoAddress LookupFlags = C.AVAHI_LOOKUP_NO_ADDRESS
 )
 
+// lookupFlagsNames contains names of LookupFlags bits.
+var lookupFlagsNames = []flagName[LookupFlags]{
+	{LookupUseWideArea, "use-wan"},
+	{LookupUseMulticast, "use-mdns"},
+	{LookupNoTXT, "no-txt"},
+	{LookupNoAddress, "no-addr"},
+}
+
 // String returns LookupFlags as string, for debugging
 func (flags LookupFlags) String() string {
-	s := []string{}
-
-	if flags&LookupUseWideArea != 0 {
-		s = append(s, "use-wan")
-	}
-	if flags&LookupUseMulticast != 0 {
-		s = append(s, "use-mdns")
-	}
-	if flags&LookupNoTXT != 0 {
-		s = append(s, "no-txt")
-	}
-	if flags&LookupNoAddress != 0 {
-		s = append(s, "no-addr")
-	}
-
-	return strings.Join(s, ",")
+	return flagsString(flags, lookupFlagsNames, ",")
+}
+
+// MarshalText returns LookupFlags as "|"-separated list of names.
+// It implements the [encoding.TextMarshaler] interface.
+func (flags LookupFlags) MarshalText() ([]byte, error) {
+	return flagsMarshalText(flags, lookupFlagsNames)
+}
+
+// UnmarshalText decodes LookupFlags from "|"-separated list of names.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (flags *LookupFlags) UnmarshalText(text []byte) error {
+	return flagsUnmarshalText("LookupFlags.UnmarshalText", flags, text,
+		lookupFlagsNames)
+}
+
+// UnmarshalJSON decodes LookupFlags from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (flags *LookupFlags) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("LookupFlags.UnmarshalJSON", flags, data,
+		flags.UnmarshalText)
 }
 
 // LookupResultFlags provides some additional information about
@@ -99,28 +108,37 @@ const (
 	LookupResultStatic LookupResultFlags = C.AVAHI_LOOKUP_RESULT_STATIC
 )
 
+// lookupResultFlagsNames contains names of LookupResultFlags bits.
+var lookupResultFlagsNames = []flagName[LookupResultFlags]{
+	{LookupResultCached, "cached"},
+	{LookupResultWideArea, "wan-dns"},
+	{LookupResultMulticast, "mdns"},
+	{LookupResultLocal, "local"},
+	{LookupResultOurOwn, "our-own"},
+	{LookupResultStatic, "static"},
+}
+
 // String returns LookupResultFlags as string, for debugging
 func (flags LookupResultFlags) String() string {
-	s := []string{}
-
-	if flags&LookupResultCached != 0 {
-		s = append(s, "cached")
-	}
-	if flags&LookupResultWideArea != 0 {
-		s = append(s, "wan-dns")
-	}
-	if flags&LookupResultMulticast != 0 {
-		s = append(s, "mdns")
-	}
-	if flags&LookupResultCached != 0 {
-		s = append(s, "local")
-	}
-	if flags&LookupResultOurOwn != 0 {
-		s = append(s, "our-own")
-	}
-	if flags&LookupResultStatic != 0 {
-		s = append(s, "static")
-	}
-
-	return strings.Join(s, ",")
+	return flagsString(flags, lookupResultFlagsNames, ",")
+}
+
+// MarshalText returns LookupResultFlags as "|"-separated list of names.
+// It implements the [encoding.TextMarshaler] interface.
+func (flags LookupResultFlags) MarshalText() ([]byte, error) {
+	return flagsMarshalText(flags, lookupResultFlagsNames)
+}
+
+// UnmarshalText decodes LookupResultFlags from "|"-separated list of names.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (flags *LookupResultFlags) UnmarshalText(text []byte) error {
+	return flagsUnmarshalText("LookupResultFlags.UnmarshalText", flags, text,
+		lookupResultFlagsNames)
+}
+
+// UnmarshalJSON decodes LookupResultFlags from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (flags *LookupResultFlags) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("LookupResultFlags.UnmarshalJSON", flags, data,
+		flags.UnmarshalText)
 }
@@ -0,0 +1,42 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Lookup flags test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import "testing"
+
+// TestLookupFlagsString tests LookupFlags.String and
+// LookupResultFlags.String
+func TestLookupFlagsString(t *testing.T) {
+	type testData struct {
+		flags interface{ String() string } // Flags to format
+		s     string                       // Expected string
+	}
+
+	tests := []testData{
+		{LookupFlags(0), ""},
+		{LookupUseMulticast, "use-mdns"},
+		{LookupUseWideArea | LookupNoTXT, "use-wan,no-txt"},
+		{LookupResultFlags(0), ""},
+		{LookupResultCached, "cached"},
+		{LookupResultLocal, "local"},
+		{LookupResultMulticast | LookupResultLocal, "mdns,local"},
+		{LookupResultCached | LookupResultOurOwn, "cached,our-own"},
+	}
+
+	for _, test := range tests {
+		s := test.flags.String()
+		if s != test.s {
+			t.Errorf("%#v:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.flags, test.s, s)
+		}
+	}
+}
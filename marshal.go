@@ -0,0 +1,162 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Text marshaling of enums and flags
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// flagName contains a name of a single bit of the bit-flag type.
+type flagName[T ~int] struct {
+	flag T      // Flag bit
+	name string // Flag name
+}
+
+// enumMarshalText implements encoding.TextMarshaler for enum types.
+//
+// Known values are marshaled by name, unknown by number.
+func enumMarshalText[T ~int](v T, names map[T]string) ([]byte, error) {
+	if n, found := names[v]; found {
+		return []byte(n), nil
+	}
+	return []byte(strconv.Itoa(int(v))), nil
+}
+
+// enumUnmarshalText implements encoding.TextUnmarshaler for enum types.
+//
+// It accepts names (case-insensitive) and numbers.
+func enumUnmarshalText[T ~int](op string, p *T, text []byte,
+	names map[T]string) error {
+
+	s := strings.TrimSpace(string(text))
+	for v, n := range names {
+		if strings.EqualFold(s, n) {
+			*p = v
+			return nil
+		}
+	}
+
+	v, err := strconv.ParseInt(s, 0, 0)
+	if err != nil {
+		return newError(op, ErrInvalidArgument, string(text))
+	}
+
+	*p = T(v)
+	return nil
+}
+
+// flagsNames returns names of the known bits of the bit-flags
+// and the remaining unknown bits.
+func flagsNames[T ~int](flags T, names []flagName[T]) ([]string, T) {
+	s := []string{}
+
+	for _, fn := range names {
+		if flags&fn.flag != 0 {
+			s = append(s, fn.name)
+			flags &^= fn.flag
+		}
+	}
+
+	return s, flags
+}
+
+// flagsString formats bit-flags as a list of names, separated
+// by the sep. Unknown bits are ignored.
+func flagsString[T ~int](flags T, names []flagName[T], sep string) string {
+	s, _ := flagsNames(flags, names)
+	return strings.Join(s, sep)
+}
+
+// flagsMarshalText implements encoding.TextMarshaler for the bit-flag
+// types. Flags are marshaled as "|"-separated list of names. Unknown
+// bits are marshaled as hex number, so they are not lost.
+func flagsMarshalText[T ~int](flags T, names []flagName[T]) ([]byte, error) {
+	s, unknown := flagsNames(flags, names)
+	if unknown != 0 {
+		s = append(s, fmt.Sprintf("%#x", int(unknown)))
+	}
+
+	return []byte(strings.Join(s, "|")), nil
+}
+
+// flagsUnmarshalText implements encoding.TextUnmarshaler for the
+// bit-flag types.
+//
+// It accepts "|"-separated list of names (case-insensitive) and
+// numbers. Empty string means no flags.
+func flagsUnmarshalText[T ~int](op string, p *T, text []byte,
+	names []flagName[T]) error {
+
+	var flags T
+
+	for _, s := range strings.Split(string(text), "|") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		found := false
+		for _, fn := range names {
+			if strings.EqualFold(s, fn.name) {
+				flags |= fn.flag
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			v, err := strconv.ParseInt(s, 0, 0)
+			if err != nil {
+				return newError(op, ErrInvalidArgument,
+					string(text))
+			}
+			flags |= T(v)
+		}
+	}
+
+	*p = flags
+	return nil
+}
+
+// numericUnmarshalJSON implements json.Unmarshaler for enum and bit-flag
+// types.
+//
+// Captures, written before these types got their text representation,
+// contain them as JSON numbers, and encoding/json never passes JSON
+// numbers to the UnmarshalText. So JSON numbers are decoded here
+// directly, and JSON strings are passed to the unmarshalText.
+func numericUnmarshalJSON[T ~int](op string, p *T, data []byte,
+	unmarshalText func([]byte) error) error {
+
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return newError(op, ErrInvalidArgument, string(data))
+		}
+		return unmarshalText([]byte(s))
+	}
+
+	var v int
+	if err := json.Unmarshal(data, &v); err != nil {
+		return newError(op, ErrInvalidArgument, string(data))
+	}
+
+	*p = T(v)
+	return nil
+}
@@ -0,0 +1,216 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Text marshaling test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+// TestMarshalText tests MarshalText and UnmarshalText of enums and flags
+func TestMarshalText(t *testing.T) {
+	type testData struct {
+		v    encoding.TextMarshaler // Value to marshal
+		text string                 // Expected text
+	}
+
+	tests := []testData{
+		{BrowserNew, "BrowserNew"},
+		{BrowserFailure, "BrowserFailure"},
+		{BrowserEvent(100), "100"},
+		{ResolverFound, "ResolverFound"},
+		{ProtocolIP6, "ip6"},
+		{ProtocolUnspec, "unspec"},
+		{ClientStateRunning, "running"},
+		{EntryGroupStateCollision, "collision"},
+		{DomainBrowserRegisterDefault, "register-default"},
+		{DomainBrowserType(100), "100"},
+		{DNSClassIN, "IN"},
+		{DNSTypeAAAA, "AAAA"},
+		{DNSType(65), "65"},
+		{ErrTimeout, "avahi: Timeout reached"},
+		{NoError, "avahi: OK"},
+		{ClientLoopbackWorkarounds, "loopback-workarounds"},
+		{LookupFlags(0), ""},
+		{LookupUseMulticast | LookupNoTXT, "use-mdns|no-txt"},
+		{LookupResultCached | LookupResultMulticast, "cached|mdns"},
+		{LookupResultFlags(0x1000), "0x1000"},
+		{PublishUnique | PublishNoProbe, "unique|no-probe"},
+	}
+
+	for _, test := range tests {
+		data, err := test.v.MarshalText()
+		if err != nil || string(data) != test.text {
+			t.Errorf("%#v.MarshalText:\n"+
+				"expected: %q\n"+
+				"present:  %q (%v)\n",
+				test.v, test.text, data, err)
+			continue
+		}
+
+		// Decode back into the new value of the same type
+		p := reflect.New(reflect.TypeOf(test.v))
+		err = p.Interface().(encoding.TextUnmarshaler).
+			UnmarshalText(data)
+
+		v := p.Elem().Interface()
+		if err != nil || v != test.v {
+			t.Errorf("%#v.UnmarshalText(%q):\n"+
+				"expected: %#v\n"+
+				"present:  %#v (%v)\n",
+				test.v, data, test.v, v, err)
+		}
+	}
+
+	// Numeric form of unknown values is used only for marshaling,
+	// String output is not affected
+	stringers := []struct {
+		v fmt.Stringer // Value to format
+		s string       // Expected string
+	}{
+		{DomainBrowserType(100), "UNKNOWN 100"},
+		{Protocol(100), "UNKNOWN 100"},
+		{LookupResultCached | 0x1000, "cached"},
+		{PublishUnique | 0x10000, "unique"},
+	}
+
+	for _, test := range stringers {
+		s := test.v.String()
+		if s != test.s {
+			t.Errorf("%#v.String:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.v, test.s, s)
+		}
+	}
+}
+
+// TestUnmarshalText tests UnmarshalText with non-canonical and
+// invalid input
+func TestUnmarshalText(t *testing.T) {
+	var proto Protocol
+	if err := proto.UnmarshalText([]byte("IP4")); err != nil ||
+		proto != ProtocolIP4 {
+		t.Errorf("Protocol.UnmarshalText(%q): %v, %v", "IP4", proto, err)
+	}
+
+	if err := proto.UnmarshalText([]byte("-1")); err != nil ||
+		proto != ProtocolUnspec {
+		t.Errorf("Protocol.UnmarshalText(%q): %v, %v", "-1", proto, err)
+	}
+
+	var flags LookupResultFlags
+	err := flags.UnmarshalText([]byte(" Cached | 0x1000 "))
+	if err != nil || flags != LookupResultCached|0x1000 {
+		t.Errorf("LookupResultFlags.UnmarshalText: %v, %v", flags, err)
+	}
+
+	var code ErrCode
+	err = code.UnmarshalText([]byte("Timeout reached"))
+	if err != nil || code != ErrTimeout {
+		t.Errorf("ErrCode.UnmarshalText: %v, %v", code, err)
+	}
+
+	// Invalid input
+	invalid := []struct {
+		p    encoding.TextUnmarshaler
+		text string
+	}{
+		{new(Protocol), "ip5"},
+		{new(BrowserEvent), ""},
+		{new(DNSType), "TYPE"},
+		{new(LookupFlags), "use-mdns|no-such-flag"},
+		{new(ErrCode), "avahi: no such error"},
+	}
+
+	for _, test := range invalid {
+		err := test.p.UnmarshalText([]byte(test.text))
+		if !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%T.UnmarshalText(%q): error not detected",
+				test.p, test.text)
+		}
+	}
+}
+
+// TestMarshalJSON tests JSON encoding of events
+func TestMarshalJSON(t *testing.T) {
+	evnt := &RecordBrowserEvent{
+		Event:  BrowserNew,
+		IfIdx:  2,
+		Proto:  ProtocolIP6,
+		Err:    NoError,
+		Flags:  LookupResultMulticast | LookupResultLocal,
+		Name:   "printer.local",
+		RClass: DNSClassIN,
+		RType:  DNSTypeAAAA,
+		RData:  netip.MustParseAddr("fe80::1").AsSlice(),
+	}
+
+	expected := `{"Event":"BrowserNew","IfIdx":2,"Proto":"ip6",` +
+		`"Err":"avahi: OK","Flags":"mdns|local",` +
+		`"Name":"printer.local","RClass":"IN","RType":"AAAA",` +
+		`"RData":"/oAAAAAAAAAAAAAAAAAAAQ=="}`
+
+	data, err := json.Marshal(evnt)
+	if err != nil || string(data) != expected {
+		t.Errorf("json.Marshal:\n"+
+			"expected: %s\n"+
+			"present:  %s (%v)\n",
+			expected, data, err)
+	}
+
+	var evnt2 *RecordBrowserEvent
+	err = json.Unmarshal(data, &evnt2)
+	if err != nil || !reflect.DeepEqual(evnt, evnt2) {
+		t.Errorf("json.Unmarshal:\n"+
+			"expected: %#v\n"+
+			"present:  %#v (%v)\n",
+			evnt, evnt2, err)
+	}
+
+	// Captures, written by older versions, contain enums and
+	// flags as numbers
+	old := `{"Event":0,"IfIdx":2,"Proto":1,"Err":0,"Flags":12,` +
+		`"Name":"printer.local","RClass":1,"RType":28,` +
+		`"RData":"/oAAAAAAAAAAAAAAAAAAAQ=="}`
+
+	evnt2 = nil
+	err = json.Unmarshal([]byte(old), &evnt2)
+	if err != nil || !reflect.DeepEqual(evnt, evnt2) {
+		t.Errorf("json.Unmarshal (numeric):\n"+
+			"expected: %#v\n"+
+			"present:  %#v (%v)\n",
+			evnt, evnt2, err)
+	}
+
+	// Invalid JSON values
+	invalid := []struct {
+		p    json.Unmarshaler
+		data string
+	}{
+		{new(Protocol), `"ip5"`},
+		{new(Protocol), `1.5`},
+		{new(LookupResultFlags), `true`},
+		{new(ErrCode), `{}`},
+	}
+
+	for _, test := range invalid {
+		err := test.p.UnmarshalJSON([]byte(test.data))
+		if !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%T.UnmarshalJSON(%s): error not detected",
+				test.p, test.data)
+		}
+	}
+}
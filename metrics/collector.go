@@ -0,0 +1,385 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Prometheus metrics collector
+//
+//go:build linux || freebsd
+
+// Package metrics exports statistics of the avahi package objects
+// (Client, browsers, resolvers, entry groups) as Prometheus/OpenMetrics
+// metrics.
+//
+// It lives in a separate module, so the core avahi package doesn't
+// depend on the Prometheus client library.
+//
+// Usage:
+//
+//	clnt, err := avahi.NewClient(0)
+//	...
+//	collector := metrics.NewCollector()
+//	clnt.SetObserver(collector)
+//	prometheus.MustRegister(collector)
+//
+// Only objects, created after the [avahi.Client.SetObserver] call,
+// are accounted. The following metrics are exported:
+//
+//	avahi_objects{kind}                       - objects currently open
+//	avahi_objects_created_total{kind}         - objects created
+//	avahi_events_total{kind,event}            - events, reported by objects
+//	avahi_services{svctype}                   - unique services currently discovered
+//	avahi_resolve_duration_seconds{kind}      - time to the first result
+//	avahi_resolver_failures_total{kind,error} - resolver failures
+//	avahi_event_queue_length{kind}            - events, not consumed yet
+//	avahi_entry_group_states_total{state}     - EntryGroup state changes
+//	avahi_entry_group_collisions_total        - EntryGroup name collisions
+//	avahi_client_states_total{state}          - Client state changes
+//
+// The same Collector may be attached to multiple Clients.
+package metrics
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenPrinting/go-avahi"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector collects statistics of the avahi objects.
+//
+// It implements both [avahi.Observer], to be attached to the
+// [avahi.Client] with [avahi.Client.SetObserver], and
+// [prometheus.Collector], to be registered with the
+// [prometheus.Registerer].
+type Collector struct {
+	lock    sync.Mutex            // Access lock
+	objects map[any]*object       // Currently open objects
+	now     func() time.Time      // Current time, replaceable by tests
+	created map[string]float64    // Objects created, by kind
+	events  map[[2]string]float64 // Events, by kind and event
+
+	// Metrics, maintained by the Prometheus client library
+	resolveDuration  *prometheus.HistogramVec
+	resolverFailures *prometheus.CounterVec
+	entryGroupStates *prometheus.CounterVec
+	collisions       prometheus.Counter
+	clientStates     *prometheus.CounterVec
+
+	// Descriptors of metrics, computed at the Collect time
+	objectsDesc  *prometheus.Desc
+	createdDesc  *prometheus.Desc
+	eventsDesc   *prometheus.Desc
+	servicesDesc *prometheus.Desc
+	queueLenDesc *prometheus.Desc
+}
+
+// object represents the avahi object, known to the Collector.
+type object struct {
+	kind      string                // Object kind
+	started   time.Time             // Creation time
+	resolved  bool                  // Resolver reported its first result
+	queueLen  func() int            // Returns event queue length
+	instances map[instance]struct{} // ServiceBrowser's instances
+}
+
+// instance identifies the service instance, discovered by the
+// ServiceBrowser.
+type instance struct {
+	ifidx    avahi.IfIndex  // Network interface index
+	proto    avahi.Protocol // Network protocol
+	instname string         // Instance name
+	svctype  string         // Service type
+	domain   string         // Service domain
+}
+
+// Metrics namespace
+const namespace = "avahi"
+
+// NewCollector creates a new [Collector].
+func NewCollector() *Collector {
+	c := &Collector{
+		objects: make(map[any]*object),
+		now:     time.Now,
+		created: make(map[string]float64),
+		events:  make(map[[2]string]float64),
+
+		resolveDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "resolve_duration_seconds",
+				Help: "Time from the resolver creation " +
+					"to its first result.",
+				Buckets: []float64{
+					0.001, 0.005, 0.01, 0.05, 0.1,
+					0.25, 0.5, 1, 2.5, 5, 10,
+				},
+			},
+			[]string{"kind"}),
+
+		resolverFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "resolver_failures_total",
+				Help:      "Resolver failures, by error.",
+			},
+			[]string{"kind", "error"}),
+
+		entryGroupStates: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "entry_group_states_total",
+				Help:      "EntryGroup state changes.",
+			},
+			[]string{"state"}),
+
+		collisions: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "entry_group_collisions_total",
+				Help:      "EntryGroup name collisions.",
+			}),
+
+		clientStates: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "client_states_total",
+				Help:      "Client state changes.",
+			},
+			[]string{"state"}),
+
+		objectsDesc: prometheus.NewDesc(
+			namespace+"_objects",
+			"Objects currently open.",
+			[]string{"kind"}, nil),
+
+		createdDesc: prometheus.NewDesc(
+			namespace+"_objects_created_total",
+			"Objects created.",
+			[]string{"kind"}, nil),
+
+		eventsDesc: prometheus.NewDesc(
+			namespace+"_events_total",
+			"Events, reported by objects.",
+			[]string{"kind", "event"}, nil),
+
+		servicesDesc: prometheus.NewDesc(
+			namespace+"_services",
+			"Service instances currently discovered, by type.",
+			[]string{"svctype"}, nil),
+
+		queueLenDesc: prometheus.NewDesc(
+			namespace+"_event_queue_length",
+			"Events, queued by objects and not consumed yet.",
+			[]string{"kind"}, nil),
+	}
+
+	return c
+}
+
+// Created is called when object is created.
+// It implements the [avahi.Observer] interface.
+func (c *Collector) Created(ctx context.Context, obj any,
+	info *avahi.ObjectInfo) {
+
+	o := &object{
+		kind:     info.Kind,
+		started:  c.now(),
+		queueLen: info.QueueLen,
+	}
+
+	if info.Kind == "ServiceBrowser" {
+		o.instances = make(map[instance]struct{})
+	}
+
+	c.lock.Lock()
+	c.objects[obj] = o
+	c.created[info.Kind]++
+	c.lock.Unlock()
+}
+
+// Closed is called when object is closed.
+// It implements the [avahi.Observer] interface.
+func (c *Collector) Closed(obj any) {
+	c.lock.Lock()
+	delete(c.objects, obj)
+	c.lock.Unlock()
+}
+
+// Event is called for each event, generated by object.
+// It implements the [avahi.Observer] interface.
+func (c *Collector) Event(obj, evnt any) {
+	switch evnt := evnt.(type) {
+	case *avahi.ClientEvent:
+		c.clientStates.WithLabelValues(evnt.State.String()).Inc()
+
+	case *avahi.EntryGroupEvent:
+		c.entryGroupStates.WithLabelValues(evnt.State.String()).Inc()
+		if evnt.State == avahi.EntryGroupStateCollision {
+			c.collisions.Inc()
+		}
+
+	case *avahi.ServiceBrowserEvent:
+		c.browserEvent("ServiceBrowser", evnt.Event)
+
+		c.lock.Lock()
+		if o := c.objects[obj]; o != nil && o.instances != nil {
+			inst := instance{
+				ifidx:    evnt.IfIdx,
+				proto:    evnt.Proto,
+				instname: evnt.InstanceName,
+				svctype:  evnt.SvcType,
+				domain:   evnt.Domain,
+			}
+
+			switch evnt.Event {
+			case avahi.BrowserNew:
+				o.instances[inst] = struct{}{}
+			case avahi.BrowserRemove:
+				delete(o.instances, inst)
+			}
+		}
+		c.lock.Unlock()
+
+	case *avahi.ServiceTypeBrowserEvent:
+		c.browserEvent("ServiceTypeBrowser", evnt.Event)
+
+	case *avahi.DomainBrowserEvent:
+		c.browserEvent("DomainBrowser", evnt.Event)
+
+	case *avahi.RecordBrowserEvent:
+		c.browserEvent("RecordBrowser", evnt.Event)
+
+	case *avahi.ServiceResolverEvent:
+		c.resolverEvent(obj, "ServiceResolver", evnt.Event, evnt.Err)
+
+	case *avahi.HostNameResolverEvent:
+		c.resolverEvent(obj, "HostNameResolver", evnt.Event, evnt.Err)
+
+	case *avahi.AddressResolverEvent:
+		c.resolverEvent(obj, "AddressResolver", evnt.Event, evnt.Err)
+	}
+}
+
+// browserEvent accounts event, reported by browser.
+func (c *Collector) browserEvent(kind string, event avahi.BrowserEvent) {
+
+	c.lock.Lock()
+	c.events[[2]string{kind, event.String()}]++
+	c.lock.Unlock()
+}
+
+// resolverEvent accounts event, reported by resolver.
+func (c *Collector) resolverEvent(obj any, kind string,
+	event avahi.ResolverEvent, err avahi.ErrCode) {
+
+	c.lock.Lock()
+	c.events[[2]string{kind, event.String()}]++
+
+	var elapsed time.Duration
+	o := c.objects[obj]
+	first := o != nil && !o.resolved
+	if first {
+		o.resolved = true
+		elapsed = c.now().Sub(o.started)
+	}
+	c.lock.Unlock()
+
+	if first {
+		c.resolveDuration.WithLabelValues(kind).
+			Observe(elapsed.Seconds())
+	}
+
+	if event == avahi.ResolverFailure {
+		c.resolverFailures.WithLabelValues(kind, errLabel(err)).Inc()
+	}
+}
+
+// Describe sends descriptors of all metrics to the channel.
+// It implements the [prometheus.Collector] interface.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.objectsDesc
+	ch <- c.createdDesc
+	ch <- c.eventsDesc
+	ch <- c.servicesDesc
+	ch <- c.queueLenDesc
+
+	c.resolveDuration.Describe(ch)
+	c.resolverFailures.Describe(ch)
+	c.entryGroupStates.Describe(ch)
+	c.collisions.Describe(ch)
+	c.clientStates.Describe(ch)
+}
+
+// Collect sends all metrics to the channel.
+// It implements the [prometheus.Collector] interface.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+
+	// Export metrics, computed at the Collect time
+	objects := make(map[string]float64)
+	services := make(map[string]float64)
+	queues := make(map[string]float64)
+
+	// The same service instance may be reported by many browsers
+	// and on many interfaces and protocols, so count it only once.
+	seen := make(map[instance]struct{})
+
+	for _, o := range c.objects {
+		objects[o.kind]++
+		for inst := range o.instances {
+			key := instance{
+				instname: inst.instname,
+				svctype:  avahi.DomainToLower(inst.svctype),
+				domain:   avahi.DomainCanonical(inst.domain),
+			}
+
+			if _, dup := seen[key]; !dup {
+				seen[key] = struct{}{}
+				services[key.svctype]++
+			}
+		}
+		if o.queueLen != nil {
+			queues[o.kind] += float64(o.queueLen())
+		}
+	}
+
+	for kind, n := range c.created {
+		ch <- prometheus.MustNewConstMetric(c.createdDesc,
+			prometheus.CounterValue, n, kind)
+		ch <- prometheus.MustNewConstMetric(c.objectsDesc,
+			prometheus.GaugeValue, objects[kind], kind)
+	}
+
+	for key, n := range c.events {
+		ch <- prometheus.MustNewConstMetric(c.eventsDesc,
+			prometheus.CounterValue, n, key[0], key[1])
+	}
+
+	for svctype, n := range services {
+		ch <- prometheus.MustNewConstMetric(c.servicesDesc,
+			prometheus.GaugeValue, n, svctype)
+	}
+
+	for kind, n := range queues {
+		ch <- prometheus.MustNewConstMetric(c.queueLenDesc,
+			prometheus.GaugeValue, n, kind)
+	}
+
+	c.lock.Unlock()
+
+	// Export metrics, maintained by the Prometheus client library
+	c.resolveDuration.Collect(ch)
+	c.resolverFailures.Collect(ch)
+	c.entryGroupStates.Collect(ch)
+	c.collisions.Collect(ch)
+	c.clientStates.Collect(ch)
+}
+
+// errLabel returns the "error" label value for the ErrCode.
+func errLabel(err avahi.ErrCode) string {
+	return strings.TrimPrefix(err.Error(), "avahi: ")
+}
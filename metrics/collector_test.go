@@ -0,0 +1,228 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Prometheus metrics collector test
+//
+//go:build linux || freebsd
+
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenPrinting/go-avahi"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// testCollector creates Collector with the fake clock, which
+// advances by 100ms on each reading.
+func testCollector() *Collector {
+	c := NewCollector()
+
+	now := time.Unix(0, 0)
+	c.now = func() time.Time {
+		now = now.Add(100 * time.Millisecond)
+		return now
+	}
+
+	return c
+}
+
+// TestCollector tests Collector, fed by the synthetic Observer calls.
+func TestCollector(t *testing.T) {
+	c := testCollector()
+	ctx := context.Background()
+
+	// Client and EntryGroup states
+	clnt := &avahi.Client{}
+	c.Event(clnt, &avahi.ClientEvent{State: avahi.ClientStateRunning})
+
+	egrp := &avahi.EntryGroup{}
+	c.Created(ctx, egrp, &avahi.ObjectInfo{Kind: "EntryGroup"})
+	c.Event(egrp, &avahi.EntryGroupEvent{
+		State: avahi.EntryGroupStateRegistering})
+	c.Event(egrp, &avahi.EntryGroupEvent{
+		State: avahi.EntryGroupStateCollision})
+
+	// ServiceBrowser with pending events
+	browser := &avahi.ServiceBrowser{}
+	c.Created(ctx, browser, &avahi.ObjectInfo{
+		Kind:     "ServiceBrowser",
+		SvcType:  "_ipp._tcp",
+		QueueLen: func() int { return 3 },
+	})
+
+	for _, name := range []string{"Printer 1", "Printer 2"} {
+		c.Event(browser, &avahi.ServiceBrowserEvent{
+			Event:        avahi.BrowserNew,
+			InstanceName: name,
+			SvcType:      "_ipp._tcp",
+			Domain:       "local",
+		})
+	}
+
+	c.Event(browser, &avahi.ServiceBrowserEvent{
+		Event:        avahi.BrowserRemove,
+		InstanceName: "Printer 2",
+		SvcType:      "_ipp._tcp",
+		Domain:       "local",
+	})
+
+	// Resolvers: the successful one and the failed one.
+	// Only the first event is accounted for latency.
+	resolver := &avahi.ServiceResolver{}
+	c.Created(ctx, resolver, &avahi.ObjectInfo{Kind: "ServiceResolver"})
+	c.Event(resolver, &avahi.ServiceResolverEvent{
+		Event: avahi.ResolverFound})
+	c.Event(resolver, &avahi.ServiceResolverEvent{
+		Event: avahi.ResolverFound})
+	c.Closed(resolver)
+
+	resolver = &avahi.ServiceResolver{}
+	c.Created(ctx, resolver, &avahi.ObjectInfo{Kind: "ServiceResolver"})
+	c.Event(resolver, &avahi.ServiceResolverEvent{
+		Event: avahi.ResolverFailure,
+		Err:   avahi.ErrTimeout,
+	})
+
+	expected := `
+# HELP avahi_client_states_total Client state changes.
+# TYPE avahi_client_states_total counter
+avahi_client_states_total{state="running"} 1
+# HELP avahi_entry_group_collisions_total EntryGroup name collisions.
+# TYPE avahi_entry_group_collisions_total counter
+avahi_entry_group_collisions_total 1
+# HELP avahi_entry_group_states_total EntryGroup state changes.
+# TYPE avahi_entry_group_states_total counter
+avahi_entry_group_states_total{state="collision"} 1
+avahi_entry_group_states_total{state="registering"} 1
+# HELP avahi_event_queue_length Events, queued by objects and not consumed yet.
+# TYPE avahi_event_queue_length gauge
+avahi_event_queue_length{kind="ServiceBrowser"} 3
+# HELP avahi_events_total Events, reported by objects.
+# TYPE avahi_events_total counter
+avahi_events_total{event="BrowserNew",kind="ServiceBrowser"} 2
+avahi_events_total{event="BrowserRemove",kind="ServiceBrowser"} 1
+avahi_events_total{event="ResolverFailure",kind="ServiceResolver"} 1
+avahi_events_total{event="ResolverFound",kind="ServiceResolver"} 2
+# HELP avahi_objects Objects currently open.
+# TYPE avahi_objects gauge
+avahi_objects{kind="EntryGroup"} 1
+avahi_objects{kind="ServiceBrowser"} 1
+avahi_objects{kind="ServiceResolver"} 1
+# HELP avahi_objects_created_total Objects created.
+# TYPE avahi_objects_created_total counter
+avahi_objects_created_total{kind="EntryGroup"} 1
+avahi_objects_created_total{kind="ServiceBrowser"} 1
+avahi_objects_created_total{kind="ServiceResolver"} 2
+# HELP avahi_resolver_failures_total Resolver failures, by error.
+# TYPE avahi_resolver_failures_total counter
+avahi_resolver_failures_total{error="Timeout reached",kind="ServiceResolver"} 1
+# HELP avahi_services Service instances currently discovered, by type.
+# TYPE avahi_services gauge
+avahi_services{svctype="_ipp._tcp"} 1
+`
+
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"avahi_client_states_total",
+		"avahi_entry_group_collisions_total",
+		"avahi_entry_group_states_total",
+		"avahi_event_queue_length",
+		"avahi_events_total",
+		"avahi_objects",
+		"avahi_objects_created_total",
+		"avahi_resolver_failures_total",
+		"avahi_services",
+	)
+
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+
+	// Both resolvers took 100ms (one fake clock tick)
+	expected = `
+# HELP avahi_resolve_duration_seconds Time from the resolver creation to its first result.
+# TYPE avahi_resolve_duration_seconds histogram
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="0.001"} 0
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="0.005"} 0
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="0.01"} 0
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="0.05"} 0
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="0.1"} 2
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="0.25"} 2
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="0.5"} 2
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="1"} 2
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="2.5"} 2
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="5"} 2
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="10"} 2
+avahi_resolve_duration_seconds_bucket{kind="ServiceResolver",le="+Inf"} 2
+avahi_resolve_duration_seconds_sum{kind="ServiceResolver"} 0.2
+avahi_resolve_duration_seconds_count{kind="ServiceResolver"} 2
+`
+
+	err = testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"avahi_resolve_duration_seconds")
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+}
+
+// TestCollectorServices tests that service instances, reported by
+// many ServiceBrowsers or on many interfaces, are counted once.
+func TestCollectorServices(t *testing.T) {
+	c := testCollector()
+	ctx := context.Background()
+
+	type testData struct {
+		ifidx    avahi.IfIndex
+		proto    avahi.Protocol
+		instname string
+		svctype  string
+		domain   string
+	}
+
+	events := []testData{
+		{1, avahi.ProtocolIP4, "Printer", "_ipp._tcp", "local"},
+		{1, avahi.ProtocolIP6, "Printer", "_ipp._tcp", "local"},
+		{2, avahi.ProtocolIP4, "Printer", "_ipp._tcp", "local"},
+		{2, avahi.ProtocolIP4, "Printer", "_IPP._tcp", "Local."},
+		{2, avahi.ProtocolIP4, "Scanner", "_ipp._tcp", "local"},
+		{2, avahi.ProtocolIP4, "Scanner", "_uscan._tcp", "local"},
+	}
+
+	for i := 0; i < 2; i++ {
+		browser := &avahi.ServiceBrowser{}
+		c.Created(ctx, browser, &avahi.ObjectInfo{
+			Kind:    "ServiceBrowser",
+			SvcType: "_ipp._tcp",
+		})
+
+		for _, evnt := range events {
+			c.Event(browser, &avahi.ServiceBrowserEvent{
+				Event:        avahi.BrowserNew,
+				IfIdx:        evnt.ifidx,
+				Proto:        evnt.proto,
+				InstanceName: evnt.instname,
+				SvcType:      evnt.svctype,
+				Domain:       evnt.domain,
+			})
+		}
+	}
+
+	expected := `
+# HELP avahi_services Service instances currently discovered, by type.
+# TYPE avahi_services gauge
+avahi_services{svctype="_ipp._tcp"} 2
+avahi_services{svctype="_uscan._tcp"} 1
+`
+
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"avahi_services")
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+}
@@ -0,0 +1,385 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Multi-domain service browser
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// MultiDomainBrowser reports available services of the specified type
+// in the default domain and in all browsing domains, discovered by
+// the [DomainBrowser].
+//
+// Unlike other browsers, MultiDomainBrowser is not a native Avahi
+// object. It is the helper, that combines DomainBrowser and many
+// [ServiceBrowser]s together, one per discovered domain.
+type MultiDomainBrowser struct {
+	clnt          *Client                          // Owning Client
+	ifidx         IfIndex                          // Network interface
+	proto         Protocol                         // Network protocol
+	svctype       string                           // Service type
+	flags         LookupFlags                      // Lookup flags
+	domainBrowser *DomainBrowser                   // Domain browser
+	queue         eventqueue[*ServiceBrowserEvent] // Event queue
+	lock          sync.Mutex                       // Access lock
+	domains       map[string]*multiDomainEntry     // Browsed domains
+	done          sync.WaitGroup                   // Wait for goroutines
+	closed        atomic.Bool                      // Browser is closed
+}
+
+// multiDomainEntry represents a single domain, browsed by
+// the MultiDomainBrowser.
+type multiDomainEntry struct {
+	domain    string                                      // Domain name
+	browser   *ServiceBrowser                             // Service browser
+	sources   map[multiDomainSource]struct{}              // Reported by
+	instances map[multiDomainInstance]ServiceBrowserEvent // Known instances
+}
+
+// multiDomainSource identifies the network interface and protocol,
+// where the browsing domain is reported by the DomainBrowser.
+//
+// The same domain may be reported via many interfaces and protocols,
+// and it is browsed until the last of them is removed.
+type multiDomainSource struct {
+	ifidx IfIndex  // Network interface index
+	proto Protocol // Network protocol
+}
+
+// multiDomainInstance identifies the discovered service instance.
+type multiDomainInstance struct {
+	ifidx    IfIndex  // Network interface index
+	proto    Protocol // Network protocol
+	instname string   // Instance name
+	svctype  string   // Service type
+}
+
+// NewMultiDomainBrowser creates a new [MultiDomainBrowser].
+//
+// MultiDomainBrowser runs the [DomainBrowser] of the
+// [DomainBrowserBrowse] type in the default domain. For the
+// default domain and for each discovered browsing domain it
+// runs the [ServiceBrowser] of the specified service type.
+// When browsing domain disappears, its ServiceBrowser is
+// closed.
+//
+// Events from all ServiceBrowsers are merged together and reported
+// as a single series of [ServiceBrowserEvent] events via channel
+// returned by the [MultiDomainBrowser.Chan]. The ServiceBrowserEvent.Domain
+// field tells, which domain the event belongs to.
+//
+// Please notice:
+//   - the same browsing domain may be reported by the DomainBrowser
+//     via many network interfaces and protocols. It is browsed until
+//     it disappears from all of them
+//   - when browsing domain disappears, the [BrowserRemove] events
+//     are generated for all service instances previously reported
+//     in that domain, so event consumer always sees the consistent
+//     picture
+//   - [BrowserCacheExhausted] and [BrowserAllForNow] events are
+//     reported separately for each domain
+//   - failure of the DomainBrowser is reported as [BrowserFailure]
+//     event with the empty Domain. ServiceBrowsers, already running,
+//     are not affected by this failure.
+//
+// Function parameters are the same as for the [NewServiceBrowser],
+// except for the domain, which is not needed here.
+//
+// MultiDomainBrowser must be closed after use with the
+// [MultiDomainBrowser.Close] function call.
+func NewMultiDomainBrowser(
+	clnt *Client,
+	ifidx IfIndex,
+	proto Protocol,
+	svctype string,
+	flags LookupFlags) (*MultiDomainBrowser, error) {
+
+	// Initialize MultiDomainBrowser structure
+	browser := &MultiDomainBrowser{
+		clnt:    clnt,
+		ifidx:   ifidx,
+		proto:   proto,
+		svctype: svctype,
+		flags:   flags,
+		domains: make(map[string]*multiDomainEntry),
+	}
+
+	browser.queue.init()
+
+	// Create DomainBrowser
+	domainBrowser, err := NewDomainBrowser(clnt, ifidx, proto, "",
+		DomainBrowserBrowse, flags&(LookupUseWideArea|LookupUseMulticast))
+	if err != nil {
+		browser.queue.Close()
+		return nil, err
+	}
+
+	browser.domainBrowser = domainBrowser
+
+	// Start browsing the default domain. Its events are ignored
+	// by the procDomains, so it is never removed.
+	err = browser.addDomain("", multiDomainSource{IfIndexUnspec,
+		ProtocolUnspec})
+	if err != nil {
+		domainBrowser.Close()
+		browser.queue.Close()
+		return nil, err
+	}
+
+	browser.done.Add(1)
+	go browser.procDomains()
+
+	// Register self to be closed if Client is closed
+	clnt.begin()
+	clnt.addCloser(browser)
+	clnt.end()
+
+	// Report object creation
+	if hooks := clnt.hooks(); hooks != nil {
+		hooks.create(context.Background(), browser, &ObjectInfo{
+			Kind:     "MultiDomainBrowser",
+			IfIdx:    ifidx,
+			Proto:    proto,
+			SvcType:  svctype,
+			Flags:    flags,
+			QueueLen: browser.queue.Len,
+		})
+	}
+
+	return browser, nil
+}
+
+// Chan returns channel where [ServiceBrowserEvent]s are sent.
+func (browser *MultiDomainBrowser) Chan() <-chan *ServiceBrowserEvent {
+	return browser.queue.Chan()
+}
+
+// Get waits for the next [ServiceBrowserEvent].
+//
+// It returns:
+//   - event, nil - if event available
+//   - nil, error - if context is canceled
+//   - nil, nil   - if MultiDomainBrowser was closed
+func (browser *MultiDomainBrowser) Get(ctx context.Context) (
+	*ServiceBrowserEvent, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case evnt := <-browser.Chan():
+		return evnt, nil
+	}
+}
+
+// Close closes the [MultiDomainBrowser] and releases allocated resources.
+// It closes the event channel, effectively unblocking pending readers.
+//
+// Note, double close is safe.
+func (browser *MultiDomainBrowser) Close() {
+	if !browser.closed.Swap(true) {
+		browser.clnt.notifyClose("MultiDomainBrowser", browser)
+
+		browser.clnt.begin()
+		browser.clnt.delCloser(browser)
+		browser.clnt.end()
+
+		browser.domainBrowser.Close()
+
+		browser.lock.Lock()
+		domains := browser.domains
+		browser.domains = nil
+		browser.lock.Unlock()
+
+		for _, entry := range domains {
+			entry.browser.Close()
+		}
+
+		browser.done.Wait()
+		browser.queue.Close()
+	}
+}
+
+// procDomains runs in its own goroutine and handles
+// DomainBrowser events.
+func (browser *MultiDomainBrowser) procDomains() {
+	defer browser.done.Done()
+
+	dflt := multiDomainKey(browser.clnt.GetDomainName())
+
+	for evnt := range browser.domainBrowser.Chan() {
+		// Events for the default domain are ignored, as
+		// default domain is always browsed.
+		if multiDomainKey(evnt.Domain) == dflt {
+			continue
+		}
+
+		src := multiDomainSource{evnt.IfIdx, evnt.Proto}
+
+		switch evnt.Event {
+		case BrowserNew:
+			err := browser.addDomain(evnt.Domain, src)
+			if err != nil {
+				browser.queue.Push(&ServiceBrowserEvent{
+					Event:   BrowserFailure,
+					IfIdx:   evnt.IfIdx,
+					Proto:   evnt.Proto,
+					Err:     errCode(err),
+					SvcType: browser.svctype,
+					Domain:  evnt.Domain,
+				})
+			}
+
+		case BrowserRemove:
+			browser.delDomain(evnt.Domain, src)
+
+		case BrowserFailure:
+			browser.queue.Push(&ServiceBrowserEvent{
+				Event:   BrowserFailure,
+				IfIdx:   evnt.IfIdx,
+				Proto:   evnt.Proto,
+				Err:     evnt.Err,
+				SvcType: browser.svctype,
+			})
+		}
+	}
+}
+
+// addDomain starts browsing of the new domain, reported by the src.
+// If domain is already browsed, src is added to its sources.
+//
+// Domain "" means the default domain.
+func (browser *MultiDomainBrowser) addDomain(domain string,
+	src multiDomainSource) error {
+
+	browser.lock.Lock()
+	defer browser.lock.Unlock()
+
+	if browser.domains == nil {
+		return nil
+	}
+
+	key := multiDomainKey(domain)
+	if entry := browser.domains[key]; entry != nil {
+		entry.sources[src] = struct{}{}
+		return nil
+	}
+
+	sb, err := NewServiceBrowser(browser.clnt, browser.ifidx, browser.proto,
+		browser.svctype, domain, browser.flags)
+	if err != nil {
+		return err
+	}
+
+	entry := &multiDomainEntry{
+		domain:    domain,
+		browser:   sb,
+		sources:   map[multiDomainSource]struct{}{src: {}},
+		instances: make(map[multiDomainInstance]ServiceBrowserEvent),
+	}
+
+	browser.domains[key] = entry
+
+	browser.done.Add(1)
+	go browser.procServices(entry)
+
+	return nil
+}
+
+// delDomain removes src from the domain sources. When the last
+// source is removed, it stops browsing of the domain and generates
+// BrowserRemove events for all service instances, known in that
+// domain.
+func (browser *MultiDomainBrowser) delDomain(domain string,
+	src multiDomainSource) {
+
+	browser.lock.Lock()
+
+	key := multiDomainKey(domain)
+	entry := browser.domains[key]
+	if entry != nil {
+		delete(entry.sources, src)
+		if len(entry.sources) != 0 {
+			entry = nil
+		}
+	}
+
+	if entry != nil {
+		delete(browser.domains, key)
+
+		for _, evnt := range entry.instances {
+			evnt.Event = BrowserRemove
+			evnt.Flags = 0
+			browser.queue.Push(&evnt)
+		}
+	}
+
+	browser.lock.Unlock()
+
+	if entry != nil {
+		entry.browser.Close()
+	}
+}
+
+// procServices runs in its own goroutine and handles ServiceBrowser
+// events for the particular domain.
+func (browser *MultiDomainBrowser) procServices(entry *multiDomainEntry) {
+	defer browser.done.Done()
+
+	for evnt := range entry.browser.Chan() {
+		browser.lock.Lock()
+
+		// Drop events of the already removed domain
+		if browser.domains[multiDomainKey(entry.domain)] != entry {
+			browser.lock.Unlock()
+			continue
+		}
+
+		// Events without domain (i.e., BrowserAllForNow)
+		// are tagged with the domain being browsed
+		if evnt.Domain == "" {
+			evnt.Domain = entry.domain
+		}
+
+		inst := multiDomainInstance{
+			ifidx:    evnt.IfIdx,
+			proto:    evnt.Proto,
+			instname: evnt.InstanceName,
+			svctype:  evnt.SvcType,
+		}
+
+		switch evnt.Event {
+		case BrowserNew:
+			entry.instances[inst] = *evnt
+		case BrowserRemove:
+			delete(entry.instances, inst)
+		}
+
+		browser.queue.Push(evnt)
+		browser.lock.Unlock()
+	}
+}
+
+// multiDomainKey returns the domain key, used to index
+// MultiDomainBrowser.domains
+func multiDomainKey(domain string) string {
+	return DomainToLower(strings.TrimSuffix(domain, "."))
+}
+
+// errCode converts error into ErrCode.
+func errCode(err error) ErrCode {
+	var code ErrCode
+	if errors.As(err, &code) {
+		return code
+	}
+	return ErrFailure
+}
@@ -0,0 +1,180 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Multi-domain service browser test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMultiDomainKey tests multiDomainKey
+func TestMultiDomainKey(t *testing.T) {
+	type testData struct {
+		domain   string // Input domain
+		expected string // Expected key
+	}
+
+	tests := []testData{
+		{"", ""},
+		{"local", "local"},
+		{"local.", "local"},
+		{"Local.", "local"},
+		{"Example.COM.", "example.com"},
+		{`My\.Domain.com`, `my\.domain.com`},
+	}
+
+	for _, test := range tests {
+		key := multiDomainKey(test.domain)
+		if key != test.expected {
+			t.Errorf("%q:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.domain, test.expected, key)
+		}
+	}
+}
+
+// TestMultiDomainBrowser tests MultiDomainBrowser with the replay Client
+//
+// The capture contains the DomainBrowser, that reports the default
+// domain (which must be ignored) and two other domains, each via two
+// network interfaces, and ServiceBrowsers for all these domains:
+//   - "Example.COM." disappears from one interface only, so it
+//     must be browsed further
+//   - "example.org." disappears from both interfaces, so it must
+//     not be browsed anymore
+//
+// The ServiceBrowser for the "local" domain must never be created,
+// and if it is, its events will be reported twice.
+func TestMultiDomainBrowser(t *testing.T) {
+	capture := strings.Join([]string{
+		`{"time":"2024-01-01T00:00:00Z","client":{"state":"running","hostname":"host","domain":"local","fqdn":"host.local","version":"avahi 0.8"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"DomainBrowser","created":{"Kind":"DomainBrowser","IfIdx":-1,"Proto":"unspec","BrowserType":"browse"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"DomainBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Domain":"Local."}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"DomainBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Domain":"Example.COM."}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"DomainBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Domain":"example.org."}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"DomainBrowser","event":{"Event":"BrowserNew","IfIdx":3,"Proto":"ip4","Domain":"example.com"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"DomainBrowser","event":{"Event":"BrowserNew","IfIdx":3,"Proto":"ip4","Domain":"example.org."}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"DomainBrowser","event":{"Event":"BrowserRemove","IfIdx":2,"Proto":"ip4","Domain":"Example.COM."}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"DomainBrowser","event":{"Event":"BrowserRemove","IfIdx":3,"Proto":"ip4","Domain":"example.org."}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"DomainBrowser","event":{"Event":"BrowserRemove","IfIdx":2,"Proto":"ip4","Domain":"example.org."}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"ServiceBrowser","created":{"Kind":"ServiceBrowser","IfIdx":-1,"Proto":"unspec","SvcType":"_ipp._tcp"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"ServiceBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Flags":"mdns","InstanceName":"Printer","SvcType":"_ipp._tcp","Domain":"local"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":3,"kind":"ServiceBrowser","created":{"Kind":"ServiceBrowser","IfIdx":-1,"Proto":"unspec","SvcType":"_ipp._tcp","Domain":"Local."}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":3,"kind":"ServiceBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Flags":"mdns","InstanceName":"Printer","SvcType":"_ipp._tcp","Domain":"local"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":4,"kind":"ServiceBrowser","created":{"Kind":"ServiceBrowser","IfIdx":-1,"Proto":"unspec","SvcType":"_ipp._tcp","Domain":"Example.COM."}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":4,"kind":"ServiceBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Flags":"wan-dns","InstanceName":"Scanner","SvcType":"_ipp._tcp","Domain":"example.com"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":4,"kind":"ServiceBrowser","event":{"Event":"BrowserAllForNow","IfIdx":-1,"Proto":"unspec"}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":5,"kind":"ServiceBrowser","created":{"Kind":"ServiceBrowser","IfIdx":-1,"Proto":"unspec","SvcType":"_ipp._tcp","Domain":"example.org."}}`,
+		`{"time":"2024-01-01T00:00:00Z","obj":5,"kind":"ServiceBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Flags":"wan-dns","InstanceName":"Fax","SvcType":"_ipp._tcp","Domain":"example.org"}}`,
+	}, "\n") + "\n"
+
+	clnt, err := NewReplayClient(strings.NewReader(capture), 0)
+	if err != nil {
+		t.Fatalf("NewReplayClient: %s", err)
+	}
+
+	defer clnt.Close()
+
+	browser, err := NewMultiDomainBrowser(clnt, IfIndexUnspec,
+		ProtocolUnspec, "_ipp._tcp", 0)
+	if err != nil {
+		t.Fatalf("NewMultiDomainBrowser: %s", err)
+	}
+
+	defer browser.Close()
+
+	// Wait until all DomainBrowser events are handled
+	deadline := time.Now().Add(time.Second)
+	for {
+		browser.lock.Lock()
+		_, com := browser.domains["example.com"]
+		_, org := browser.domains["example.org"]
+		browser.lock.Unlock()
+
+		if com && !org {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("MultiDomainBrowser: domains not updated")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	// Order of events from different domains is not defined, and
+	// "Fax" may be reported or not, depending on timing, so only
+	// the resulting set of instances is checked. If "Fax" is
+	// reported, it must be followed by the BrowserRemove event.
+	//
+	// Instances are keyed by the BrowserNew events without Flags,
+	// as BrowserRemove events don't have them.
+	printer := ServiceBrowserEvent{
+		Event:        BrowserNew,
+		IfIdx:        2,
+		Proto:        ProtocolIP4,
+		InstanceName: "Printer",
+		SvcType:      "_ipp._tcp",
+		Domain:       "local",
+	}
+
+	scanner := ServiceBrowserEvent{
+		Event:        BrowserNew,
+		IfIdx:        2,
+		Proto:        ProtocolIP4,
+		InstanceName: "Scanner",
+		SvcType:      "_ipp._tcp",
+		Domain:       "example.com",
+	}
+
+	expected := map[ServiceBrowserEvent]bool{
+		printer: true,
+		scanner: true,
+	}
+
+	present := make(map[ServiceBrowserEvent]bool)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			100*time.Millisecond)
+		evnt, _ := browser.Get(ctx)
+		cancel()
+
+		if evnt == nil {
+			break
+		}
+
+		inst := *evnt
+		inst.Event = BrowserNew
+		inst.Flags = 0
+
+		switch evnt.Event {
+		case BrowserNew:
+			present[inst] = true
+
+		case BrowserRemove:
+			if !present[inst] {
+				t.Errorf("MultiDomainBrowser.Get: "+
+					"unexpected %+v", evnt)
+			}
+			delete(present, inst)
+		}
+	}
+
+	if !reflect.DeepEqual(expected, present) {
+		t.Errorf("MultiDomainBrowser:\n"+
+			"expected: %+v\n"+
+			"present:  %+v\n",
+			expected, present)
+	}
+}
@@ -0,0 +1,97 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Observer (instrumentation hooks)
+//
+//go:build linux || freebsd
+
+package avahi
+
+import "context"
+
+// Observer receives notifications about activity of the [Client] and
+// objects it owns (browsers, resolvers, entry groups).
+//
+// It is the extension point for instrumentation (metrics, tracing),
+// implemented outside of this package. See [Client.SetObserver].
+//
+// Observer methods are called synchronously, often from the Avahi
+// event loop thread, so they must be fast, must not block and must
+// not call methods of the Client and objects it owns. The same
+// Observer may be called from multiple goroutines simultaneously.
+type Observer interface {
+	// Created is called when object is created.
+	//
+	// ctx is the context of the operation that created the object,
+	// if any (for example, ctx of the [LookupHost]), otherwise
+	// it is context.Background().
+	Created(ctx context.Context, obj any, info *ObjectInfo)
+
+	// Closed is called when object is closed.
+	Closed(obj any)
+
+	// Event is called for each event, generated by object, before
+	// the event is queued for delivery.
+	//
+	// evnt is the pointer to the event structure, for example,
+	// *ServiceBrowserEvent. State changes of the Client and
+	// EntryGroup are reported as *ClientEvent and *EntryGroupEvent.
+	// The Client itself is never reported as created or closed.
+	Event(obj, evnt any)
+}
+
+// SetObserver sets the [Observer] for the [Client] and all objects,
+// created by it since this call.
+//
+// If o is nil, the Observer is removed. This is the default, and
+// it costs virtually nothing.
+func (clnt *Client) SetObserver(o Observer) {
+	clnt.setHooks(func(hooks *clientHooks) {
+		hooks.observer = o
+	})
+}
+
+// MultiObserver creates an [Observer] that duplicates its calls
+// to all the provided observers, in order, similar to the Unix
+// tee(1) command.
+//
+// It allows to attach many observers (i.e., metrics and tracing)
+// to the same [Client].
+func MultiObserver(observers ...Observer) Observer {
+	all := make(multiObserver, 0, len(observers))
+	for _, o := range observers {
+		if mo, ok := o.(multiObserver); ok {
+			all = append(all, mo...)
+		} else if o != nil {
+			all = append(all, o)
+		}
+	}
+	return all
+}
+
+// multiObserver implements the MultiObserver.
+type multiObserver []Observer
+
+// Created is called when object is created.
+func (mo multiObserver) Created(ctx context.Context, obj any,
+	info *ObjectInfo) {
+	for _, o := range mo {
+		o.Created(ctx, obj, info)
+	}
+}
+
+// Closed is called when object is closed.
+func (mo multiObserver) Closed(obj any) {
+	for _, o := range mo {
+		o.Closed(obj)
+	}
+}
+
+// Event is called for each event, generated by object.
+func (mo multiObserver) Event(obj, evnt any) {
+	for _, o := range mo {
+		o.Event(obj, evnt)
+	}
+}
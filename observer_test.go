@@ -0,0 +1,96 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Observer test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// testObserver records Observer calls as strings.
+type testObserver struct {
+	calls []string
+}
+
+// Created records object creation.
+func (o *testObserver) Created(ctx context.Context, obj any,
+	info *ObjectInfo) {
+	o.calls = append(o.calls,
+		fmt.Sprintf("created %s %s", info.Kind, info.SvcType))
+}
+
+// Closed records object Close.
+func (o *testObserver) Closed(obj any) {
+	o.calls = append(o.calls, fmt.Sprintf("closed %T", obj))
+}
+
+// Event records event.
+func (o *testObserver) Event(obj, evnt any) {
+	o.calls = append(o.calls, fmt.Sprintf("event %T", evnt))
+}
+
+// TestObserver tests Observer hooks
+func TestObserver(t *testing.T) {
+	clnt := &Client{}
+	obs := &testObserver{}
+	obj := &ServiceBrowser{}
+
+	clnt.SetObserver(obs)
+
+	if hooks := clnt.hooks(); hooks != nil {
+		hooks.create(context.Background(), obj, &ObjectInfo{
+			Kind:    "ServiceBrowser",
+			SvcType: "_ipp._tcp",
+		})
+	}
+
+	clnt.notifyEvent("ServiceBrowser", obj,
+		&ServiceBrowserEvent{Event: BrowserNew})
+	clnt.notifyState("EntryGroup", obj,
+		&EntryGroupEvent{State: EntryGroupStateEstablished})
+	clnt.notifyClose("ServiceBrowser", obj)
+
+	expected := []string{
+		"created ServiceBrowser _ipp._tcp",
+		"event *avahi.ServiceBrowserEvent",
+		"event *avahi.EntryGroupEvent",
+		"closed *avahi.ServiceBrowser",
+	}
+
+	if !reflect.DeepEqual(obs.calls, expected) {
+		t.Errorf("Observer calls:\n"+
+			"expected: %q\n"+
+			"present:  %q\n",
+			expected, obs.calls)
+	}
+
+	// Test MultiObserver
+	obs1, obs2 := &testObserver{}, &testObserver{}
+	clnt.SetObserver(MultiObserver(obs1, nil, MultiObserver(obs2)))
+	clnt.notifyClose("ServiceBrowser", obj)
+
+	expected = []string{"closed *avahi.ServiceBrowser"}
+	for _, o := range []*testObserver{obs1, obs2} {
+		if !reflect.DeepEqual(o.calls, expected) {
+			t.Errorf("MultiObserver calls:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				expected, o.calls)
+		}
+	}
+
+	// Test that Observer can be reset
+	clnt.SetObserver(nil)
+	if clnt.hooks() != nil {
+		t.Errorf("SetObserver(nil) failed")
+	}
+}
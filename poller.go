@@ -115,6 +115,11 @@ func (p *Poller) AddServiceBrowser(browser *ServiceBrowser) {
 	pollerAddSource(p, browser.Chan())
 }
 
+// AddMultiDomainBrowser adds [MultiDomainBrowser] as the event source.
+func (p *Poller) AddMultiDomainBrowser(browser *MultiDomainBrowser) {
+	pollerAddSource(p, browser.Chan())
+}
+
 // AddServiceTypeBrowser adds [ServiceTypeBrowser] as the event source.
 func (p *Poller) AddServiceTypeBrowser(browser *ServiceTypeBrowser) {
 	pollerAddSource(p, browser.Chan())
@@ -0,0 +1,338 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Printer advertisement
+//
+//go:build linux || freebsd
+
+package printers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/OpenPrinting/go-avahi"
+)
+
+// Advertisement describes a printer to be published via DNS-SD,
+// as IPP Everywhere and/or AirPrint printer.
+//
+// It generates the following services:
+//   - "_ipp._tcp", if IPPPort is not zero
+//   - "_ipps._tcp", if IPPSPort is not zero
+//   - "_printer._tcp" with zero port, the placeholder that
+//     reserves the instance name for LPD (see [Service.IsPlaceholder])
+//
+// IPP and IPPS services get the "_print" subtype, if Everywhere
+// is set, and the "_universal" subtype, if AirPrint is set.
+//
+// TXT record of IPP and IPPS services contains "txtvers=1" and
+// "qtotal=1", followed by the printer keys of the encoded Attrs (see
+// [Attributes.Txt]), followed by Txt. The placeholder service has empty TXT record.
+//
+// See [Bonjour Printing Specification] and [PWG 5100.14] (IPP
+// Everywhere) for details.
+//
+// [Bonjour Printing Specification]: https://developer.apple.com/bonjour/printing-specification/bonjourprinting-1.2.1.pdf
+// [PWG 5100.14]: https://ftp.pwg.org/pub/pwg/candidates/cs-ippeve11-20200515-5100.14.pdf
+type Advertisement struct {
+	IfIdx        avahi.IfIndex  // Network interface index
+	Proto        avahi.Protocol // Publishing network protocol
+	InstanceName string         // Service instance name
+	Domain       string         // Service domain (use "" for default)
+	Hostname     string         // Host name (use "" for default)
+	IPPPort      int            // IPP port, 0 if not supported
+	IPPSPort     int            // IPPS port, 0 if not supported
+	Everywhere   bool           // Advertise as IPP Everywhere printer
+	AirPrint     bool           // Advertise as AirPrint printer
+	Attrs        Attributes     // Printer attributes
+	Txt          []string       // Additional TXT keys ("key=value"...)
+}
+
+// AdvertisedService is the single service, generated by the
+// [Advertisement].
+type AdvertisedService struct {
+	avahi.EntryGroupService          // The service
+	Subtypes                []string // Service subtypes
+}
+
+// Advertisement limits:
+const (
+	advertiseTxtMax = 255 // Max length of the TXT string
+)
+
+// Validate checks the Advertisement against requirements of the
+// Bonjour Printing Specification and, if requested, the IPP Everywhere
+// and AirPrint.
+//
+// In particular:
+//   - InstanceName, Domain and Hostname must be valid
+//   - at least one of IPPPort and IPPSPort must be set
+//   - Attrs.MakeModel ("ty") and Attrs.RP ("rp") are required
+//   - IPP Everywhere requires Attrs.UUID, Attrs.Color, Attrs.Duplex,
+//     "image/pwg-raster" in Attrs.PDL and Attrs.RP to be "ipp/print"
+//     or "ipp/print/<name>"
+//   - AirPrint requires Attrs.URF and "image/urf" in Attrs.PDL
+//   - TXT keys must be unique (case-insensitive), non-empty, printable
+//     US-ASCII, and each "key=value" string must fit 255 bytes
+func (ad *Advertisement) Validate() error {
+	_, err := ad.validate()
+	return err
+}
+
+// Services returns all services, generated by the Advertisement.
+//
+// Services are returned in the same order, as they are published by
+// the [Advertisement.Publish]: IPPS, IPP, then LPD placeholder.
+func (ad *Advertisement) Services() ([]*AdvertisedService, error) {
+	txt, err := ad.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var services []*AdvertisedService
+
+	add := func(kind ServiceKind, port int, txt []string) {
+		svctype := kind.SvcType()
+		svc := &AdvertisedService{
+			EntryGroupService: avahi.EntryGroupService{
+				IfIdx:        ad.IfIdx,
+				Proto:        ad.Proto,
+				InstanceName: ad.InstanceName,
+				SvcType:      svctype,
+				Domain:       ad.Domain,
+				Hostname:     ad.Hostname,
+				Port:         port,
+				Txt:          txt,
+			},
+		}
+
+		if kind != ServiceLPD {
+			if ad.Everywhere {
+				svc.Subtypes = append(svc.Subtypes,
+					avahi.ServiceSubtypeName("_print", svctype))
+			}
+
+			if ad.AirPrint {
+				svc.Subtypes = append(svc.Subtypes,
+					avahi.ServiceSubtypeName("_universal", svctype))
+			}
+		}
+
+		services = append(services, svc)
+	}
+
+	if ad.IPPSPort != 0 {
+		add(ServiceIPPS, ad.IPPSPort, txt)
+	}
+
+	if ad.IPPPort != 0 {
+		add(ServiceIPP, ad.IPPPort, txt)
+	}
+
+	add(ServiceLPD, 0, nil)
+
+	return services, nil
+}
+
+// Publish adds all services, generated by the Advertisement, with
+// their subtypes, to the [avahi.EntryGroup].
+//
+// It doesn't commit the EntryGroup, so other entries may be
+// added to the same group. Use [avahi.EntryGroup.Commit] after
+// all entries are added.
+func (ad *Advertisement) Publish(egrp *avahi.EntryGroup,
+	flags avahi.PublishFlags) error {
+
+	services, err := ad.Services()
+	if err != nil {
+		return err
+	}
+
+	for _, svc := range services {
+		err = egrp.AddService(&svc.EntryGroupService, flags)
+		if err != nil {
+			return fmt.Errorf("printers: publish %q: %w",
+				svc.SvcType, err)
+		}
+
+		svcid := svc.ident()
+		for _, subtype := range svc.Subtypes {
+			err = egrp.AddServiceSubtype(svcid, subtype, flags)
+			if err != nil {
+				return fmt.Errorf("printers: publish %q: %w",
+					subtype, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// UpdateTxt updates TXT records of the already published services,
+// after Attrs or Txt of the Advertisement were changed.
+//
+// Other fields (names, ports and the Everywhere and AirPrint flags)
+// must remain the same, as when Advertisement was published.
+// Otherwise, Reset the EntryGroup and Publish again.
+//
+// Unlike Publish, the update takes effect immediately, without
+// commit.
+func (ad *Advertisement) UpdateTxt(egrp *avahi.EntryGroup,
+	flags avahi.PublishFlags) error {
+
+	services, err := ad.Services()
+	if err != nil {
+		return err
+	}
+
+	for _, svc := range services {
+		if svc.Port == 0 {
+			continue
+		}
+
+		err = egrp.UpdateServiceTxt(svc.ident(), svc.Txt, flags)
+		if err != nil {
+			return fmt.Errorf("printers: update %q: %w",
+				svc.SvcType, err)
+		}
+	}
+
+	return nil
+}
+
+// ident returns avahi.EntryGroupServiceIdent of the service.
+func (svc *AdvertisedService) ident() *avahi.EntryGroupServiceIdent {
+	return &avahi.EntryGroupServiceIdent{
+		IfIdx:        svc.IfIdx,
+		Proto:        svc.Proto,
+		InstanceName: svc.InstanceName,
+		SvcType:      svc.SvcType,
+		Domain:       svc.Domain,
+	}
+}
+
+// validate validates the Advertisement and returns TXT record
+// for the IPP and IPPS services.
+func (ad *Advertisement) validate() ([]string, error) {
+	// Validate names
+	err := avahi.ValidateInstanceName(ad.InstanceName)
+	if err == nil && ad.Domain != "" {
+		err = avahi.DomainValidate(ad.Domain)
+	}
+	if err == nil && ad.Hostname != "" {
+		err = avahi.ValidateHostName(ad.Hostname)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("printers: %w", err)
+	}
+
+	// Validate ports
+	switch {
+	case ad.IPPPort == 0 && ad.IPPSPort == 0:
+		return nil, ad.errorf("neither IPP nor IPPS port is set")
+	case ad.IPPPort < 0 || ad.IPPPort > 65535:
+		return nil, ad.errorf("invalid IPP port %d", ad.IPPPort)
+	case ad.IPPSPort < 0 || ad.IPPSPort > 65535:
+		return nil, ad.errorf("invalid IPPS port %d", ad.IPPSPort)
+	}
+
+	// Validate attributes
+	attrs := &ad.Attrs
+	switch {
+	case attrs.MakeModel == "":
+		return nil, ad.errorf(`missed "ty" (MakeModel)`)
+	case attrs.RP == "":
+		return nil, ad.errorf(`missed "rp" (RP)`)
+	case strings.HasPrefix(attrs.RP, "/"):
+		return nil, ad.errorf(`"rp" must not start with "/"`)
+	}
+
+	if ad.Everywhere {
+		switch {
+		case attrs.RP != "ipp/print" &&
+			!strings.HasPrefix(attrs.RP, "ipp/print/"):
+			return nil, ad.errorf(`IPP Everywhere: "rp" must be ` +
+				`"ipp/print" or "ipp/print/<name>"`)
+		case attrs.UUID == "":
+			return nil, ad.errorf(`IPP Everywhere: missed "UUID"`)
+		case attrs.Color == Unknown:
+			return nil, ad.errorf(`IPP Everywhere: missed "Color"`)
+		case attrs.Duplex == Unknown:
+			return nil, ad.errorf(`IPP Everywhere: missed "Duplex"`)
+		case !advertiseHas(attrs.PDL, "image/pwg-raster"):
+			return nil, ad.errorf(`IPP Everywhere: "pdl" must ` +
+				`contain "image/pwg-raster"`)
+		}
+	}
+
+	if ad.AirPrint {
+		switch {
+		case len(attrs.URF) == 0:
+			return nil, ad.errorf(`AirPrint: missed "URF"`)
+		case !advertiseHas(attrs.PDL, "image/urf"):
+			return nil, ad.errorf(`AirPrint: "pdl" must ` +
+				`contain "image/urf"`)
+		}
+	}
+
+	// Build and validate TXT record
+	txt := []string{"txtvers=1", "qtotal=1"}
+	txt = append(txt, attrs.Txt(ServiceIPP)...)
+	txt = append(txt, ad.Txt...)
+
+	seen := make(map[string]struct{})
+	for _, kv := range txt {
+		k, _, _ := strings.Cut(kv, "=")
+
+		switch {
+		case k == "":
+			return nil, ad.errorf("TXT %q: empty key", kv)
+		case len(kv) > advertiseTxtMax:
+			return nil, ad.errorf("TXT %q: too long", k)
+		case !advertiseTxtKeyValid(k):
+			return nil, ad.errorf("TXT %q: invalid key", k)
+		}
+
+		lk := strings.ToLower(k)
+		if _, dup := seen[lk]; dup {
+			return nil, ad.errorf("TXT %q: duplicated key", k)
+		}
+		seen[lk] = struct{}{}
+	}
+
+	return txt, nil
+}
+
+// errorf returns Advertisement validation error.
+func (ad *Advertisement) errorf(format string, args ...any) error {
+	return fmt.Errorf("printers: %q: "+format,
+		append([]any{ad.InstanceName}, args...)...)
+}
+
+// advertiseHas reports if list contains the value, case-insensitive.
+func advertiseHas(list []string, value string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// advertiseTxtKeyValid reports if TXT key is valid. According to
+// [RFC6763, 6.4.], key must consist of printable US-ASCII characters,
+// excluding '='.
+//
+// [RFC6763, 6.4.]: https://datatracker.ietf.org/doc/html/rfc6763#section-6.4
+func advertiseTxtKeyValid(k string) bool {
+	for i := 0; i < len(k); i++ {
+		if c := k[i]; c < 0x20 || c > 0x7e || c == '=' {
+			return false
+		}
+	}
+	return true
+}
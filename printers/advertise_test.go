@@ -0,0 +1,268 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Printer advertisement test
+//
+//go:build linux || freebsd
+
+package printers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testAdvertisement returns a valid IPP Everywhere/AirPrint
+// Advertisement for tests.
+func testAdvertisement() *Advertisement {
+	return &Advertisement{
+		InstanceName: "Virtual Printer",
+		IPPPort:      631,
+		IPPSPort:     631,
+		Everywhere:   true,
+		AirPrint:     true,
+		Attrs: Attributes{
+			MakeModel: "Example Printer",
+			UUID:      "4509a320-00a0-008f-00b6-00257366d6c4",
+			RP:        "ipp/print",
+			PDL:       []string{"application/pdf", "image/pwg-raster", "image/urf"},
+			URF:       []string{"V1.4", "W8", "RS300"},
+			Color:     True,
+			Duplex:    False,
+		},
+		Txt: []string{"TLS=1.2"},
+	}
+}
+
+// TestAdvertisementServices tests Advertisement.Services
+func TestAdvertisementServices(t *testing.T) {
+	ad := testAdvertisement()
+	services, err := ad.Services()
+	if err != nil {
+		t.Errorf("Advertisement.Services: %s", err)
+		return
+	}
+
+	if len(services) != 3 {
+		t.Errorf("Advertisement.Services: 3 services expected, %d present",
+			len(services))
+		return
+	}
+
+	txt := []string{
+		"txtvers=1",
+		"qtotal=1",
+		"rp=ipp/print",
+		"ty=Example Printer",
+		"pdl=application/pdf,image/pwg-raster,image/urf",
+		"UUID=4509a320-00a0-008f-00b6-00257366d6c4",
+		"Color=T",
+		"Duplex=F",
+		"URF=V1.4,W8,RS300",
+		"TLS=1.2",
+	}
+
+	type testData struct {
+		svctype  string
+		port     int
+		txt      []string
+		subtypes []string
+	}
+
+	tests := []testData{
+		{
+			svctype: "_ipps._tcp",
+			port:    631,
+			txt:     txt,
+			subtypes: []string{
+				"_print._sub._ipps._tcp",
+				"_universal._sub._ipps._tcp",
+			},
+		},
+
+		{
+			svctype: "_ipp._tcp",
+			port:    631,
+			txt:     txt,
+			subtypes: []string{
+				"_print._sub._ipp._tcp",
+				"_universal._sub._ipp._tcp",
+			},
+		},
+
+		{
+			svctype: "_printer._tcp",
+		},
+	}
+
+	for i, test := range tests {
+		svc := services[i]
+		present := testData{svc.SvcType, svc.Port, svc.Txt, svc.Subtypes}
+		if !reflect.DeepEqual(present, test) {
+			t.Errorf("%s:\n"+
+				"expected: %#v\n"+
+				"present:  %#v\n",
+				test.svctype, test, present)
+		}
+
+		if svc.InstanceName != ad.InstanceName {
+			t.Errorf("%s: wrong instance name %q",
+				test.svctype, svc.InstanceName)
+		}
+	}
+
+	// TXT record must be parsed back into the same attributes
+	attrs := ParseAttributes(services[0].Txt)
+	if !reflect.DeepEqual(attrs, ad.Attrs) {
+		t.Errorf("ParseAttributes(Txt):\n"+
+			"expected: %#v\n"+
+			"present:  %#v\n",
+			ad.Attrs, attrs)
+	}
+}
+
+// TestAdvertisementValidate tests Advertisement.Validate
+func TestAdvertisementValidate(t *testing.T) {
+	type testData struct {
+		name   string               // Test name
+		modify func(*Advertisement) // Modifies valid Advertisement
+		err    string               // Expected error substring, "" if OK
+	}
+
+	tests := []testData{
+		{
+			name:   "valid",
+			modify: func(ad *Advertisement) {},
+		},
+
+		{
+			name: "IPP only, no Everywhere/AirPrint",
+			modify: func(ad *Advertisement) {
+				ad.IPPSPort = 0
+				ad.Everywhere = false
+				ad.AirPrint = false
+				ad.Attrs = Attributes{MakeModel: "Old", RP: "printers/lp"}
+			},
+		},
+
+		{
+			name:   "bad instance name",
+			modify: func(ad *Advertisement) { ad.InstanceName = "" },
+			err:    "Invalid service name",
+		},
+
+		{
+			name: "no ports",
+			modify: func(ad *Advertisement) {
+				ad.IPPPort = 0
+				ad.IPPSPort = 0
+			},
+			err: "neither IPP nor IPPS port",
+		},
+
+		{
+			name:   "bad port",
+			modify: func(ad *Advertisement) { ad.IPPPort = 65536 },
+			err:    "invalid IPP port",
+		},
+
+		{
+			name:   "no ty",
+			modify: func(ad *Advertisement) { ad.Attrs.MakeModel = "" },
+			err:    `missed "ty"`,
+		},
+
+		{
+			name:   "rp with slash",
+			modify: func(ad *Advertisement) { ad.Attrs.RP = "/ipp/print" },
+			err:    `must not start with "/"`,
+		},
+
+		{
+			name:   "Everywhere rp",
+			modify: func(ad *Advertisement) { ad.Attrs.RP = "printers/lp" },
+			err:    "IPP Everywhere",
+		},
+
+		{
+			name:   "Everywhere UUID",
+			modify: func(ad *Advertisement) { ad.Attrs.UUID = "" },
+			err:    `IPP Everywhere: missed "UUID"`,
+		},
+
+		{
+			name:   "Everywhere Duplex",
+			modify: func(ad *Advertisement) { ad.Attrs.Duplex = Unknown },
+			err:    `IPP Everywhere: missed "Duplex"`,
+		},
+
+		{
+			name: "Everywhere pdl",
+			modify: func(ad *Advertisement) {
+				ad.Attrs.PDL = []string{"image/urf"}
+			},
+			err: "image/pwg-raster",
+		},
+
+		{
+			name:   "AirPrint URF",
+			modify: func(ad *Advertisement) { ad.Attrs.URF = nil },
+			err:    `AirPrint: missed "URF"`,
+		},
+
+		{
+			name: "AirPrint pdl",
+			modify: func(ad *Advertisement) {
+				ad.Attrs.PDL = []string{"image/pwg-raster"}
+			},
+			err: "image/urf",
+		},
+
+		{
+			name:   "empty TXT key",
+			modify: func(ad *Advertisement) { ad.Txt = []string{"=x"} },
+			err:    "empty key",
+		},
+
+		{
+			name:   "invalid TXT key",
+			modify: func(ad *Advertisement) { ad.Txt = []string{"k\x01=x"} },
+			err:    "invalid key",
+		},
+
+		{
+			name:   "duplicated TXT key",
+			modify: func(ad *Advertisement) { ad.Txt = []string{"uuid=x"} },
+			err:    "duplicated key",
+		},
+
+		{
+			name: "TXT too long",
+			modify: func(ad *Advertisement) {
+				ad.Attrs.Note = strings.Repeat("x", 251)
+			},
+			err: "too long",
+		},
+	}
+
+	for _, test := range tests {
+		ad := testAdvertisement()
+		test.modify(ad)
+
+		err := ad.Validate()
+		switch {
+		case err == nil && test.err != "":
+			t.Errorf("%s: error %q expected", test.name, test.err)
+		case err != nil && test.err == "":
+			t.Errorf("%s: unexpected error %q", test.name, err)
+		case err != nil && !strings.Contains(err.Error(), test.err):
+			t.Errorf("%s:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.name, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Printer TXT record attributes
+//
+//go:build linux || freebsd
+
+package printers
+
+import "strings"
+
+// Bool is the boolean value that may be unknown, because
+// the corresponding TXT key is missed.
+type Bool int
+
+// Bool values:
+const (
+	Unknown Bool = iota // Key is missed or has invalid value
+	False               // Key has "F" value
+	True                // Key has "T" value
+)
+
+// String returns Bool name, for debugging.
+func (b Bool) String() string {
+	switch b {
+	case False:
+		return "false"
+	case True:
+		return "true"
+	}
+	return "unknown"
+}
+
+// Attributes contains device attributes, decoded from the TXT record
+// of the IPP, IPPS or LPD printer service or eSCL scanner service.
+//
+// Some keys are specific for printers, some for scanners and some
+// are common. Keys that are not applicable to the service kind are
+// normally missed, so the corresponding fields remain empty.
+//
+// See [Bonjour Printing Specification], [PWG 5100.14] (IPP
+// Everywhere) and the Mopria eSCL Specification for details.
+//
+// [Bonjour Printing Specification]: https://developer.apple.com/bonjour/printing-specification/bonjourprinting-1.2.1.pdf
+// [PWG 5100.14]: https://ftp.pwg.org/pub/pwg/candidates/cs-ippeve11-20200515-5100.14.pdf
+type Attributes struct {
+	// Common attributes
+	MakeModel string // "ty": device make and model
+	UUID      string // "UUID": device UUID, lowercase, without "urn:uuid:"
+	Duplex    Bool   // "Duplex": duplex printing or scanning supported
+	AdminURL  string // "adminurl": device administration page
+	Note      string // "note": device location
+
+	// Printer attributes
+	RP    string   // "rp": resource path or queue name, "ipp/print"
+	PDL   []string // "pdl": supported document formats (MIME types)
+	Color Bool     // "Color": color printing supported
+	URF   []string // "URF": AirPrint raster capabilities
+	Kind  []string // "kind": media kinds ("document", "photo", ...)
+
+	// Scanner attributes
+	RS             string   // "rs": eSCL resource path, "eSCL"
+	Representation string   // "representation": URL of device icon
+	ColorSpaces    []string // "cs": color spaces ("color", "grayscale", ...)
+	InputSources   []string // "is": input sources ("platen", "adf", ...)
+}
+
+// ParseAttributes parses the TXT record into [Attributes].
+//
+// Keys are case-insensitive and, according to [RFC6763, 6.4.],
+// only the first occurrence of each key is used. Unknown keys
+// are ignored.
+//
+// [RFC6763, 6.4.]: https://datatracker.ietf.org/doc/html/rfc6763#section-6.4
+func ParseAttributes(txt []string) Attributes {
+	var attrs Attributes
+
+	seen := make(map[string]struct{})
+
+	for _, kv := range txt {
+		k, v, _ := strings.Cut(kv, "=")
+		k = strings.ToLower(k)
+
+		if _, dup := seen[k]; dup {
+			continue
+		}
+		seen[k] = struct{}{}
+
+		switch k {
+		case "rp":
+			attrs.RP = strings.TrimPrefix(v, "/")
+		case "ty":
+			attrs.MakeModel = v
+		case "pdl":
+			attrs.PDL = parseList(v)
+		case "uuid":
+			attrs.UUID = parseUUID(v)
+		case "color":
+			attrs.Color = parseBool(v)
+		case "duplex":
+			attrs.Duplex = parseBool(v)
+		case "urf":
+			attrs.URF = parseList(v)
+		case "adminurl":
+			attrs.AdminURL = v
+		case "note":
+			attrs.Note = v
+		case "kind":
+			attrs.Kind = parseList(v)
+		case "rs":
+			attrs.RS = strings.Trim(v, "/")
+		case "representation":
+			attrs.Representation = v
+		case "cs":
+			attrs.ColorSpaces = parseList(v)
+		case "is":
+			attrs.InputSources = parseList(v)
+		}
+	}
+
+	return attrs
+}
+
+// Txt encodes Attributes into the TXT record of the service of
+// the specified kind. This is the reverse of [ParseAttributes].
+//
+// Only keys for non-empty fields are generated. Common keys are
+// generated for any kind, printer keys only for printer services
+// and scanner keys only for scanner services (see
+// [ServiceKind.IsPrinter] and [ServiceKind.IsScanner]).
+func (attrs *Attributes) Txt(kind ServiceKind) []string {
+	var txt []string
+
+	add := func(k, v string) {
+		if v != "" {
+			txt = append(txt, k+"="+v)
+		}
+	}
+
+	addBool := func(k string, v Bool) {
+		switch v {
+		case False:
+			add(k, "F")
+		case True:
+			add(k, "T")
+		}
+	}
+
+	printer := kind.IsPrinter()
+	scanner := kind.IsScanner()
+
+	if printer {
+		add("rp", attrs.RP)
+	}
+
+	add("ty", attrs.MakeModel)
+	add("adminurl", attrs.AdminURL)
+	add("note", attrs.Note)
+
+	if printer {
+		add("pdl", strings.Join(attrs.PDL, ","))
+	}
+
+	add("UUID", attrs.UUID)
+
+	if printer {
+		addBool("Color", attrs.Color)
+	}
+
+	addBool("Duplex", attrs.Duplex)
+
+	if printer {
+		add("URF", strings.Join(attrs.URF, ","))
+		add("kind", strings.Join(attrs.Kind, ","))
+	}
+
+	if scanner {
+		add("rs", attrs.RS)
+		add("representation", attrs.Representation)
+		add("cs", strings.Join(attrs.ColorSpaces, ","))
+		add("is", strings.Join(attrs.InputSources, ","))
+	}
+
+	return txt
+}
+
+// parseList parses comma-separated list of values.
+func parseList(v string) []string {
+	var list []string
+
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			list = append(list, s)
+		}
+	}
+
+	return list
+}
+
+// parseBool parses "T"/"F" boolean value.
+func parseBool(v string) Bool {
+	switch strings.ToUpper(v) {
+	case "T":
+		return True
+	case "F":
+		return False
+	}
+	return Unknown
+}
+
+// parseUUID normalizes UUID: removes the "urn:uuid:" prefix,
+// if any, and converts to lower case.
+func parseUUID(v string) string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	return strings.TrimPrefix(v, "urn:uuid:")
+}
@@ -0,0 +1,349 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Printers and scanners browser
+//
+//go:build linux || freebsd
+
+package printers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/OpenPrinting/go-avahi"
+)
+
+// EventType identifies the type of the [Event].
+type EventType int
+
+// EventType values:
+const (
+	// New printer discovered
+	PrinterAdded EventType = iota
+
+	// Printer attributes or services changed
+	PrinterUpdated
+
+	// Printer disappeared
+	PrinterRemoved
+
+	// Initial discovery completed: all browsers has reported
+	// avahi.BrowserAllForNow and all discovered services are
+	// resolved.
+	AllForNow
+
+	// Failure occurred; Event.Err contains details.
+	Failure
+
+	// New scanner discovered
+	ScannerAdded
+
+	// Scanner attributes or services changed
+	ScannerUpdated
+
+	// Scanner disappeared
+	ScannerRemoved
+)
+
+// String returns EventType name, for debugging.
+func (t EventType) String() string {
+	switch t {
+	case PrinterAdded:
+		return "PrinterAdded"
+	case PrinterUpdated:
+		return "PrinterUpdated"
+	case PrinterRemoved:
+		return "PrinterRemoved"
+	case AllForNow:
+		return "AllForNow"
+	case Failure:
+		return "Failure"
+	case ScannerAdded:
+		return "ScannerAdded"
+	case ScannerUpdated:
+		return "ScannerUpdated"
+	case ScannerRemoved:
+		return "ScannerRemoved"
+	}
+	return "Unknown"
+}
+
+// Event is generated by the [Browser].
+type Event struct {
+	Type    EventType // Event type
+	Printer *Printer  // Affected printer, for Printer events
+	Scanner *Scanner  // Affected scanner, for Scanner events
+	Err     error     // Error, for Failure
+}
+
+// Browser continuously discovers printers and scanners.
+//
+// [Printer] and [Scanner] objects, reported by the Browser, are never
+// modified after being reported, so they can be safely used from any
+// goroutine.
+type Browser struct {
+	disc     *discovery          // Discovery engine
+	printers map[string]*Printer // Current printers
+	scanners map[string]*Scanner // Current scanners
+	lock     sync.Mutex          // Access lock
+	events   chan *Event         // Events channel
+	cancel   context.CancelFunc  // Cancels proc goroutine
+	done     sync.WaitGroup      // Wait for proc goroutine
+	closed   bool                // Browser is closed
+}
+
+// NewBrowser creates a new [Browser].
+//
+// Function parameters:
+//   - clnt is the pointer to [avahi.Client]
+//   - ifidx is the network interface index. Use [avahi.IfIndexUnspec]
+//     to browse on all interfaces.
+//   - proto is the IP4/IP6 protocol. Use [avahi.ProtocolUnspec] to
+//     browse via both protocols.
+//   - domain is the domain where to browse. Use "" for default
+//   - flags provide some lookup options. See [avahi.LookupFlags]
+//     for details.
+//
+// Browser must be closed after use with the [Browser.Close]
+// function call.
+func NewBrowser(clnt *avahi.Client, ifidx avahi.IfIndex,
+	proto avahi.Protocol, domain string,
+	flags avahi.LookupFlags) (*Browser, error) {
+
+	svctypes := []string{
+		ServiceIPPS.SvcType(),
+		ServiceIPP.SvcType(),
+		ServiceLPD.SvcType(),
+		ServiceESCLS.SvcType(),
+		ServiceESCL.SvcType(),
+	}
+
+	disc, err := newDiscovery(clnt, ifidx, proto, domain, flags, svctypes)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	browser := &Browser{
+		disc:     disc,
+		printers: make(map[string]*Printer),
+		scanners: make(map[string]*Scanner),
+		events:   make(chan *Event),
+		cancel:   cancel,
+	}
+
+	disc.update = func(services []*Service) {
+		browser.update(ctx, services)
+	}
+
+	disc.failure = func(err error) {
+		browser.send(ctx, &Event{Type: Failure, Err: err})
+	}
+
+	disc.done = func() {
+		browser.send(ctx, &Event{Type: AllForNow})
+	}
+
+	browser.done.Add(1)
+	go func() {
+		disc.proc(ctx)
+		close(browser.events)
+		browser.done.Done()
+	}()
+
+	return browser, nil
+}
+
+// Chan returns channel where [Event]s are sent.
+//
+// Unlike avahi objects, Browser doesn't buffer events. Until the
+// event is received from the channel, the Browser doesn't process
+// subsequent Avahi events (which are buffered by Avahi objects).
+func (browser *Browser) Chan() <-chan *Event {
+	return browser.events
+}
+
+// Get waits for the next [Event].
+//
+// It returns:
+//   - event, nil - if event available
+//   - nil, error - if context is canceled
+//   - nil, nil   - if Browser was closed
+func (browser *Browser) Get(ctx context.Context) (*Event, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case evnt := <-browser.Chan():
+		return evnt, nil
+	}
+}
+
+// Printers returns the snapshot of currently known printers,
+// sorted by name.
+func (browser *Browser) Printers() []*Printer {
+	browser.lock.Lock()
+	defer browser.lock.Unlock()
+	return sortPrinters(browser.printers)
+}
+
+// Scanners returns the snapshot of currently known scanners,
+// sorted by name.
+func (browser *Browser) Scanners() []*Scanner {
+	browser.lock.Lock()
+	defer browser.lock.Unlock()
+	return sortScanners(browser.scanners)
+}
+
+// Close closes the [Browser] and releases allocated resources.
+// It closes the event channel, effectively unblocking pending readers.
+//
+// Note, double close is safe.
+func (browser *Browser) Close() {
+	browser.lock.Lock()
+	closed := browser.closed
+	browser.closed = true
+	browser.lock.Unlock()
+
+	if !closed {
+		browser.cancel()
+		browser.done.Wait()
+		browser.disc.close()
+	}
+}
+
+// update updates printers and scanners from the new set of services
+// and generates events.
+func (browser *Browser) update(ctx context.Context, services []*Service) {
+	printers := groupPrinters(services)
+	scanners := groupScanners(services)
+
+	browser.lock.Lock()
+	oldPrinters := browser.printers
+	oldScanners := browser.scanners
+	browser.printers = printers
+	browser.scanners = scanners
+	browser.lock.Unlock()
+
+	// Generate printer events
+	for _, key := range sortedKeys(oldPrinters) {
+		if printers[key] == nil {
+			browser.send(ctx, &Event{Type: PrinterRemoved,
+				Printer: oldPrinters[key]})
+		}
+	}
+
+	for _, key := range sortedKeys(printers) {
+		prn := printers[key]
+		switch prev := oldPrinters[key]; {
+		case prev == nil:
+			browser.send(ctx, &Event{Type: PrinterAdded, Printer: prn})
+		case !reflect.DeepEqual(prev, prn):
+			browser.send(ctx, &Event{Type: PrinterUpdated, Printer: prn})
+		}
+	}
+
+	// Generate scanner events
+	for _, key := range sortedKeys(oldScanners) {
+		if scanners[key] == nil {
+			browser.send(ctx, &Event{Type: ScannerRemoved,
+				Scanner: oldScanners[key]})
+		}
+	}
+
+	for _, key := range sortedKeys(scanners) {
+		scn := scanners[key]
+		switch prev := oldScanners[key]; {
+		case prev == nil:
+			browser.send(ctx, &Event{Type: ScannerAdded, Scanner: scn})
+		case !reflect.DeepEqual(prev, scn):
+			browser.send(ctx, &Event{Type: ScannerUpdated, Scanner: scn})
+		}
+	}
+}
+
+// send sends event to the events channel.
+// If ctx is canceled, event is dropped.
+func (browser *Browser) send(ctx context.Context, evnt *Event) {
+	select {
+	case browser.events <- evnt:
+	case <-ctx.Done():
+	}
+}
+
+// sortedKeys returns keys of the printers or scanners map, sorted.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Discover discovers printers on all network interfaces, via
+// both IP4 and IP6 protocols, in the default domain.
+//
+// It waits until initial discovery is completed (see [AllForNow])
+// and returns all discovered printers, sorted by name.
+//
+// If ctx is canceled before completion, it returns printers,
+// discovered so far, and ctx.Err().
+func Discover(ctx context.Context, clnt *avahi.Client) ([]*Printer, error) {
+	browser, err := discover(ctx, clnt)
+	if browser == nil {
+		return nil, err
+	}
+
+	defer browser.Close()
+	return browser.Printers(), err
+}
+
+// DiscoverScanners discovers eSCL scanners on all network interfaces,
+// via both IP4 and IP6 protocols, in the default domain.
+//
+// It waits until initial discovery is completed (see [AllForNow])
+// and returns all discovered scanners, sorted by name.
+//
+// If ctx is canceled before completion, it returns scanners,
+// discovered so far, and ctx.Err().
+func DiscoverScanners(ctx context.Context,
+	clnt *avahi.Client) ([]*Scanner, error) {
+
+	browser, err := discover(ctx, clnt)
+	if browser == nil {
+		return nil, err
+	}
+
+	defer browser.Close()
+	return browser.Scanners(), err
+}
+
+// discover creates a new Browser and waits until initial discovery
+// is completed or ctx is canceled.
+//
+// On success, it returns the Browser, which must be closed by caller.
+// If Browser cannot be created, it returns nil and error.
+func discover(ctx context.Context, clnt *avahi.Client) (*Browser, error) {
+	browser, err := NewBrowser(clnt, avahi.IfIndexUnspec,
+		avahi.ProtocolUnspec, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		evnt, err := browser.Get(ctx)
+		if err != nil {
+			return browser, err
+		}
+
+		if evnt == nil || evnt.Type == AllForNow {
+			return browser, nil
+		}
+	}
+}
@@ -0,0 +1,300 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Common discovery engine
+//
+//go:build linux || freebsd
+
+package printers
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+
+	"github.com/OpenPrinting/go-avahi"
+)
+
+// discovery browses and resolves services of multiple types.
+//
+// It runs one ServiceBrowser per service type and one ServiceResolver
+// per discovered service instance, and maintains the set of resolved
+// services. Every time this set changes, the update callback is
+// called.
+//
+// All methods, except the constructor and close, are called from
+// the single goroutine that runs the proc method.
+type discovery struct {
+	clnt      *avahi.Client                          // Avahi Client
+	poller    *avahi.Poller                          // Events poller
+	browsers  []*avahi.ServiceBrowser                // Service browsers
+	resolvers map[instanceKey]*avahi.ServiceResolver // Service resolvers
+	resolved  map[instanceKey]*avahi.ServiceResolverEvent
+	pending   map[instanceKey]struct{} // Not resolved yet
+	allForNow int                      // Count of BrowserAllForNow
+	update    func([]*Service)         // Update callback
+	failure   func(error)              // Failure callback
+	done      func()                   // Initial discovery done callback
+	doneSent  bool                     // done already called
+}
+
+// instanceKey identifies the discovered service instance.
+//
+// Avahi reports the same service instance, discovered on a different
+// network interfaces and via different protocols, as separate events.
+type instanceKey struct {
+	ifidx   avahi.IfIndex  // Network interface index
+	proto   avahi.Protocol // Network protocol
+	name    string         // Instance name
+	svctype string         // Service type
+	domain  string         // Service domain
+}
+
+// serviceKey identifies the service, regardless of the network
+// interface and protocol.
+type serviceKey struct {
+	name    string // Instance name
+	svctype string // Service type
+	domain  string // Service domain
+}
+
+// newDiscovery creates a new discovery for the specified
+// service types.
+func newDiscovery(clnt *avahi.Client, ifidx avahi.IfIndex,
+	proto avahi.Protocol, domain string, flags avahi.LookupFlags,
+	svctypes []string) (*discovery, error) {
+
+	disc := &discovery{
+		clnt:      clnt,
+		poller:    avahi.NewPoller(),
+		resolvers: make(map[instanceKey]*avahi.ServiceResolver),
+		resolved:  make(map[instanceKey]*avahi.ServiceResolverEvent),
+		pending:   make(map[instanceKey]struct{}),
+	}
+
+	for _, svctype := range svctypes {
+		browser, err := avahi.NewServiceBrowser(clnt, ifidx, proto,
+			svctype, domain, flags)
+
+		if err != nil {
+			disc.close()
+			return nil, fmt.Errorf("printers: browse %q: %w",
+				svctype, err)
+		}
+
+		disc.browsers = append(disc.browsers, browser)
+		disc.poller.AddServiceBrowser(browser)
+	}
+
+	return disc, nil
+}
+
+// close closes all browsers and resolvers.
+//
+// It must be called after the proc goroutine is finished.
+func (disc *discovery) close() {
+	for _, browser := range disc.browsers {
+		browser.Close()
+	}
+
+	for _, resolver := range disc.resolvers {
+		resolver.Close()
+	}
+}
+
+// proc handles Avahi events until context is canceled.
+func (disc *discovery) proc(ctx context.Context) {
+	for {
+		evnt, err := disc.poller.Poll(ctx)
+		if err != nil {
+			return
+		}
+
+		switch evnt := evnt.(type) {
+		case *avahi.ServiceBrowserEvent:
+			disc.handleBrowserEvent(evnt)
+		case *avahi.ServiceResolverEvent:
+			disc.handleResolverEvent(evnt)
+		}
+
+		disc.checkDone()
+	}
+}
+
+// handleBrowserEvent handles the ServiceBrowserEvent.
+func (disc *discovery) handleBrowserEvent(evnt *avahi.ServiceBrowserEvent) {
+	key := instanceKey{
+		ifidx:   evnt.IfIdx,
+		proto:   evnt.Proto,
+		name:    evnt.InstanceName,
+		svctype: evnt.SvcType,
+		domain:  evnt.Domain,
+	}
+
+	switch evnt.Event {
+	case avahi.BrowserNew:
+		if disc.resolvers[key] != nil {
+			return
+		}
+
+		resolver, err := avahi.NewServiceResolver(
+			disc.clnt,
+			evnt.IfIdx,
+			evnt.Proto,
+			evnt.InstanceName,
+			evnt.SvcType,
+			evnt.Domain,
+			evnt.Proto,
+			0)
+
+		if err != nil {
+			disc.failure(fmt.Errorf("printers: resolve %q: %w",
+				evnt.InstanceName, err))
+			return
+		}
+
+		disc.resolvers[key] = resolver
+		disc.pending[key] = struct{}{}
+		disc.poller.AddServiceResolver(resolver)
+
+	case avahi.BrowserRemove:
+		if resolver := disc.resolvers[key]; resolver != nil {
+			resolver.Close()
+			delete(disc.resolvers, key)
+			delete(disc.pending, key)
+
+			if disc.resolved[key] != nil {
+				delete(disc.resolved, key)
+				disc.update(disc.services())
+			}
+		}
+
+	case avahi.BrowserAllForNow:
+		disc.allForNow++
+
+	case avahi.BrowserFailure:
+		disc.allForNow++
+		disc.failure(fmt.Errorf("printers: browse %q: %w",
+			evnt.SvcType, evnt.Err))
+	}
+}
+
+// handleResolverEvent handles the ServiceResolverEvent.
+func (disc *discovery) handleResolverEvent(evnt *avahi.ServiceResolverEvent) {
+	key := instanceKey{
+		ifidx:   evnt.IfIdx,
+		proto:   evnt.Proto,
+		name:    evnt.InstanceName,
+		svctype: evnt.SvcType,
+		domain:  evnt.Domain,
+	}
+
+	if disc.resolvers[key] == nil {
+		return
+	}
+
+	delete(disc.pending, key)
+
+	switch evnt.Event {
+	case avahi.ResolverFound:
+		disc.resolved[key] = evnt
+
+	case avahi.ResolverFailure:
+		if disc.resolved[key] == nil {
+			return
+		}
+		delete(disc.resolved, key)
+	}
+
+	disc.update(disc.services())
+}
+
+// checkDone calls the done callback when all browsers has reported
+// BrowserAllForNow (or failure) and all discovered services are
+// resolved.
+func (disc *discovery) checkDone() {
+	if !disc.doneSent && disc.allForNow >= len(disc.browsers) &&
+		len(disc.pending) == 0 {
+		disc.doneSent = true
+		disc.done()
+	}
+}
+
+// services returns all currently resolved services.
+//
+// The same service, resolved on the different network interfaces
+// and via different protocols, is returned as a single Service
+// with multiple addresses.
+//
+// Returned slice is sorted by the instance name and service type.
+func (disc *discovery) services() []*Service {
+	// Sort resolved events for the stable output
+	keys := make([]instanceKey, 0, len(disc.resolved))
+	for key := range disc.resolved {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		k1, k2 := keys[i], keys[j]
+		switch {
+		case k1.name != k2.name:
+			return k1.name < k2.name
+		case k1.svctype != k2.svctype:
+			return k1.svctype < k2.svctype
+		case k1.domain != k2.domain:
+			return k1.domain < k2.domain
+		case k1.ifidx != k2.ifidx:
+			return k1.ifidx < k2.ifidx
+		}
+		return k1.proto < k2.proto
+	})
+
+	// Merge them into services
+	services := []*Service{}
+	svcmap := make(map[serviceKey]*Service)
+
+	for _, key := range keys {
+		evnt := disc.resolved[key]
+		skey := serviceKey{key.name, key.svctype, key.domain}
+		svc := svcmap[skey]
+
+		if svc == nil {
+			svc = &Service{
+				Kind:         serviceKindBySvcType(key.svctype),
+				InstanceName: key.name,
+				SvcType:      key.svctype,
+				Domain:       key.domain,
+				Hostname:     evnt.Hostname,
+				Port:         evnt.Port,
+				Txt:          evnt.Txt,
+				Attrs:        ParseAttributes(evnt.Txt),
+			}
+
+			svcmap[skey] = svc
+			services = append(services, svc)
+		}
+
+		svc.Addrs = appendAddr(svc.Addrs, evnt.Addr)
+	}
+
+	return services
+}
+
+// appendAddr appends address to the slice of addresses, if it is
+// valid and not in the slice yet.
+func appendAddr(addrs []netip.Addr, addr netip.Addr) []netip.Addr {
+	if !addr.IsValid() {
+		return addrs
+	}
+
+	for _, addr2 := range addrs {
+		if addr2 == addr {
+			return addrs
+		}
+	}
+
+	return append(addrs, addr)
+}
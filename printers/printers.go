@@ -0,0 +1,381 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Printers and services
+//
+//go:build linux || freebsd
+
+// Package printers implements discovery of network printers and
+// scanners, using DNS-SD service discovery, provided by the avahi
+// package.
+//
+// It browses for the IPP ("_ipp._tcp"), IPPS ("_ipps._tcp") and
+// LPD ("_printer._tcp") printer services and eSCL ("_uscan._tcp",
+// "_uscans._tcp") scanner services, resolves them, decodes their TXT
+// records and merges advertisements of the same device, using the
+// device UUID, into a single [Printer] or [Scanner]. If the same
+// device is both printer and scanner (MFP), Printer and Scanner
+// refer each other's services.
+//
+// Usage:
+//
+//	clnt, err := avahi.NewClient(0)
+//	...
+//	printers, err := printers.Discover(ctx, clnt)
+//	scanners, err := printers.DiscoverScanners(ctx, clnt)
+//
+// or, for the continuous monitoring, use [Browser].
+//
+// For publishing printers (virtual printers, print servers), see
+// [Advertisement].
+package printers
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/OpenPrinting/go-avahi"
+)
+
+// ServiceKind identifies the printing or scanning protocol of
+// the [Service].
+//
+// ServiceKind values are ordered by preference: if device is
+// available via multiple protocols, services of the more preferable
+// kind go first.
+type ServiceKind int
+
+// ServiceKind values:
+const (
+	ServiceIPPS    ServiceKind = iota // IPP over HTTPS, "_ipps._tcp"
+	ServiceIPP                        // IPP, "_ipp._tcp"
+	ServiceLPD                        // LPD, "_printer._tcp"
+	ServiceESCLS                      // eSCL over HTTPS, "_uscans._tcp"
+	ServiceESCL                       // eSCL, "_uscan._tcp"
+	ServiceUnknown                    // Unknown service type
+)
+
+// serviceKindSvcTypes contains service types for each ServiceKind.
+var serviceKindSvcTypes = []string{
+	ServiceIPPS:  "_ipps._tcp",
+	ServiceIPP:   "_ipp._tcp",
+	ServiceLPD:   "_printer._tcp",
+	ServiceESCLS: "_uscans._tcp",
+	ServiceESCL:  "_uscan._tcp",
+}
+
+// serviceKindNames contains names for each ServiceKind.
+var serviceKindNames = []string{
+	ServiceIPPS:  "IPPS",
+	ServiceIPP:   "IPP",
+	ServiceLPD:   "LPD",
+	ServiceESCLS: "ESCLS",
+	ServiceESCL:  "ESCL",
+}
+
+// serviceKindSchemes contains URL schemes for each ServiceKind.
+var serviceKindSchemes = []string{
+	ServiceIPPS:  "ipps",
+	ServiceIPP:   "ipp",
+	ServiceLPD:   "lpd",
+	ServiceESCLS: "https",
+	ServiceESCL:  "http",
+}
+
+// String returns ServiceKind name, for debugging.
+func (kind ServiceKind) String() string {
+	if kind >= 0 && int(kind) < len(serviceKindNames) {
+		return serviceKindNames[kind]
+	}
+	return "Unknown"
+}
+
+// IsPrinter reports if ServiceKind is the printer service.
+func (kind ServiceKind) IsPrinter() bool {
+	return kind == ServiceIPPS || kind == ServiceIPP || kind == ServiceLPD
+}
+
+// IsScanner reports if ServiceKind is the scanner service.
+func (kind ServiceKind) IsScanner() bool {
+	return kind == ServiceESCLS || kind == ServiceESCL
+}
+
+// SvcType returns the DNS-SD service type for the ServiceKind.
+func (kind ServiceKind) SvcType() string {
+	if kind >= 0 && int(kind) < len(serviceKindSvcTypes) {
+		return serviceKindSvcTypes[kind]
+	}
+	return ""
+}
+
+// serviceKindBySvcType returns ServiceKind by the service type.
+func serviceKindBySvcType(svctype string) ServiceKind {
+	for kind, s := range serviceKindSvcTypes {
+		if avahi.DomainEqual(s, svctype) {
+			return ServiceKind(kind)
+		}
+	}
+	return ServiceUnknown
+}
+
+// Service represents a single resolved printer or scanner advertisement.
+type Service struct {
+	Kind         ServiceKind  // Service kind
+	InstanceName string       // Service instance name
+	SvcType      string       // Service type
+	Domain       string       // Service domain
+	Hostname     string       // Service hostname
+	Port         uint16       // Service IP port, 0 for placeholder
+	Addrs        []netip.Addr // Service IP addresses
+	Txt          []string     // Raw TXT record
+	Attrs        Attributes   // Attributes, decoded from the TXT record
+}
+
+// IsPlaceholder reports if Service is the "placeholder", i.e.,
+// registered with zero port to reserve the instance name for the
+// protocol that the printer doesn't actually support.
+//
+// Printers often register the "_printer._tcp" placeholder this way,
+// when LPD is not supported.
+func (svc *Service) IsPlaceholder() bool {
+	return svc.Port == 0
+}
+
+// URL returns the service URL.
+//
+// For printers it returns printer URL, like
+// "ipp://host.local:631/ipp/print".
+//
+// For scanners it returns the eSCL base URL, like
+// "http://host.local:8080/eSCL/". It ends with slash, so
+// eSCL requests can be constructed by appending the request
+// path (i.e., "ScannerCapabilities") to the base URL. If the
+// "rs" key is missed, the default "eSCL" path is assumed.
+//
+// For placeholder services it returns empty string.
+func (svc *Service) URL() string {
+	if svc.IsPlaceholder() || svc.Kind == ServiceUnknown {
+		return ""
+	}
+
+	path := "/" + svc.Attrs.RP
+	if svc.Kind.IsScanner() {
+		rs := svc.Attrs.RS
+		if rs == "" {
+			rs = "eSCL"
+		}
+		path = "/" + rs + "/"
+	}
+
+	u := url.URL{
+		Scheme: serviceKindSchemes[svc.Kind],
+		Host: net.JoinHostPort(strings.TrimSuffix(svc.Hostname, "."),
+			strconv.Itoa(int(svc.Port))),
+		Path: path,
+	}
+
+	return u.String()
+}
+
+// Printer represents a discovered printer, that may be available
+// via multiple protocols.
+//
+// Printer attributes are taken from the most preferable non-placeholder
+// service (see [ServiceKind]).
+//
+// If the same device also provides scanner services, they are
+// available as ScanServices.
+type Printer struct {
+	Attributes              // Printer attributes
+	Name         string     // Printer name (instance name)
+	Services     []*Service // Printer services, most preferable first
+	ScanServices []*Service // Services of the same device's scanner
+}
+
+// Scanner represents a discovered eSCL scanner, that may be available
+// via multiple protocols.
+//
+// Scanner attributes are taken from the most preferable non-placeholder
+// service (see [ServiceKind]).
+//
+// If the same device also provides printer services, they are
+// available as PrintServices.
+type Scanner struct {
+	Attributes               // Scanner attributes
+	Name          string     // Scanner name (instance name)
+	Services      []*Service // Scanner services, most preferable first
+	PrintServices []*Service // Services of the same device's printer
+}
+
+// groupServices groups services by device.
+//
+// Services with the same UUID are merged together. Services without
+// UUID join the device with the same instance name and domain, if
+// any, or form a device on their own.
+//
+// Within each group, services are sorted by preference, non-placeholder
+// services first.
+//
+// It returns map of groups, indexed by the device key, that
+// remains the same for the same device between calls.
+func groupServices(services []*Service) map[string][]*Service {
+	groups := make(map[string][]*Service)
+	byname := make(map[string]string)
+
+	add := func(key string, svc *Service) {
+		groups[key] = append(groups[key], svc)
+		byname[deviceNameKey(svc)] = key
+	}
+
+	// Services with UUID go first
+	for _, svc := range services {
+		if svc.Attrs.UUID != "" {
+			add("uuid:"+svc.Attrs.UUID, svc)
+		}
+	}
+
+	// Then services without UUID
+	for _, svc := range services {
+		if svc.Attrs.UUID == "" {
+			namekey := deviceNameKey(svc)
+			key, found := byname[namekey]
+			if !found {
+				key = "name:" + namekey
+			}
+
+			add(key, svc)
+		}
+	}
+
+	// Sort services
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			s1, s2 := group[i], group[j]
+			if s1.IsPlaceholder() != s2.IsPlaceholder() {
+				return !s1.IsPlaceholder()
+			}
+			return s1.Kind < s2.Kind
+		})
+	}
+
+	return groups
+}
+
+// splitServices splits group of services into printer and
+// scanner services.
+//
+// Printer and scanner services are returned only if there
+// is at least one non-placeholder service of that kind.
+func splitServices(group []*Service) (prn, scn []*Service) {
+	for _, svc := range group {
+		switch {
+		case svc.Kind.IsPrinter():
+			prn = append(prn, svc)
+		case svc.Kind.IsScanner():
+			scn = append(scn, svc)
+		}
+	}
+
+	if len(prn) == 0 || prn[0].IsPlaceholder() {
+		prn = nil
+	}
+
+	if len(scn) == 0 || scn[0].IsPlaceholder() {
+		scn = nil
+	}
+
+	return
+}
+
+// groupPrinters groups services into printers. See groupServices
+// for details.
+//
+// Printers that have only placeholder services are dropped.
+func groupPrinters(services []*Service) map[string]*Printer {
+	printers := make(map[string]*Printer)
+
+	for key, group := range groupServices(services) {
+		prn, scn := splitServices(group)
+		if prn != nil {
+			printers[key] = &Printer{
+				Attributes:   prn[0].Attrs,
+				Name:         prn[0].InstanceName,
+				Services:     prn,
+				ScanServices: scn,
+			}
+		}
+	}
+
+	return printers
+}
+
+// groupScanners groups services into scanners. See groupServices
+// for details.
+//
+// Scanners that have only placeholder services are dropped.
+func groupScanners(services []*Service) map[string]*Scanner {
+	scanners := make(map[string]*Scanner)
+
+	for key, group := range groupServices(services) {
+		prn, scn := splitServices(group)
+		if scn != nil {
+			scanners[key] = &Scanner{
+				Attributes:    scn[0].Attrs,
+				Name:          scn[0].InstanceName,
+				Services:      scn,
+				PrintServices: prn,
+			}
+		}
+	}
+
+	return scanners
+}
+
+// deviceNameKey returns the key that identifies the device by
+// the instance name and domain of the service.
+func deviceNameKey(svc *Service) string {
+	instance := avahi.DomainFrom([]string{strings.ToLower(svc.InstanceName)})
+	return instance + "." + avahi.DomainCanonical(svc.Domain)
+}
+
+// sortPrinters returns printers, sorted by name.
+func sortPrinters(printers map[string]*Printer) []*Printer {
+	list := make([]*Printer, 0, len(printers))
+	for _, prn := range printers {
+		list = append(list, prn)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		p1, p2 := list[i], list[j]
+		if p1.Name != p2.Name {
+			return p1.Name < p2.Name
+		}
+		return p1.UUID < p2.UUID
+	})
+
+	return list
+}
+
+// sortScanners returns scanners, sorted by name.
+func sortScanners(scanners map[string]*Scanner) []*Scanner {
+	list := make([]*Scanner, 0, len(scanners))
+	for _, scn := range scanners {
+		list = append(list, scn)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		s1, s2 := list[i], list[j]
+		if s1.Name != s2.Name {
+			return s1.Name < s2.Name
+		}
+		return s1.UUID < s2.UUID
+	})
+
+	return list
+}
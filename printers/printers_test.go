@@ -0,0 +1,405 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Printers discovery test
+//
+//go:build linux || freebsd
+
+package printers
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseAttributes tests ParseAttributes function
+func TestParseAttributes(t *testing.T) {
+	txt := []string{
+		"txtvers=1",
+		"rp=ipp/print",
+		"ty=Kyocera ECOSYS M2040dn",
+		"pdl=application/pdf, image/urf,,image/jpeg",
+		"UUID=urn:uuid:4509A320-00A0-008F-00B6-00257366D6C4",
+		"Color=F",
+		"duplex=t",
+		"URF=V1.4,CP1,W8,RS300-600",
+		"adminurl=https://printer.local/",
+		"note=Room 101",
+		"kind=document,envelope",
+		"RP=ignored",
+	}
+
+	expected := Attributes{
+		RP:        "ipp/print",
+		MakeModel: "Kyocera ECOSYS M2040dn",
+		PDL:       []string{"application/pdf", "image/urf", "image/jpeg"},
+		UUID:      "4509a320-00a0-008f-00b6-00257366d6c4",
+		Color:     False,
+		Duplex:    True,
+		URF:       []string{"V1.4", "CP1", "W8", "RS300-600"},
+		AdminURL:  "https://printer.local/",
+		Note:      "Room 101",
+		Kind:      []string{"document", "envelope"},
+	}
+
+	attrs := ParseAttributes(txt)
+	if !reflect.DeepEqual(attrs, expected) {
+		t.Errorf("ParseAttributes:\n"+
+			"expected: %#v\n"+
+			"present:  %#v\n",
+			expected, attrs)
+	}
+
+	txt = []string{
+		"txtvers=1",
+		"ty=Kyocera ECOSYS M2040dn",
+		"UUID=4509a320-00a0-008f-00b6-00257366d6c4",
+		"rs=/eSCL/",
+		"representation=http://printer.local/icon.png",
+		"cs=color,grayscale,binary",
+		"is=platen,adf",
+		"duplex=T",
+	}
+
+	expected = Attributes{
+		MakeModel:      "Kyocera ECOSYS M2040dn",
+		UUID:           "4509a320-00a0-008f-00b6-00257366d6c4",
+		Duplex:         True,
+		RS:             "eSCL",
+		Representation: "http://printer.local/icon.png",
+		ColorSpaces:    []string{"color", "grayscale", "binary"},
+		InputSources:   []string{"platen", "adf"},
+	}
+
+	attrs = ParseAttributes(txt)
+	if !reflect.DeepEqual(attrs, expected) {
+		t.Errorf("ParseAttributes:\n"+
+			"expected: %#v\n"+
+			"present:  %#v\n",
+			expected, attrs)
+	}
+
+	attrs = ParseAttributes([]string{"Color=X"})
+	if attrs.Color != Unknown || attrs.Duplex != Unknown {
+		t.Errorf("ParseAttributes: Unknown expected")
+	}
+}
+
+// TestAttributesTxt tests Attributes.Txt
+func TestAttributesTxt(t *testing.T) {
+	attrs := Attributes{
+		MakeModel:      "Kyocera ECOSYS M2040dn",
+		UUID:           "4509a320-00a0-008f-00b6-00257366d6c4",
+		Duplex:         True,
+		AdminURL:       "https://printer.local/",
+		Note:           "Room 101",
+		RP:             "ipp/print",
+		PDL:            []string{"application/pdf", "image/urf"},
+		Color:          False,
+		URF:            []string{"V1.4", "W8"},
+		Kind:           []string{"document"},
+		RS:             "eSCL",
+		Representation: "http://printer.local/icon.png",
+		ColorSpaces:    []string{"color", "grayscale"},
+		InputSources:   []string{"platen", "adf"},
+	}
+
+	type testData struct {
+		kind ServiceKind // Service kind
+		txt  []string    // Expected TXT record
+	}
+
+	tests := []testData{
+		{
+			kind: ServiceIPP,
+			txt: []string{
+				"rp=ipp/print",
+				"ty=Kyocera ECOSYS M2040dn",
+				"adminurl=https://printer.local/",
+				"note=Room 101",
+				"pdl=application/pdf,image/urf",
+				"UUID=4509a320-00a0-008f-00b6-00257366d6c4",
+				"Color=F",
+				"Duplex=T",
+				"URF=V1.4,W8",
+				"kind=document",
+			},
+		},
+
+		{
+			kind: ServiceESCL,
+			txt: []string{
+				"ty=Kyocera ECOSYS M2040dn",
+				"adminurl=https://printer.local/",
+				"note=Room 101",
+				"UUID=4509a320-00a0-008f-00b6-00257366d6c4",
+				"Duplex=T",
+				"rs=eSCL",
+				"representation=http://printer.local/icon.png",
+				"cs=color,grayscale",
+				"is=platen,adf",
+			},
+		},
+
+		{
+			kind: ServiceUnknown,
+			txt: []string{
+				"ty=Kyocera ECOSYS M2040dn",
+				"adminurl=https://printer.local/",
+				"note=Room 101",
+				"UUID=4509a320-00a0-008f-00b6-00257366d6c4",
+				"Duplex=T",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		txt := attrs.Txt(test.kind)
+		if !reflect.DeepEqual(txt, test.txt) {
+			t.Errorf("%s:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.kind, test.txt, txt)
+		}
+	}
+}
+
+// TestServiceURL tests Service.URL
+func TestServiceURL(t *testing.T) {
+	type testData struct {
+		svc *Service
+		url string
+	}
+
+	tests := []testData{
+		{
+			svc: &Service{
+				Kind:     ServiceIPP,
+				Hostname: "printer.local",
+				Port:     631,
+				Attrs:    Attributes{RP: "ipp/print"},
+			},
+			url: "ipp://printer.local:631/ipp/print",
+		},
+
+		{
+			svc: &Service{
+				Kind:     ServiceIPPS,
+				Hostname: "printer.local.",
+				Port:     443,
+			},
+			url: "ipps://printer.local:443/",
+		},
+
+		{
+			svc: &Service{
+				Kind:     ServiceLPD,
+				Hostname: "printer.local",
+				Port:     515,
+				Attrs:    Attributes{RP: "queue"},
+			},
+			url: "lpd://printer.local:515/queue",
+		},
+
+		{
+			svc: &Service{
+				Kind:     ServiceLPD,
+				Hostname: "printer.local",
+				Port:     0,
+			},
+			url: "",
+		},
+
+		{
+			svc: &Service{
+				Kind:     ServiceESCL,
+				Hostname: "scanner.local",
+				Port:     8080,
+			},
+			url: "http://scanner.local:8080/eSCL/",
+		},
+
+		{
+			svc: &Service{
+				Kind:     ServiceESCLS,
+				Hostname: "scanner.local",
+				Port:     443,
+				Attrs:    Attributes{RS: "scan/eSCL"},
+			},
+			url: "https://scanner.local:443/scan/eSCL/",
+		},
+	}
+
+	for _, test := range tests {
+		url := test.svc.URL()
+		if url != test.url {
+			t.Errorf("%s:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.svc.Kind, test.url, url)
+		}
+	}
+}
+
+// TestGroupPrinters tests groupPrinters function
+func TestGroupPrinters(t *testing.T) {
+	const uuid = "4509a320-00a0-008f-00b6-00257366d6c4"
+
+	ipp := &Service{
+		Kind:         ServiceIPP,
+		InstanceName: "Kyocera",
+		Domain:       "local",
+		Port:         631,
+		Attrs:        Attributes{UUID: uuid, MakeModel: "IPP"},
+	}
+
+	ipps := &Service{
+		Kind:         ServiceIPPS,
+		InstanceName: "Kyocera (secure)",
+		Domain:       "local",
+		Port:         443,
+		Attrs:        Attributes{UUID: uuid, MakeModel: "IPPS"},
+	}
+
+	lpd := &Service{
+		Kind:         ServiceLPD,
+		InstanceName: "kyocera",
+		Domain:       "local",
+		Port:         0,
+	}
+
+	other := &Service{
+		Kind:         ServiceIPP,
+		InstanceName: "Other",
+		Domain:       "local",
+		Port:         631,
+	}
+
+	placeholder := &Service{
+		Kind:         ServiceLPD,
+		InstanceName: "Placeholder",
+		Domain:       "local",
+		Port:         0,
+	}
+
+	printers := groupPrinters([]*Service{lpd, ipp, other, ipps, placeholder})
+	if len(printers) != 2 {
+		t.Errorf("groupPrinters: 2 printers expected, %d present",
+			len(printers))
+		return
+	}
+
+	prn := printers["uuid:"+uuid]
+	if prn == nil {
+		t.Errorf("groupPrinters: printer by UUID not found")
+		return
+	}
+
+	expected := []*Service{ipps, ipp, lpd}
+	if !reflect.DeepEqual(prn.Services, expected) {
+		t.Errorf("groupPrinters: services not merged properly")
+	}
+
+	if prn.Name != "Kyocera (secure)" || prn.MakeModel != "IPPS" {
+		t.Errorf("groupPrinters: wrong printer attributes: %q %q",
+			prn.Name, prn.MakeModel)
+	}
+
+	list := sortPrinters(printers)
+	if list[0] != prn || list[1].Name != "Other" {
+		t.Errorf("sortPrinters: wrong order")
+	}
+}
+
+// TestGroupScanners tests groupScanners function
+func TestGroupScanners(t *testing.T) {
+	const uuid = "4509a320-00a0-008f-00b6-00257366d6c4"
+
+	ipp := &Service{
+		Kind:         ServiceIPP,
+		InstanceName: "Kyocera",
+		Domain:       "local",
+		Port:         631,
+		Attrs:        Attributes{UUID: uuid},
+	}
+
+	escl := &Service{
+		Kind:         ServiceESCL,
+		InstanceName: "Kyocera",
+		Domain:       "local",
+		Port:         80,
+		Attrs:        Attributes{UUID: uuid, MakeModel: "ESCL"},
+	}
+
+	escls := &Service{
+		Kind:         ServiceESCLS,
+		InstanceName: "Kyocera",
+		Domain:       "local",
+		Port:         443,
+		Attrs:        Attributes{UUID: uuid, MakeModel: "ESCLS"},
+	}
+
+	// Scanner without UUID joins the printer with the same name
+	other := &Service{
+		Kind:         ServiceESCL,
+		InstanceName: "Other",
+		Domain:       "local",
+		Port:         80,
+	}
+
+	otherPrn := &Service{
+		Kind:         ServiceIPP,
+		InstanceName: "other",
+		Domain:       "local.",
+		Port:         631,
+	}
+
+	services := []*Service{ipp, escl, other, escls, otherPrn}
+
+	scanners := groupScanners(services)
+	if len(scanners) != 2 {
+		t.Errorf("groupScanners: 2 scanners expected, %d present",
+			len(scanners))
+		return
+	}
+
+	scn := scanners["uuid:"+uuid]
+	if scn == nil {
+		t.Errorf("groupScanners: scanner by UUID not found")
+		return
+	}
+
+	if !reflect.DeepEqual(scn.Services, []*Service{escls, escl}) {
+		t.Errorf("groupScanners: services not merged properly")
+	}
+
+	if !reflect.DeepEqual(scn.PrintServices, []*Service{ipp}) {
+		t.Errorf("groupScanners: print services not linked")
+	}
+
+	if scn.MakeModel != "ESCLS" {
+		t.Errorf("groupScanners: wrong scanner attributes: %q",
+			scn.MakeModel)
+	}
+
+	printers := groupPrinters(services)
+	if len(printers) != 2 {
+		t.Errorf("groupPrinters: 2 printers expected, %d present",
+			len(printers))
+		return
+	}
+
+	prn := printers["uuid:"+uuid]
+	if prn == nil || !reflect.DeepEqual(prn.ScanServices, scn.Services) {
+		t.Errorf("groupPrinters: scan services not linked")
+	}
+
+	list := sortScanners(scanners)
+	if list[0] != scn ||
+		!reflect.DeepEqual(list[1].Services, []*Service{other}) ||
+		!reflect.DeepEqual(list[1].PrintServices, []*Service{otherPrn}) {
+		t.Errorf("sortScanners: wrong order or grouping")
+	}
+}
@@ -39,3 +39,23 @@ func (proto Protocol) String() string {
 	return n
 
 }
+
+// MarshalText returns a name of the Protocol.
+// It implements the [encoding.TextMarshaler] interface.
+func (proto Protocol) MarshalText() ([]byte, error) {
+	return enumMarshalText(proto, protocolNames)
+}
+
+// UnmarshalText decodes Protocol from its name.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (proto *Protocol) UnmarshalText(text []byte) error {
+	return enumUnmarshalText("Protocol.UnmarshalText", proto, text,
+		protocolNames)
+}
+
+// UnmarshalJSON decodes Protocol from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (proto *Protocol) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("Protocol.UnmarshalJSON", proto, data,
+		proto.UnmarshalText)
+}
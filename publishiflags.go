@@ -9,10 +9,6 @@
 
 package avahi
 
-import (
-	"strings"
-)
-
 // #include <avahi-common/defs.h>
 import "C"
 
@@ -49,39 +45,40 @@ const (
 	PublishUseMulticast PublishFlags = C.AVAHI_PUBLISH_USE_MULTICAST
 )
 
+// publishFlagsNames contains names of PublishFlags bits.
+var publishFlagsNames = []flagName[PublishFlags]{
+	{PublishUnique, "unique"},
+	{PublishNoProbe, "no-probe"},
+	{PublishNoAnnounce, "no-announce"},
+	{PublishAllowMultiple, "allow-multiple"},
+	{PublishNoReverse, "no-reverse"},
+	{PublishNoCookie, "no-cookie"},
+	{PublishUpdate, "update"},
+	{PublishUseWideArea, "use-wan"},
+	{PublishUseMulticast, "use-mdns"},
+}
+
 // String returns PublishFlags as string, for debugging
 func (flags PublishFlags) String() string {
-	s := []string{}
-
-	if flags&PublishUnique != 0 {
-		s = append(s, "unique")
-	}
-	if flags&PublishNoProbe != 0 {
-		s = append(s, "no-probe")
-	}
-	if flags&PublishNoAnnounce != 0 {
-		s = append(s, "no-announce")
-	}
-	if flags&PublishAllowMultiple != 0 {
-		s = append(s, "allow-multiple")
-	}
+	return flagsString(flags, publishFlagsNames, ",")
+}
 
-	if flags&PublishNoReverse != 0 {
-		s = append(s, "no-reverse")
-	}
-	if flags&PublishNoCookie != 0 {
-		s = append(s, "no-cookie")
-	}
+// MarshalText returns PublishFlags as "|"-separated list of names.
+// It implements the [encoding.TextMarshaler] interface.
+func (flags PublishFlags) MarshalText() ([]byte, error) {
+	return flagsMarshalText(flags, publishFlagsNames)
+}
 
-	if flags&PublishUpdate != 0 {
-		s = append(s, "update")
-	}
-	if flags&PublishUseWideArea != 0 {
-		s = append(s, "use-wan")
-	}
-	if flags&PublishUseMulticast != 0 {
-		s = append(s, "use-mdns")
-	}
+// UnmarshalText decodes PublishFlags from "|"-separated list of names.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (flags *PublishFlags) UnmarshalText(text []byte) error {
+	return flagsUnmarshalText("PublishFlags.UnmarshalText", flags, text,
+		publishFlagsNames)
+}
 
-	return strings.Join(s, ",")
+// UnmarshalJSON decodes PublishFlags from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (flags *PublishFlags) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("PublishFlags.UnmarshalJSON", flags, data,
+		flags.UnmarshalText)
 }
@@ -96,30 +96,45 @@ func NewRecordBrowser(
 	defer C.free(unsafe.Pointer(cname))
 
 	// Create AvahiRecordBrowser
-	avahiClient := clnt.begin()
-	defer clnt.end()
-
-	browser.avahiBrowser = C.avahi_record_browser_new(
-		avahiClient,
-		C.AvahiIfIndex(ifidx),
-		C.AvahiProtocol(proto),
-		cname,
-		C.uint16_t(dnsclass),
-		C.uint16_t(dnstype),
-		C.AvahiLookupFlags(flags),
-		C.AvahiRecordBrowserCallback(C.recordBrowserCallback),
-		unsafe.Pointer(&browser.handle),
-	)
-
-	if browser.avahiBrowser == nil {
+	info := &ObjectInfo{
+		Kind:   "RecordBrowser",
+		IfIdx:  ifidx,
+		Proto:  proto,
+		Name:   name,
+		RClass: dnsclass,
+		RType:  dnstype,
+		Flags:  flags,
+	}
+
+	err := newObject(context.Background(), clnt, browser, &browser.queue,
+		info, func(avahiClient *C.AvahiClient) error {
+			browser.avahiBrowser = C.avahi_record_browser_new(
+				avahiClient,
+				C.AvahiIfIndex(ifidx),
+				C.AvahiProtocol(proto),
+				cname,
+				C.uint16_t(dnsclass),
+				C.uint16_t(dnstype),
+				C.AvahiLookupFlags(flags),
+				C.AvahiRecordBrowserCallback(
+					C.recordBrowserCallback),
+				unsafe.Pointer(&browser.handle),
+			)
+
+			if browser.avahiBrowser == nil {
+				return clnt.errno()
+			}
+
+			return nil
+		})
+
+	if err != nil {
 		browser.queue.Close()
 		browser.handle.Delete()
-		return nil, clnt.errno()
+		return nil, newError("NewRecordBrowser", err,
+			name, dnsclass, dnstype)
 	}
 
-	// Register self to be closed if Client is closed
-	browser.clnt.addCloser(browser)
-
 	return browser, nil
 }
 
@@ -150,10 +165,14 @@ func (browser *RecordBrowser) Get(ctx context.Context) (*RecordBrowserEvent,
 // Note, double close is safe.
 func (browser *RecordBrowser) Close() {
 	if !browser.closed.Swap(true) {
+		browser.clnt.notifyClose("RecordBrowser", browser)
+
 		browser.clnt.begin()
 		browser.clnt.delCloser(browser)
-		C.avahi_record_browser_free(browser.avahiBrowser)
-		browser.avahiBrowser = nil
+		if browser.avahiBrowser != nil {
+			C.avahi_record_browser_free(browser.avahiBrowser)
+			browser.avahiBrowser = nil
+		}
 		browser.clnt.end()
 
 		browser.queue.Close()
@@ -197,5 +216,6 @@ func recordBrowserCallback(
 		evnt.Err = browser.clnt.errno()
 	}
 
+	browser.clnt.notifyEvent("RecordBrowser", browser, evnt)
 	browser.queue.Push(evnt)
 }
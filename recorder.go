@@ -0,0 +1,180 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Events recorder
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Recorder captures events of the [Client] and all objects, created
+// by it, for later replay with the [NewReplayClient].
+//
+// Recorder implements the [Observer] interface and needs to be
+// attached to the Client with the [Client.SetObserver]:
+//
+//	rec, err := avahi.NewRecorder(clnt, file)
+//	...
+//	clnt.SetObserver(rec)
+//
+// Capture is written as JSON lines, one record per line. Each record
+// has the "time" field, that contains its timestamp. Records are:
+//   - the header with the Client state, host and domain names
+//   - object creation, with the [ObjectInfo]
+//   - event, generated by the object (i.e., [ServiceBrowserEvent])
+//   - object Close
+//
+// Objects are identified by the "obj" field, which is unique for
+// each created object and 0 for the Client itself. Objects, created
+// before the Recorder is attached, are not recorded.
+//
+// Recorder writes each record with a single Write call, from the Avahi
+// event loop thread, so the io.Writer must be fast and must not block.
+// If writing fails, recording stops and the error is reported by the
+// [Recorder.Err].
+type Recorder struct {
+	lock    sync.Mutex        // Access lock
+	w       io.Writer         // Destination
+	objects map[any]recordObj // Recorded objects
+	nextID  uint64            // Next object ID
+	now     func() time.Time  // Current time, replaceable by tests
+	err     error             // Sticky write error
+}
+
+// recordObj represents the recorded object.
+type recordObj struct {
+	id   uint64 // Object ID
+	kind string // Object kind
+}
+
+// record is the single record of the capture.
+type record struct {
+	Time    time.Time       `json:"time"`
+	Client  *recordClient   `json:"client,omitempty"`
+	Obj     uint64          `json:"obj,omitempty"`
+	Kind    string          `json:"kind,omitempty"`
+	Created *ObjectInfo     `json:"created,omitempty"`
+	Event   json.RawMessage `json:"event,omitempty"`
+	Closed  bool            `json:"closed,omitempty"`
+}
+
+// recordClient is the capture header with the Client parameters.
+type recordClient struct {
+	State      ClientState `json:"state"`
+	HostName   string      `json:"hostname"`
+	DomainName string      `json:"domain"`
+	HostFQDN   string      `json:"fqdn"`
+	Version    string      `json:"version"`
+}
+
+// NewRecorder creates a new [Recorder] and writes the capture header.
+func NewRecorder(clnt *Client, w io.Writer) (*Recorder, error) {
+	rec := &Recorder{
+		w:       w,
+		objects: make(map[any]recordObj),
+		nextID:  1,
+		now:     time.Now,
+	}
+
+	rec.write(&record{
+		Client: &recordClient{
+			State:      clnt.getState(),
+			HostName:   clnt.GetHostName(),
+			DomainName: clnt.GetDomainName(),
+			HostFQDN:   clnt.GetHostFQDN(),
+			Version:    clnt.GetVersionString(),
+		},
+	})
+
+	if rec.err != nil {
+		return nil, newError("NewRecorder", rec.err)
+	}
+
+	return rec, nil
+}
+
+// Err returns the first error, occurred while writing the capture.
+func (rec *Recorder) Err() error {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	return rec.err
+}
+
+// Created is called when object is created.
+// It implements the [Observer] interface.
+func (rec *Recorder) Created(ctx context.Context, obj any,
+	info *ObjectInfo) {
+
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+
+	ro := recordObj{id: rec.nextID, kind: info.Kind}
+	rec.nextID++
+	rec.objects[obj] = ro
+
+	rec.write(&record{Obj: ro.id, Kind: ro.kind, Created: info})
+}
+
+// Closed is called when object is closed.
+// It implements the [Observer] interface.
+func (rec *Recorder) Closed(obj any) {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+
+	ro, found := rec.objects[obj]
+	if found {
+		delete(rec.objects, obj)
+		rec.write(&record{Obj: ro.id, Kind: ro.kind, Closed: true})
+	}
+}
+
+// Event is called for each event, generated by object.
+// It implements the [Observer] interface.
+func (rec *Recorder) Event(obj, evnt any) {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+
+	ro, found := rec.objects[obj]
+	if _, isClient := evnt.(*ClientEvent); isClient {
+		ro, found = recordObj{kind: "Client"}, true
+	}
+
+	if !found || rec.err != nil {
+		return
+	}
+
+	data, err := json.Marshal(evnt)
+	if err != nil {
+		rec.err = err
+		return
+	}
+
+	rec.write(&record{Obj: ro.id, Kind: ro.kind, Event: data})
+}
+
+// write writes the record. It must be called under the lock
+// (except for NewRecorder).
+func (rec *Recorder) write(r *record) {
+	if rec.err != nil {
+		return
+	}
+
+	r.Time = rec.now()
+
+	data, err := json.Marshal(r)
+	if err == nil {
+		_, err = rec.w.Write(append(data, '\n'))
+	}
+
+	rec.err = err
+}
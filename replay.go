@@ -0,0 +1,196 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Events replay
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"runtime/cgo"
+	"sync"
+)
+
+// #include <avahi-client/client.h>
+import "C"
+
+// replay is the clientBackend that replays the capture.
+type replay struct {
+	lock         sync.Mutex              // Replaces Avahi event loop lock
+	client       recordClient            // Client parameters
+	clientEvents []json.RawMessage       // Client events
+	objects      map[string][]*replayObj // Recorded objects, by key
+}
+
+// replayObj represents the recorded object.
+type replayObj struct {
+	events []json.RawMessage // Object events
+}
+
+// NewReplayClient creates a new [Client] that replays a capture,
+// written by the [Recorder], instead of talking to the Avahi daemon.
+//
+// It allows to re-run an application against the capture deterministically,
+// as application code works with the same types (browsers, resolvers and
+// their events), as with the real Client.
+//
+// When application creates the object, the first not yet used object
+// of the capture with the same kind and parameters (see [ObjectInfo]) is
+// looked up, and all its recorded events are queued for delivery
+// immediately. So events of each object come in the recorded order,
+// but their timing is not reproduced. If there is no matching object
+// in the capture, created object reports no events.
+//
+// Client methods that return host and domain names and the daemon
+// version return values, recorded in the capture header. The initial
+// Client state is also taken from the header.
+//
+// Publishing is not supported: [NewEntryGroup] fails with the
+// [ErrNotSupported] error.
+func NewReplayClient(r io.Reader, flags ClientFlags) (*Client, error) {
+	// Load the capture
+	rp := &replay{
+		client:  recordClient{State: ClientStateRunning},
+		objects: make(map[string][]*replayObj),
+	}
+
+	byID := make(map[uint64]*replayObj)
+	dec := json.NewDecoder(r)
+
+	for {
+		var rec record
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, newError("NewReplayClient", err)
+		}
+
+		switch {
+		case rec.Client != nil:
+			rp.client = *rec.Client
+
+		case rec.Created != nil:
+			obj := &replayObj{}
+			byID[rec.Obj] = obj
+
+			key := replayKey(rec.Created)
+			rp.objects[key] = append(rp.objects[key], obj)
+
+		case rec.Event != nil && rec.Obj == 0:
+			rp.clientEvents = append(rp.clientEvents, rec.Event)
+
+		case rec.Event != nil && byID[rec.Obj] != nil:
+			obj := byID[rec.Obj]
+			obj.events = append(obj.events, rec.Event)
+		}
+	}
+
+	// Create the Client
+	clnt := &Client{flags: flags, backend: rp}
+	clnt.handle = cgo.NewHandle(clnt)
+	clnt.queue.init()
+	clnt.children.init()
+
+	clnt.queue.Push(&ClientEvent{State: rp.client.State})
+	for _, data := range rp.clientEvents {
+		var evnt *ClientEvent
+		if json.Unmarshal(data, &evnt) == nil {
+			clnt.queue.Push(evnt)
+		}
+	}
+
+	return clnt, nil
+}
+
+// begin locks the replay backend. There is no AvahiClient,
+// so it returns nil.
+func (rp *replay) begin() *C.AvahiClient {
+	rp.lock.Lock()
+	return nil
+}
+
+// end unlocks the replay backend.
+func (rp *replay) end() {
+	rp.lock.Unlock()
+}
+
+// close closes the replay backend.
+func (rp *replay) close(closeChildren func()) {
+	closeChildren()
+}
+
+// create returns recorded events of the first not yet used object
+// of the capture, that matches the info. If there is no such object,
+// the created object reports no events.
+//
+// Publishing is not supported, so creation of the EntryGroup
+// fails with ErrNotSupported.
+func (rp *replay) create(info *ObjectInfo,
+	native func(*C.AvahiClient) error) ([]json.RawMessage, error) {
+
+	if info.Kind == "EntryGroup" {
+		return nil, ErrNotSupported
+	}
+
+	return rp.take(replayKey(info)), nil
+}
+
+// errno returns error code of the latest failed operation.
+// Native operations are never performed by the replay backend,
+// so it always returns ErrNotSupported.
+func (rp *replay) errno() ErrCode {
+	return ErrNotSupported
+}
+
+// versionString returns avahi-daemon version string from the capture.
+func (rp *replay) versionString() string {
+	return rp.client.Version
+}
+
+// hostName returns host name from the capture.
+func (rp *replay) hostName() string {
+	return rp.client.HostName
+}
+
+// domainName returns domain name from the capture.
+func (rp *replay) domainName() string {
+	return rp.client.DomainName
+}
+
+// hostFQDN returns FQDN host name from the capture.
+func (rp *replay) hostFQDN() string {
+	return rp.client.HostFQDN
+}
+
+// state returns the initial Client state from the capture.
+func (rp *replay) state() ClientState {
+	return rp.client.State
+}
+
+// take returns events of the first not yet used recorded object
+// with the given key and marks that object as used.
+//
+// It must be called under the replay lock.
+func (rp *replay) take(key string) []json.RawMessage {
+	objs := rp.objects[key]
+	if len(objs) == 0 {
+		return nil
+	}
+
+	rp.objects[key] = objs[1:]
+	return objs[0].events
+}
+
+// replayKey returns the key, used to match the created object
+// against the recorded objects.
+func replayKey(info *ObjectInfo) string {
+	data, _ := json.Marshal(info)
+	return string(data)
+}
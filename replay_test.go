@@ -0,0 +1,160 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Events recording and replay test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/netip"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCapture is the capture, used by tests.
+//
+// It contains ServiceBrowser, two HostNameResolvers, as created by
+// the LookupHost, and the unrelated RecordBrowser, that is never matched.
+var testCapture = strings.Join([]string{
+	`{"time":"2024-01-01T00:00:00Z","client":{"state":"running","hostname":"host","domain":"local","fqdn":"host.local","version":"avahi 0.8"}}`,
+	`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"ServiceBrowser","created":{"Kind":"ServiceBrowser","IfIdx":-1,"Proto":"unspec","Name":"","SvcType":"_ipp._tcp","Domain":"","Addr":"","AddrProto":"ip4","RClass":"0","RType":"0","BrowserType":"browse","Flags":""}}`,
+	`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"ServiceBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Err":"avahi: OK","Flags":"mdns","InstanceName":"Printer","SvcType":"_ipp._tcp","Domain":"local"}}`,
+	`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"ServiceBrowser","event":{"Event":"BrowserAllForNow","IfIdx":-1,"Proto":"unspec","Err":"avahi: OK","Flags":"","InstanceName":"","SvcType":"","Domain":""}}`,
+	`{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"HostNameResolver","created":{"Kind":"HostNameResolver","IfIdx":-1,"Proto":"unspec","Name":"printer.local","SvcType":"","Domain":"","Addr":"","AddrProto":"ip4","RClass":"0","RType":"0","BrowserType":"browse","Flags":""}}`,
+	`{"time":"2024-01-01T00:00:00Z","obj":2,"kind":"HostNameResolver","event":{"Event":"ResolverFound","IfIdx":2,"Proto":"ip4","Err":"avahi: OK","Flags":"cached|mdns","Hostname":"printer.local","Addr":"192.168.0.1"}}`,
+	`{"time":"2024-01-01T00:00:00Z","obj":3,"kind":"HostNameResolver","created":{"Kind":"HostNameResolver","IfIdx":-1,"Proto":"unspec","Name":"printer.local","SvcType":"","Domain":"","Addr":"","AddrProto":"ip6","RClass":"0","RType":"0","BrowserType":"browse","Flags":""}}`,
+	`{"time":"2024-01-01T00:00:00Z","obj":3,"kind":"HostNameResolver","event":{"Event":"ResolverFailure","IfIdx":-1,"Proto":"unspec","Err":"avahi: Invalid address","Flags":"","Hostname":"printer.local","Addr":""}}`,
+	`{"time":"2024-01-01T00:00:00Z","obj":4,"kind":"RecordBrowser","created":{"Kind":"RecordBrowser","IfIdx":-1,"Proto":"unspec","Name":"printer.local","SvcType":"","Domain":"","Addr":"","AddrProto":"ip4","RClass":"IN","RType":"TXT","BrowserType":"browse","Flags":""}}`,
+	`{"time":"2024-01-01T00:00:00Z","obj":4,"kind":"RecordBrowser","event":{"Event":"BrowserNew","IfIdx":2,"Proto":"ip4","Err":"avahi: OK","Flags":"","Name":"printer.local","RClass":"IN","RType":"TXT","RData":"AA=="}}`,
+	`{"time":"2024-01-01T00:00:00Z","obj":1,"kind":"ServiceBrowser","closed":true}`,
+}, "\n") + "\n"
+
+// TestReplay tests replaying the capture and recording the
+// replayed events back.
+func TestReplay(t *testing.T) {
+	clnt, err := NewReplayClient(strings.NewReader(testCapture), 0)
+	if err != nil {
+		t.Fatalf("NewReplayClient: %s", err)
+	}
+
+	defer clnt.Close()
+
+	// Record replayed events back
+	buf := &bytes.Buffer{}
+	rec, err := NewRecorder(clnt, buf)
+	if err != nil {
+		t.Fatalf("NewRecorder: %s", err)
+	}
+
+	clnt.SetObserver(rec)
+
+	// Check Client
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cevnt, _ := clnt.Get(ctx)
+	if cevnt == nil || cevnt.State != ClientStateRunning {
+		t.Errorf("Client.Get: %+v", cevnt)
+	}
+
+	if s := clnt.GetHostFQDN(); s != "host.local" {
+		t.Errorf("Client.GetHostFQDN: %q", s)
+	}
+
+	// Replay ServiceBrowser
+	browser, err := NewServiceBrowser(clnt, IfIndexUnspec, ProtocolUnspec,
+		"_ipp._tcp", "", 0)
+	if err != nil {
+		t.Fatalf("NewServiceBrowser: %s", err)
+	}
+
+	bevnt, _ := browser.Get(ctx)
+	if bevnt == nil || bevnt.Event != BrowserNew ||
+		bevnt.InstanceName != "Printer" ||
+		bevnt.Flags != LookupResultMulticast {
+		t.Errorf("ServiceBrowser.Get: %+v", bevnt)
+	}
+
+	bevnt, _ = browser.Get(ctx)
+	if bevnt == nil || bevnt.Event != BrowserAllForNow {
+		t.Errorf("ServiceBrowser.Get: %+v", bevnt)
+	}
+
+	browser.Close()
+
+	// Replay LookupHost: IP4 resolver succeeds, IP6 fails
+	addrs, err := LookupHost(ctx, clnt, "printer.local")
+	expected := []netip.Addr{netip.MustParseAddr("192.168.0.1")}
+	if err != nil || len(addrs) != 1 || addrs[0] != expected[0] {
+		t.Errorf("LookupHost: %v, %v", addrs, err)
+	}
+
+	// Object without the recorded counterpart reports nothing
+	resolver, err := NewHostNameResolver(clnt, IfIndexUnspec,
+		ProtocolUnspec, "scanner.local", ProtocolUnspec, 0)
+	if err != nil {
+		t.Fatalf("NewHostNameResolver: %s", err)
+	}
+
+	if n := resolver.queue.Len(); n != 0 {
+		t.Errorf("HostNameResolver: %d unexpected events", n)
+	}
+
+	resolver.Close()
+
+	// Publishing is not supported
+	_, err = NewEntryGroup(clnt)
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("NewEntryGroup: %v", err)
+	}
+
+	// Native operations are never performed
+	if err := clnt.errno(); err != ErrNotSupported {
+		t.Errorf("Client.errno: %v", err)
+	}
+
+	// Parameters are validated, as with the real Client
+	_, err = NewAddressResolver(clnt, IfIndexUnspec, ProtocolUnspec,
+		netip.Addr{}, 0)
+	if !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("NewAddressResolver: %v", err)
+	}
+
+	// Errors are reported with the name of the called constructor
+	_, err = NewAddressResolverContext(context.Background(), clnt,
+		IfIndexUnspec, ProtocolUnspec, netip.Addr{}, 0)
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "NewAddressResolverContext" {
+		t.Errorf("NewAddressResolverContext: %v", err)
+	}
+
+	// Check that replayed events are recorded back
+	tm := regexp.MustCompile(`"time":"[^"]*"`)
+	present := tm.ReplaceAllString(buf.String(),
+		`"time":"2024-01-01T00:00:00Z"`)
+	lines := strings.Split(testCapture, "\n")
+
+	for _, line := range lines[:8] {
+		if !strings.Contains(present, line+"\n") {
+			t.Errorf("not recorded:\n%s\npresent:\n%s",
+				line, present)
+		}
+	}
+}
+
+// TestReplayInvalid tests NewReplayClient with invalid capture
+func TestReplayInvalid(t *testing.T) {
+	_, err := NewReplayClient(strings.NewReader("{}\n{"), 0)
+	if err == nil {
+		t.Errorf("NewReplayClient: error not detected")
+	}
+}
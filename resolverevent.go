@@ -41,3 +41,23 @@ func (e ResolverEvent) String() string {
 	}
 	return n
 }
+
+// MarshalText returns a name of the ResolverEvent.
+// It implements the [encoding.TextMarshaler] interface.
+func (e ResolverEvent) MarshalText() ([]byte, error) {
+	return enumMarshalText(e, resolverEventNames)
+}
+
+// UnmarshalText decodes ResolverEvent from its name.
+// It implements the [encoding.TextUnmarshaler] interface.
+func (e *ResolverEvent) UnmarshalText(text []byte) error {
+	return enumUnmarshalText("ResolverEvent.UnmarshalText", e, text,
+		resolverEventNames)
+}
+
+// UnmarshalJSON decodes ResolverEvent from JSON string or number.
+// It implements the [encoding/json.Unmarshaler] interface.
+func (e *ResolverEvent) UnmarshalJSON(data []byte) error {
+	return numericUnmarshalJSON("ResolverEvent.UnmarshalJSON", e, data,
+		e.UnmarshalText)
+}
@@ -106,28 +106,41 @@ func NewServiceBrowser(
 	}
 
 	// Create AvahiServiceBrowser
-	avahiClient := clnt.begin()
-	defer clnt.end()
-
-	browser.avahiBrowser = C.avahi_service_browser_new(
-		avahiClient,
-		C.AvahiIfIndex(ifidx),
-		C.AvahiProtocol(proto),
-		csvctype, cdomain,
-		C.AvahiLookupFlags(flags),
-		C.AvahiServiceBrowserCallback(C.serviceBrowserCallback),
-		unsafe.Pointer(&browser.handle),
-	)
-
-	if browser.avahiBrowser == nil {
+	info := &ObjectInfo{
+		Kind:    "ServiceBrowser",
+		IfIdx:   ifidx,
+		Proto:   proto,
+		SvcType: svctype,
+		Domain:  domain,
+		Flags:   flags,
+	}
+
+	err := newObject(context.Background(), clnt, browser, &browser.queue,
+		info, func(avahiClient *C.AvahiClient) error {
+			browser.avahiBrowser = C.avahi_service_browser_new(
+				avahiClient,
+				C.AvahiIfIndex(ifidx),
+				C.AvahiProtocol(proto),
+				csvctype, cdomain,
+				C.AvahiLookupFlags(flags),
+				C.AvahiServiceBrowserCallback(
+					C.serviceBrowserCallback),
+				unsafe.Pointer(&browser.handle),
+			)
+
+			if browser.avahiBrowser == nil {
+				return clnt.errno()
+			}
+
+			return nil
+		})
+
+	if err != nil {
 		browser.queue.Close()
 		browser.handle.Delete()
-		return nil, clnt.errno()
+		return nil, newError("NewServiceBrowser", err, svctype, domain)
 	}
 
-	// Register self to be closed if Client is closed
-	browser.clnt.addCloser(browser)
-
 	return browser, nil
 }
 
@@ -158,10 +171,14 @@ func (browser *ServiceBrowser) Get(ctx context.Context) (*ServiceBrowserEvent,
 // Note, double close is safe.
 func (browser *ServiceBrowser) Close() {
 	if !browser.closed.Swap(true) {
+		browser.clnt.notifyClose("ServiceBrowser", browser)
+
 		browser.clnt.begin()
 		browser.clnt.delCloser(browser)
-		C.avahi_service_browser_free(browser.avahiBrowser)
-		browser.avahiBrowser = nil
+		if browser.avahiBrowser != nil {
+			C.avahi_service_browser_free(browser.avahiBrowser)
+			browser.avahiBrowser = nil
+		}
 		browser.clnt.end()
 
 		browser.queue.Close()
@@ -198,5 +215,6 @@ func serviceBrowserCallback(
 		evnt.Err = browser.clnt.errno()
 	}
 
+	browser.clnt.notifyEvent("ServiceBrowser", browser, evnt)
 	browser.queue.Push(evnt)
 }
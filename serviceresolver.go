@@ -131,6 +131,45 @@ func NewServiceResolver(
 	addrproto Protocol,
 	flags LookupFlags) (*ServiceResolver, error) {
 
+	return newServiceResolver(context.Background(),
+		"NewServiceResolver", clnt, ifidx, proto,
+		instname, svctype, domain, addrproto, flags)
+}
+
+// NewServiceResolverContext creates a new [ServiceResolver], like the
+// [NewServiceResolver] does, and associates it with the context.
+//
+// The ctx doesn't limit the ServiceResolver lifetime, it lives until
+// closed. Instead, ctx is passed to the [Observer], so instrumentation
+// (i.e., tracing) may link the ServiceResolver with the operation it
+// was created for.
+func NewServiceResolverContext(
+	ctx context.Context,
+	clnt *Client,
+	ifidx IfIndex,
+	proto Protocol,
+	instname, svctype, domain string,
+	addrproto Protocol,
+	flags LookupFlags) (*ServiceResolver, error) {
+
+	return newServiceResolver(ctx, "NewServiceResolverContext",
+		clnt, ifidx, proto, instname, svctype, domain,
+		addrproto, flags)
+}
+
+// newServiceResolver creates a new [ServiceResolver]. It is the common
+// implementation of the NewServiceResolver and NewServiceResolverContext.
+// op is the operation name, used for errors reporting.
+func newServiceResolver(
+	ctx context.Context,
+	op string,
+	clnt *Client,
+	ifidx IfIndex,
+	proto Protocol,
+	instname, svctype, domain string,
+	addrproto Protocol,
+	flags LookupFlags) (*ServiceResolver, error) {
+
 	// Initialize ServiceResolver structure
 	resolver := &ServiceResolver{clnt: clnt}
 	resolver.handle = cgo.NewHandle(resolver)
@@ -147,29 +186,45 @@ func NewServiceResolver(
 	defer C.free(unsafe.Pointer(cdomain))
 
 	// Create AvahiServiceResolver
-	avahiClient := clnt.begin()
-	defer clnt.end()
-
-	resolver.avahiResolver = C.avahi_service_resolver_new(
-		avahiClient,
-		C.AvahiIfIndex(ifidx),
-		C.AvahiProtocol(proto),
-		cinstname, csvctype, cdomain,
-		C.AvahiProtocol(addrproto),
-		C.AvahiLookupFlags(flags),
-		C.AvahiServiceResolverCallback(C.serviceResolverCallback),
-		unsafe.Pointer(&resolver.handle),
-	)
-
-	if resolver.avahiResolver == nil {
+	info := &ObjectInfo{
+		Kind:      "ServiceResolver",
+		IfIdx:     ifidx,
+		Proto:     proto,
+		Name:      instname,
+		SvcType:   svctype,
+		Domain:    domain,
+		AddrProto: addrproto,
+		Flags:     flags,
+	}
+
+	err := newObject(ctx, clnt, resolver, &resolver.queue,
+		info, func(avahiClient *C.AvahiClient) error {
+			resolver.avahiResolver = C.avahi_service_resolver_new(
+				avahiClient,
+				C.AvahiIfIndex(ifidx),
+				C.AvahiProtocol(proto),
+				cinstname, csvctype, cdomain,
+				C.AvahiProtocol(addrproto),
+				C.AvahiLookupFlags(flags),
+				C.AvahiServiceResolverCallback(
+					C.serviceResolverCallback),
+				unsafe.Pointer(&resolver.handle),
+			)
+
+			if resolver.avahiResolver == nil {
+				return clnt.errno()
+			}
+
+			return nil
+		})
+
+	if err != nil {
 		resolver.queue.Close()
 		resolver.handle.Delete()
-		return nil, clnt.errno()
+		return nil, newError(op, err,
+			instname, svctype, domain)
 	}
 
-	// Register self to be closed if Client is closed
-	resolver.clnt.addCloser(resolver)
-
 	return resolver, nil
 }
 
@@ -198,10 +253,14 @@ func (resolver *ServiceResolver) Get(ctx context.Context) (
 // It closes the event channel, effectively unblocking pending readers.
 func (resolver *ServiceResolver) Close() {
 	if !resolver.closed.Swap(true) {
+		resolver.clnt.notifyClose("ServiceResolver", resolver)
+
 		resolver.clnt.begin()
 		resolver.clnt.delCloser(resolver)
-		C.avahi_service_resolver_free(resolver.avahiResolver)
-		resolver.avahiResolver = nil
+		if resolver.avahiResolver != nil {
+			C.avahi_service_resolver_free(resolver.avahiResolver)
+			resolver.avahiResolver = nil
+		}
 		resolver.clnt.end()
 
 		resolver.queue.Close()
@@ -262,5 +321,6 @@ func serviceResolverCallback(
 		evnt.Err = clnt.errno()
 	}
 
+	resolver.clnt.notifyEvent("ServiceResolver", resolver, evnt)
 	resolver.queue.Push(evnt)
 }
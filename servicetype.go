@@ -0,0 +1,120 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Service type
+//
+//go:build linux || freebsd
+
+package avahi
+
+// ServiceType represents the parsed DNS-SD service type, optionally
+// with subtype:
+//
+//	"_ipp._tcp"                 -> {Name: "ipp", Proto: "tcp"}
+//	"_universal._sub._ipp._tcp" -> {Name: "ipp", Proto: "tcp",
+//	                                Subtype: "_universal"}
+//
+// Name and Proto are stored without the leading underscore.
+// Subtype is stored as is (unescaped), as subtype labels are not
+// required to start with underscore. See [RFC6763, 7.1.] for details.
+//
+// [RFC6763, 7.1.]: https://datatracker.ietf.org/doc/html/rfc6763#section-7.1
+type ServiceType struct {
+	Name    string // Service name (i.e., "ipp")
+	Proto   string // Protocol, "tcp" or "udp"
+	Subtype string // Subtype (i.e., "_universal"), "" if none
+}
+
+// ParseServiceType parses the service type string, like "_ipp._tcp"
+// or "_universal._sub._ipp._tcp".
+//
+// The service type is validated like [ValidateServiceType] and
+// [ValidateServiceSubtype] do. In a case of error, [*ValidationError]
+// is returned.
+func ParseServiceType(svctype string) (ServiceType, error) {
+	var err error
+	var labels []string
+
+	labels, err = validateDomain(svctype, ErrInvalidServiceType)
+	if err == nil {
+		switch len(labels) {
+		case 2:
+			err = validateServiceType(ErrInvalidServiceType,
+				svctype, labels, 0, true)
+		case 4:
+			err = ValidateServiceSubtype(svctype)
+		default:
+			err = validateError(ErrInvalidServiceType, svctype, -1,
+				"must consist of 2 or 4 labels")
+		}
+	}
+
+	if err != nil {
+		return ServiceType{}, err
+	}
+
+	st := ServiceType{
+		Name:  labels[len(labels)-2][1:],
+		Proto: labels[len(labels)-1][1:],
+	}
+
+	if len(labels) == 4 {
+		st.Subtype = labels[0]
+	}
+
+	return st, nil
+}
+
+// String returns the service type string, including subtype, if
+// any, as accepted by the [ServiceBrowser]:
+//
+//	"_ipp._tcp"
+//	"_universal._sub._ipp._tcp"
+func (st ServiceType) String() string {
+	labels := make([]string, 0, 4)
+	if st.Subtype != "" {
+		labels = append(labels, st.Subtype, "_sub")
+	}
+
+	labels = append(labels, "_"+st.Name, "_"+st.Proto)
+	return DomainFrom(labels)
+}
+
+// IsSubtype reports if ServiceType has subtype.
+func (st ServiceType) IsSubtype() bool {
+	return st.Subtype != ""
+}
+
+// Base returns ServiceType without subtype. Its string form
+// is the service type, as [EntryGroupService].SvcType expects.
+func (st ServiceType) Base() ServiceType {
+	st.Subtype = ""
+	return st
+}
+
+// WithSubtype returns copy of the ServiceType with the
+// specified subtype.
+func (st ServiceType) WithSubtype(subtype string) ServiceType {
+	st.Subtype = subtype
+	return st
+}
+
+// ServiceSubtypeName constructs the subtype name from the unescaped
+// subtype label and the service type:
+//
+//	"_universal", "_ipp._tcp" -> "_universal._sub._ipp._tcp"
+//
+// The result can be used for browsing with [ServiceBrowser] and
+// for publishing with the [EntryGroup.AddServiceSubtype].
+//
+// Strong validation of input strings is not performed here.
+// In a case of error it returns empty string.
+func ServiceSubtypeName(subtype, svctype string) string {
+	if subtype == "" || svctype == "" {
+		return ""
+	}
+
+	return DomainFrom([]string{subtype, "_sub"}) + "." + svctype
+}
@@ -0,0 +1,122 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Service type test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import "testing"
+
+// TestParseServiceType tests ParseServiceType and ServiceType.String
+func TestParseServiceType(t *testing.T) {
+	type testData struct {
+		in  string
+		st  ServiceType
+		out string
+		err bool
+	}
+
+	tests := []testData{
+		{
+			in:  `_ipp._tcp`,
+			st:  ServiceType{Name: "ipp", Proto: "tcp"},
+			out: `_ipp._tcp`,
+		},
+
+		{
+			in: `_universal._sub._ipp._tcp`,
+			st: ServiceType{
+				Name:    "ipp",
+				Proto:   "tcp",
+				Subtype: "_universal",
+			},
+			out: `_universal._sub._ipp._tcp`,
+		},
+
+		{
+			in: `Color\.Laser._sub._printer._tcp`,
+			st: ServiceType{
+				Name:    "printer",
+				Proto:   "tcp",
+				Subtype: "Color.Laser",
+			},
+			out: `Color\.Laser._sub._printer._tcp`,
+		},
+
+		{
+			in:  `_ipp._tcp.local`,
+			err: true,
+		},
+
+		{
+			in:  `_ipp._xxx`,
+			err: true,
+		},
+
+		{
+			in:  `_universal._subtype._ipp._tcp`,
+			err: true,
+		},
+	}
+
+	for _, test := range tests {
+		st, err := ParseServiceType(test.in)
+		switch {
+		case test.err && err == nil:
+			t.Errorf("%q: error expected", test.in)
+
+		case !test.err && err != nil:
+			t.Errorf("%q: %s", test.in, err)
+
+		case err == nil && st != test.st:
+			t.Errorf("%q:\n"+
+				"expected: %#v\n"+
+				"present:  %#v\n",
+				test.in, test.st, st)
+
+		case err == nil && st.String() != test.out:
+			t.Errorf("%q:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				test.in, test.out, st.String())
+		}
+	}
+}
+
+// TestServiceSubtypeName tests ServiceSubtypeName and ServiceType
+// subtype-related methods
+func TestServiceSubtypeName(t *testing.T) {
+	name := ServiceSubtypeName("_universal", "_ipp._tcp")
+	if name != `_universal._sub._ipp._tcp` {
+		t.Errorf("ServiceSubtypeName: %q", name)
+	}
+
+	name = ServiceSubtypeName("Color.Laser", "_printer._tcp")
+	if name != `Color\.Laser._sub._printer._tcp` {
+		t.Errorf("ServiceSubtypeName: %q", name)
+	}
+
+	name = ServiceSubtypeName("", "_ipp._tcp")
+	if name != `` {
+		t.Errorf("ServiceSubtypeName: %q", name)
+	}
+
+	st := ServiceType{Name: "ipp", Proto: "tcp"}
+	sub := st.WithSubtype("_print")
+
+	if st.IsSubtype() || !sub.IsSubtype() {
+		t.Errorf("ServiceType.IsSubtype: wrong answer")
+	}
+
+	if sub.String() != ServiceSubtypeName("_print", st.String()) {
+		t.Errorf("ServiceType.WithSubtype: %q", sub)
+	}
+
+	if sub.Base() != st {
+		t.Errorf("ServiceType.Base: %q", sub.Base())
+	}
+}
@@ -100,29 +100,41 @@ func NewServiceTypeBrowser(
 		defer C.free(unsafe.Pointer(cdomain))
 	}
 
-	// Create AvahiServiceBrowser
-	avahiClient := clnt.begin()
-	defer clnt.end()
-
-	browser.avahiBrowser = C.avahi_service_type_browser_new(
-		avahiClient,
-		C.AvahiIfIndex(ifidx),
-		C.AvahiProtocol(proto),
-		cdomain,
-		C.AvahiLookupFlags(flags),
-		C.AvahiServiceBrowserCallback(C.serviceTypeBrowserCallback),
-		unsafe.Pointer(&browser.handle),
-	)
-
-	if browser.avahiBrowser == nil {
+	// Create AvahiServiceTypeBrowser
+	info := &ObjectInfo{
+		Kind:   "ServiceTypeBrowser",
+		IfIdx:  ifidx,
+		Proto:  proto,
+		Domain: domain,
+		Flags:  flags,
+	}
+
+	err := newObject(context.Background(), clnt, browser, &browser.queue,
+		info, func(avahiClient *C.AvahiClient) error {
+			browser.avahiBrowser = C.avahi_service_type_browser_new(
+				avahiClient,
+				C.AvahiIfIndex(ifidx),
+				C.AvahiProtocol(proto),
+				cdomain,
+				C.AvahiLookupFlags(flags),
+				C.AvahiServiceBrowserCallback(
+					C.serviceTypeBrowserCallback),
+				unsafe.Pointer(&browser.handle),
+			)
+
+			if browser.avahiBrowser == nil {
+				return clnt.errno()
+			}
+
+			return nil
+		})
+
+	if err != nil {
 		browser.queue.Close()
 		browser.handle.Delete()
-		return nil, clnt.errno()
+		return nil, newError("NewServiceTypeBrowser", err, domain)
 	}
 
-	// Register self to be closed if Client is closed
-	browser.clnt.addCloser(browser)
-
 	return browser, nil
 }
 
@@ -153,10 +165,14 @@ func (browser *ServiceTypeBrowser) Get(ctx context.Context) (
 // Note, double close is safe.
 func (browser *ServiceTypeBrowser) Close() {
 	if !browser.closed.Swap(true) {
+		browser.clnt.notifyClose("ServiceTypeBrowser", browser)
+
 		browser.clnt.begin()
 		browser.clnt.delCloser(browser)
-		C.avahi_service_type_browser_free(browser.avahiBrowser)
-		browser.avahiBrowser = nil
+		if browser.avahiBrowser != nil {
+			C.avahi_service_type_browser_free(browser.avahiBrowser)
+			browser.avahiBrowser = nil
+		}
 		browser.clnt.end()
 
 		browser.queue.Close()
@@ -192,5 +208,6 @@ func serviceTypeBrowserCallback(
 		evnt.Err = browser.clnt.errno()
 	}
 
+	browser.clnt.notifyEvent("ServiceTypeBrowser", browser, evnt)
 	browser.queue.Push(evnt)
 }
@@ -0,0 +1,431 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Static service definition files
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// StaticServiceGroup represents a content of the avahi-daemon static
+// service definition file (/etc/avahi/services/*.service).
+//
+// The file format is described in the [avahi.service(5)] manual page.
+// In short, each file defines a single service instance name, shared
+// between one or more services:
+//
+//	<?xml version="1.0" standalone='no'?>
+//	<!DOCTYPE service-group SYSTEM "avahi-service.dtd">
+//	<service-group>
+//	  <name replace-wildcards="yes">Printer on %h</name>
+//	  <service protocol="ipv4">
+//	    <type>_ipp._tcp</type>
+//	    <subtype>_universal._sub._ipp._tcp</subtype>
+//	    <port>631</port>
+//	    <txt-record>rp=ipp/print</txt-record>
+//	  </service>
+//	</service-group>
+//
+// [avahi.service(5)]: https://linux.die.net/man/5/avahi.service
+type StaticServiceGroup struct {
+	Name             string           // Service instance name
+	ReplaceWildcards bool             // Replace %h in Name with host name
+	Services         []*StaticService // Services in the group
+}
+
+// StaticService represents a single service, defined in the
+// static service definition file.
+//
+// The InstanceName of the embedded EntryGroupService is always the
+// same as the Name of the containing [StaticServiceGroup], without
+// wildcards substitution. IfIdx is always [IfIndexUnspec], as the
+// file format doesn't allow to specify the network interface.
+type StaticService struct {
+	EntryGroupService          // The service
+	Subtypes          []string // Service subtypes
+}
+
+// staticServiceGroupXML is the XML representation
+// of the StaticServiceGroup
+type staticServiceGroupXML struct {
+	XMLName  xml.Name           `xml:"service-group"`
+	Name     staticNameXML      `xml:"name"`
+	Services []staticServiceXML `xml:"service"`
+}
+
+// staticNameXML is the XML representation of the <name> element
+type staticNameXML struct {
+	ReplaceWildcards string `xml:"replace-wildcards,attr,omitempty"`
+	Value            string `xml:",chardata"`
+}
+
+// staticServiceXML is the XML representation of the <service> element
+type staticServiceXML struct {
+	Protocol string         `xml:"protocol,attr,omitempty"`
+	Type     string         `xml:"type"`
+	Subtypes []string       `xml:"subtype"`
+	Domain   string         `xml:"domain-name,omitempty"`
+	Hostname string         `xml:"host-name,omitempty"`
+	Port     string         `xml:"port"`
+	Txt      []staticTxtXML `xml:"txt-record"`
+}
+
+// staticTxtXML is the XML representation of the <txt-record> element
+type staticTxtXML struct {
+	ValueFormat string `xml:"value-format,attr,omitempty"`
+	Value       string `xml:",chardata"`
+}
+
+// staticServiceHeader is written in front of the generated
+// static service definition files.
+const staticServiceHeader = `<?xml version="1.0" standalone='no'?>` + "\n" +
+	`<!DOCTYPE service-group SYSTEM "avahi-service.dtd">` + "\n"
+
+// LoadStaticServiceGroup loads the static service definition file.
+func LoadStaticServiceGroup(path string) (*StaticServiceGroup, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	grp, err := ParseStaticServiceGroup(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return grp, nil
+}
+
+// ParseStaticServiceGroup parses the static service definition file
+// content.
+func ParseStaticServiceGroup(data []byte) (*StaticServiceGroup, error) {
+	// Decode XML
+	var x staticServiceGroupXML
+	err := xml.Unmarshal(data, &x)
+	if err != nil {
+		return nil, fmt.Errorf("avahi: service file: %w", err)
+	}
+
+	// Decode <name>
+	grp := &StaticServiceGroup{
+		Name: strings.TrimSpace(x.Name.Value),
+	}
+
+	if grp.Name == "" {
+		return nil, staticServiceError("missing <name>")
+	}
+
+	switch strings.ToLower(x.Name.ReplaceWildcards) {
+	case "", "no":
+	case "yes":
+		grp.ReplaceWildcards = true
+	default:
+		return nil, staticServiceError("invalid replace-wildcards=%q",
+			x.Name.ReplaceWildcards)
+	}
+
+	// Decode services
+	if len(x.Services) == 0 {
+		return nil, staticServiceError("missing <service>")
+	}
+
+	for _, xsvc := range x.Services {
+		svc, err := xsvc.decode(grp.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		grp.Services = append(grp.Services, svc)
+	}
+
+	return grp, nil
+}
+
+// decode decodes the <service> element.
+func (xsvc *staticServiceXML) decode(name string) (*StaticService, error) {
+	svc := &StaticService{
+		EntryGroupService: EntryGroupService{
+			IfIdx:        IfIndexUnspec,
+			InstanceName: name,
+			SvcType:      strings.TrimSpace(xsvc.Type),
+			Domain:       strings.TrimSpace(xsvc.Domain),
+			Hostname:     strings.TrimSpace(xsvc.Hostname),
+		},
+	}
+
+	// Decode protocol
+	switch strings.ToLower(xsvc.Protocol) {
+	case "", "any":
+		svc.Proto = ProtocolUnspec
+	case "ipv4":
+		svc.Proto = ProtocolIP4
+	case "ipv6":
+		svc.Proto = ProtocolIP6
+	default:
+		return nil, staticServiceError("invalid protocol=%q",
+			xsvc.Protocol)
+	}
+
+	// Decode type and subtypes
+	if svc.SvcType == "" {
+		return nil, staticServiceError("missing <type>")
+	}
+
+	for _, subtype := range xsvc.Subtypes {
+		subtype = strings.TrimSpace(subtype)
+		if subtype == "" {
+			return nil, staticServiceError("empty <subtype>")
+		}
+		svc.Subtypes = append(svc.Subtypes, subtype)
+	}
+
+	// Decode port
+	port := strings.TrimSpace(xsvc.Port)
+	if port == "" {
+		return nil, staticServiceError("missing <port>")
+	}
+
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, staticServiceError("invalid <port>%s</port>", port)
+	}
+
+	svc.Port = int(n)
+
+	// Decode TXT record
+	for _, xtxt := range xsvc.Txt {
+		txt, err := xtxt.decode()
+		if err != nil {
+			return nil, err
+		}
+
+		svc.Txt = append(svc.Txt, txt)
+	}
+
+	return svc, nil
+}
+
+// decode decodes the <txt-record> element.
+//
+// For binary value formats, only the value part of the
+// "key=value" pair is encoded, key remains as is.
+func (xtxt *staticTxtXML) decode() (string, error) {
+	format := strings.ToLower(xtxt.ValueFormat)
+	if format == "" || format == "text" {
+		return xtxt.Value, nil
+	}
+
+	key, val, found := strings.Cut(strings.TrimSpace(xtxt.Value), "=")
+	if !found {
+		return "", staticServiceError("<txt-record>%s</txt-record>: "+
+			"missing '=' in binary value", xtxt.Value)
+	}
+
+	var bin []byte
+	var err error
+
+	switch format {
+	case "binary-hex":
+		bin, err = hex.DecodeString(val)
+	case "binary-base64":
+		bin, err = base64.StdEncoding.DecodeString(val)
+	default:
+		return "", staticServiceError("invalid value-format=%q",
+			xtxt.ValueFormat)
+	}
+
+	if err != nil {
+		return "", staticServiceError("<txt-record>%s</txt-record>: %s",
+			xtxt.Value, err)
+	}
+
+	return key + "=" + string(bin), nil
+}
+
+// Marshal encodes the StaticServiceGroup into the static service
+// definition file format, suitable for /etc/avahi/services.
+//
+// TXT values that are not printable UTF-8 strings are written
+// using the "binary-hex" value format.
+func (grp *StaticServiceGroup) Marshal() ([]byte, error) {
+	x := staticServiceGroupXML{
+		Name: staticNameXML{Value: grp.Name},
+	}
+
+	if grp.ReplaceWildcards {
+		x.Name.ReplaceWildcards = "yes"
+	}
+
+	for _, svc := range grp.Services {
+		xsvc := staticServiceXML{
+			Type:     svc.SvcType,
+			Subtypes: svc.Subtypes,
+			Domain:   svc.Domain,
+			Hostname: svc.Hostname,
+			Port:     strconv.Itoa(svc.Port),
+		}
+
+		switch svc.Proto {
+		case ProtocolUnspec:
+		case ProtocolIP4:
+			xsvc.Protocol = "ipv4"
+		case ProtocolIP6:
+			xsvc.Protocol = "ipv6"
+		default:
+			return nil, newError("StaticServiceGroup.Marshal",
+				ErrInvalidProtocol, svc.SvcType, svc.Proto)
+		}
+
+		if svc.Port < 0 || svc.Port > 65535 {
+			return nil, newError("StaticServiceGroup.Marshal",
+				ErrInvalidPort, svc.SvcType, svc.Port)
+		}
+
+		for _, txt := range svc.Txt {
+			xsvc.Txt = append(xsvc.Txt, staticTxtEncode(txt))
+		}
+
+		x.Services = append(x.Services, xsvc)
+	}
+
+	data, err := xml.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBufferString(staticServiceHeader)
+	buf.Write(data)
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// staticTxtEncode encodes a single TXT "key=value" string
+// into the <txt-record> element.
+func staticTxtEncode(txt string) staticTxtXML {
+	if staticTxtPrintable(txt) {
+		return staticTxtXML{Value: txt}
+	}
+
+	key, val, found := strings.Cut(txt, "=")
+	if !found || !staticTxtPrintable(key) {
+		// Binary formats only encode value; if key is
+		// not printable, the best we can do is to write
+		// it as is and let the XML encoder to complain.
+		return staticTxtXML{Value: txt}
+	}
+
+	return staticTxtXML{
+		ValueFormat: "binary-hex",
+		Value:       key + "=" + hex.EncodeToString([]byte(val)),
+	}
+}
+
+// staticTxtPrintable reports if string can be written as
+// the "text" value of the <txt-record> element.
+func staticTxtPrintable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+
+	for _, c := range s {
+		if c < ' ' || c == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+// InstanceName returns service instance name of the group with
+// wildcards replaced, if [StaticServiceGroup.ReplaceWildcards]
+// is set. The only supported wildcard is "%h", which is replaced
+// with the hostname.
+func (grp *StaticServiceGroup) InstanceName(hostname string) string {
+	if grp.ReplaceWildcards {
+		return strings.ReplaceAll(grp.Name, "%h", hostname)
+	}
+	return grp.Name
+}
+
+// EntryGroupServices returns services, defined in the group, as
+// a slice of [EntryGroupService], ready to be added to the [EntryGroup].
+//
+// The hostname is used for wildcards substitution in the service
+// instance name.
+//
+// Note, service subtypes are not included into the EntryGroupService,
+// use [StaticService.Subtypes] to obtain them.
+func (grp *StaticServiceGroup) EntryGroupServices(
+	hostname string) []*EntryGroupService {
+
+	instname := grp.InstanceName(hostname)
+
+	svcs := make([]*EntryGroupService, 0, len(grp.Services))
+	for _, svc := range grp.Services {
+		egsvc := svc.EntryGroupService
+		egsvc.InstanceName = instname
+		egsvc.Txt = append([]string(nil), svc.Txt...)
+		svcs = append(svcs, &egsvc)
+	}
+
+	return svcs
+}
+
+// Publish adds all services and subtypes, defined in the group,
+// to the [EntryGroup], the same way as avahi-daemon does it.
+//
+// The "%h" wildcard in the service instance name is replaced with
+// the [Client.GetHostName].
+//
+// The EntryGroup is not committed by this call, it is the
+// caller's responsibility.
+func (grp *StaticServiceGroup) Publish(egrp *EntryGroup,
+	flags PublishFlags) error {
+
+	hostname := egrp.clnt.GetHostName()
+	svcs := grp.EntryGroupServices(hostname)
+
+	for i, svc := range svcs {
+		err := egrp.AddService(svc, flags)
+		if err != nil {
+			return err
+		}
+
+		svcid := &EntryGroupServiceIdent{
+			IfIdx:        svc.IfIdx,
+			Proto:        svc.Proto,
+			InstanceName: svc.InstanceName,
+			SvcType:      svc.SvcType,
+			Domain:       svc.Domain,
+		}
+
+		for _, subtype := range grp.Services[i].Subtypes {
+			err = egrp.AddServiceSubtype(svcid, subtype, flags)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// staticServiceError creates a new static service file parse error.
+func staticServiceError(format string, args ...any) error {
+	return fmt.Errorf("avahi: service file: "+format, args...)
+}
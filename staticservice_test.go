@@ -0,0 +1,262 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Static service definition files test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestParseStaticServiceGroup tests ParseStaticServiceGroup function
+func TestParseStaticServiceGroup(t *testing.T) {
+	type testData struct {
+		data string
+		grp  *StaticServiceGroup
+		err  string
+	}
+
+	tests := []testData{
+		{
+			data: `<?xml version="1.0" standalone='no'?>
+<!DOCTYPE service-group SYSTEM "avahi-service.dtd">
+<service-group>
+  <name replace-wildcards="yes">Printer on %h</name>
+  <service protocol="ipv4">
+    <type>_ipp._tcp</type>
+    <subtype>_universal._sub._ipp._tcp</subtype>
+    <port>631</port>
+    <txt-record>rp=ipp/print</txt-record>
+    <txt-record value-format="binary-hex">bin=00ff</txt-record>
+    <txt-record value-format="binary-base64">b64=aGVsbG8=</txt-record>
+  </service>
+  <service>
+    <type>_printer._tcp</type>
+    <domain-name>example.com</domain-name>
+    <host-name>printer.example.com</host-name>
+    <port>0</port>
+  </service>
+</service-group>`,
+			grp: &StaticServiceGroup{
+				Name:             "Printer on %h",
+				ReplaceWildcards: true,
+				Services: []*StaticService{
+					{
+						EntryGroupService: EntryGroupService{
+							IfIdx:        IfIndexUnspec,
+							Proto:        ProtocolIP4,
+							InstanceName: "Printer on %h",
+							SvcType:      "_ipp._tcp",
+							Port:         631,
+							Txt: []string{
+								"rp=ipp/print",
+								"bin=\x00\xff",
+								"b64=hello",
+							},
+						},
+						Subtypes: []string{
+							"_universal._sub._ipp._tcp",
+						},
+					},
+					{
+						EntryGroupService: EntryGroupService{
+							IfIdx:        IfIndexUnspec,
+							Proto:        ProtocolUnspec,
+							InstanceName: "Printer on %h",
+							SvcType:      "_printer._tcp",
+							Domain:       "example.com",
+							Hostname:     "printer.example.com",
+							Port:         0,
+						},
+					},
+				},
+			},
+		},
+
+		{
+			// Missed name
+			data: `<service-group>
+  <service><type>_http._tcp</type><port>80</port></service>
+</service-group>`,
+			err: `avahi: service file: missing <name>`,
+		},
+
+		{
+			// Missed port
+			data: `<service-group>
+  <name>Test</name>
+  <service><type>_http._tcp</type></service>
+</service-group>`,
+			err: `avahi: service file: missing <port>`,
+		},
+
+		{
+			// Invalid port
+			data: `<service-group>
+  <name>Test</name>
+  <service><type>_http._tcp</type><port>65536</port></service>
+</service-group>`,
+			err: `avahi: service file: invalid <port>65536</port>`,
+		},
+
+		{
+			// Invalid protocol
+			data: `<service-group>
+  <name>Test</name>
+  <service protocol="ipx">
+    <type>_http._tcp</type><port>80</port>
+  </service>
+</service-group>`,
+			err: `avahi: service file: invalid protocol="ipx"`,
+		},
+
+		{
+			// Invalid binary TXT
+			data: `<service-group>
+  <name>Test</name>
+  <service>
+    <type>_http._tcp</type><port>80</port>
+    <txt-record value-format="binary-hex">novalue</txt-record>
+  </service>
+</service-group>`,
+			err: `avahi: service file: <txt-record>novalue</txt-record>: missing '=' in binary value`,
+		},
+	}
+
+	for _, test := range tests {
+		grp, err := ParseStaticServiceGroup([]byte(test.data))
+
+		errstr := ""
+		if err != nil {
+			errstr = err.Error()
+		}
+
+		if errstr != test.err {
+			t.Errorf("%s:\n"+
+				"error expected: %s\n"+
+				"error present:  %s\n",
+				test.data, test.err, errstr)
+			continue
+		}
+
+		if !reflect.DeepEqual(grp, test.grp) {
+			t.Errorf("%s:\n"+
+				"expected: %#v\n"+
+				"present:  %#v\n",
+				test.data, test.grp, grp)
+		}
+	}
+}
+
+// TestStaticServiceGroupMarshal tests StaticServiceGroup.Marshal
+func TestStaticServiceGroupMarshal(t *testing.T) {
+	grp := &StaticServiceGroup{
+		Name:             "Web server on %h",
+		ReplaceWildcards: true,
+		Services: []*StaticService{
+			{
+				EntryGroupService: EntryGroupService{
+					IfIdx:        IfIndexUnspec,
+					Proto:        ProtocolIP6,
+					InstanceName: "Web server on %h",
+					SvcType:      "_http._tcp",
+					Port:         80,
+					Txt: []string{
+						"path=/<index>&",
+						"bin=\x01\x02",
+					},
+				},
+				Subtypes: []string{"_printer._sub._http._tcp"},
+			},
+		},
+	}
+
+	expected := `<?xml version="1.0" standalone='no'?>
+<!DOCTYPE service-group SYSTEM "avahi-service.dtd">
+<service-group>
+  <name replace-wildcards="yes">Web server on %h</name>
+  <service protocol="ipv6">
+    <type>_http._tcp</type>
+    <subtype>_printer._sub._http._tcp</subtype>
+    <port>80</port>
+    <txt-record>path=/&lt;index&gt;&amp;</txt-record>
+    <txt-record value-format="binary-hex">bin=0102</txt-record>
+  </service>
+</service-group>
+`
+
+	data, err := grp.Marshal()
+	if err != nil {
+		t.Errorf("StaticServiceGroup.Marshal: %s", err)
+		return
+	}
+
+	if string(data) != expected {
+		t.Errorf("StaticServiceGroup.Marshal:\n"+
+			"expected:\n%s\n"+
+			"present:\n%s\n",
+			expected, data)
+	}
+
+	// Check round-trip
+	grp2, err := ParseStaticServiceGroup(data)
+	if err != nil {
+		t.Errorf("ParseStaticServiceGroup: %s", err)
+		return
+	}
+
+	if !reflect.DeepEqual(grp, grp2) {
+		t.Errorf("round-trip:\n"+
+			"expected: %#v\n"+
+			"present:  %#v\n",
+			grp, grp2)
+	}
+
+	// Check errors
+	grp.Services[0].Port = 65536
+	_, err = grp.Marshal()
+
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, ErrInvalidPort) {
+		t.Errorf("StaticServiceGroup.Marshal:\n"+
+			"expected: %v\n"+
+			"present:  %v\n",
+			ErrInvalidPort, err)
+	}
+}
+
+// TestStaticServiceGroupEntryGroupServices tests
+// StaticServiceGroup.EntryGroupServices
+func TestStaticServiceGroupEntryGroupServices(t *testing.T) {
+	grp := &StaticServiceGroup{
+		Name:             "Printer on %h",
+		ReplaceWildcards: true,
+		Services: []*StaticService{
+			{
+				EntryGroupService: EntryGroupService{
+					InstanceName: "Printer on %h",
+					SvcType:      "_ipp._tcp",
+					Port:         631,
+				},
+			},
+		},
+	}
+
+	svcs := grp.EntryGroupServices("host")
+	if len(svcs) != 1 || svcs[0].InstanceName != "Printer on host" {
+		t.Errorf("EntryGroupServices: %#v", svcs)
+	}
+
+	grp.ReplaceWildcards = false
+	svcs = grp.EntryGroupServices("host")
+	if len(svcs) != 1 || svcs[0].InstanceName != "Printer on %h" {
+		t.Errorf("EntryGroupServices: %#v", svcs)
+	}
+}
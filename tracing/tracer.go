@@ -0,0 +1,273 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// OpenTelemetry tracing
+//
+//go:build linux || freebsd
+
+// Package tracing implements OpenTelemetry tracing of the avahi
+// package resolvers and entry groups.
+//
+// It lives in a separate module, so the core avahi package doesn't
+// depend on OpenTelemetry.
+//
+// Usage:
+//
+//	clnt, err := avahi.NewClient(0)
+//	...
+//	clnt.SetObserver(tracing.NewTracer(nil))
+//
+// The following spans are generated:
+//   - "avahi.ServiceResolver", "avahi.HostNameResolver" and
+//     "avahi.AddressResolver" span covers the whole resolver lifetime,
+//     from creation to Close. Resolver parameters are recorded as span
+//     attributes, each resolver event is recorded as span event, and
+//     result flags of the first result (i.e., avahi.result.cached)
+//     are recorded as span attributes. ResolverFailure sets the span
+//     status to Error.
+//   - "avahi.EntryGroup.register" span covers the EntryGroup
+//     registration, from the [avahi.EntryGroupStateRegistering]
+//     state (which follows the [avahi.EntryGroup.Commit]) to the
+//     [avahi.EntryGroupStateEstablished], [avahi.EntryGroupStateCollision]
+//     or [avahi.EntryGroupStateFailure] state. The last two set span
+//     status to Error.
+//
+// Resolvers, created with the context (see [avahi.NewServiceResolverContext]
+// and others, and [avahi.LookupHost], [avahi.LookupAddr] and [avahi.Dialer]
+// which use them), create their spans as children of the span, found in
+// that context.
+//
+// To use tracing together with other observers (i.e., metrics),
+// use the [avahi.MultiObserver].
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/OpenPrinting/go-avahi"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer generates OpenTelemetry spans for the avahi objects.
+//
+// It implements the [avahi.Observer] interface and needs to be attached
+// to the [avahi.Client] with the [avahi.Client.SetObserver].
+type Tracer struct {
+	tracer  trace.Tracer     // Underlying tracer
+	lock    sync.Mutex       // Access lock
+	objects map[any]*object  // Traced objects
+	now     func() time.Time // Current time, replaceable by tests
+}
+
+// object represents the traced object
+type object struct {
+	ctx        context.Context // Context object was created with
+	span       trace.Span      // Resolver span
+	resolved   bool            // Resolver reported its first result
+	registered time.Time       // EntryGroup registration start time
+}
+
+// scope is the instrumentation scope name
+const scope = "github.com/OpenPrinting/go-avahi/tracing"
+
+// NewTracer creates a new [Tracer].
+//
+// If tp is nil, the global TracerProvider is used (see [otel.GetTracerProvider]).
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	return &Tracer{
+		tracer:  tp.Tracer(scope),
+		objects: make(map[any]*object),
+		now:     time.Now,
+	}
+}
+
+// Created is called when object is created.
+// It implements the [avahi.Observer] interface.
+func (t *Tracer) Created(ctx context.Context, obj any,
+	info *avahi.ObjectInfo) {
+
+	var attrs []attribute.KeyValue
+
+	switch info.Kind {
+	case "ServiceResolver":
+		attrs = []attribute.KeyValue{
+			attribute.String("avahi.instance", info.Name),
+			attribute.String("avahi.service.type", info.SvcType),
+			attribute.String("avahi.domain", info.Domain),
+			attribute.String("avahi.addrproto",
+				info.AddrProto.String()),
+		}
+
+	case "HostNameResolver":
+		attrs = []attribute.KeyValue{
+			attribute.String("avahi.hostname", info.Name),
+			attribute.String("avahi.addrproto",
+				info.AddrProto.String()),
+		}
+
+	case "AddressResolver":
+		attrs = []attribute.KeyValue{
+			attribute.String("avahi.address", info.Addr.String()),
+		}
+
+	case "EntryGroup":
+		t.lock.Lock()
+		t.objects[obj] = &object{ctx: ctx}
+		t.lock.Unlock()
+		return
+
+	default:
+		return
+	}
+
+	attrs = append(attrs,
+		attribute.Int("avahi.ifidx", int(info.IfIdx)),
+		attribute.String("avahi.proto", info.Proto.String()),
+		attribute.String("avahi.lookup.flags", info.Flags.String()))
+
+	_, span := t.tracer.Start(ctx, "avahi."+info.Kind,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(t.now()),
+		trace.WithAttributes(attrs...))
+
+	t.lock.Lock()
+	t.objects[obj] = &object{ctx: ctx, span: span}
+	t.lock.Unlock()
+}
+
+// Closed is called when object is closed.
+// It implements the [avahi.Observer] interface.
+func (t *Tracer) Closed(obj any) {
+	t.lock.Lock()
+	o := t.objects[obj]
+	delete(t.objects, obj)
+	t.lock.Unlock()
+
+	if o != nil && o.span != nil {
+		o.span.End(trace.WithTimestamp(t.now()))
+	}
+}
+
+// Event is called for each event, generated by object.
+// It implements the [avahi.Observer] interface.
+func (t *Tracer) Event(obj, evnt any) {
+	switch evnt := evnt.(type) {
+	case *avahi.ServiceResolverEvent:
+		t.resolverEvent(obj, evnt.Event, evnt.Err, evnt.Flags,
+			attribute.String("avahi.hostname", evnt.Hostname),
+			attribute.String("avahi.address", evnt.Addr.String()),
+			attribute.Int("avahi.port", int(evnt.Port)))
+
+	case *avahi.HostNameResolverEvent:
+		t.resolverEvent(obj, evnt.Event, evnt.Err, evnt.Flags,
+			attribute.String("avahi.address", evnt.Addr.String()))
+
+	case *avahi.AddressResolverEvent:
+		t.resolverEvent(obj, evnt.Event, evnt.Err, evnt.Flags,
+			attribute.String("avahi.hostname", evnt.Hostname))
+
+	case *avahi.EntryGroupEvent:
+		t.entryGroupEvent(obj, evnt)
+	}
+}
+
+// resolverEvent records the resolver event.
+func (t *Tracer) resolverEvent(obj any, event avahi.ResolverEvent,
+	err avahi.ErrCode, flags avahi.LookupResultFlags,
+	attrs ...attribute.KeyValue) {
+
+	t.lock.Lock()
+	o := t.objects[obj]
+	first := o != nil && !o.resolved
+	if first {
+		o.resolved = true
+	}
+	t.lock.Unlock()
+
+	if o == nil {
+		return
+	}
+
+	now := t.now()
+
+	if event == avahi.ResolverFailure {
+		o.span.RecordError(err, trace.WithTimestamp(now))
+		o.span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	flagsAttrs := []attribute.KeyValue{
+		attribute.String("avahi.result.flags", flags.String()),
+		attribute.Bool("avahi.result.cached",
+			flags&avahi.LookupResultCached != 0),
+	}
+
+	if first {
+		o.span.SetAttributes(flagsAttrs...)
+	}
+
+	attrs = append(flagsAttrs, attrs...)
+	o.span.AddEvent(event.String(), trace.WithTimestamp(now),
+		trace.WithAttributes(attrs...))
+}
+
+// entryGroupEvent records the EntryGroup state change.
+func (t *Tracer) entryGroupEvent(obj any, evnt *avahi.EntryGroupEvent) {
+	now := t.now()
+
+	t.lock.Lock()
+	o := t.objects[obj]
+	if o == nil {
+		t.lock.Unlock()
+		return
+	}
+
+	started := o.registered
+	switch evnt.State {
+	case avahi.EntryGroupStateRegistering:
+		if started.IsZero() {
+			o.registered = now
+		}
+		started = time.Time{}
+
+	default:
+		o.registered = time.Time{}
+	}
+
+	ctx := o.ctx
+	t.lock.Unlock()
+
+	// Registration completed?
+	if started.IsZero() ||
+		evnt.State == avahi.EntryGroupStateUncommited {
+		return
+	}
+
+	_, span := t.tracer.Start(ctx, "avahi.EntryGroup.register",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(started),
+		trace.WithAttributes(
+			attribute.String("avahi.entry_group.state",
+				evnt.State.String())))
+
+	if evnt.Err != avahi.NoError {
+		span.RecordError(evnt.Err, trace.WithTimestamp(now))
+	}
+
+	if evnt.State != avahi.EntryGroupStateEstablished {
+		span.SetStatus(codes.Error, evnt.State.String())
+	}
+
+	span.End(trace.WithTimestamp(now))
+}
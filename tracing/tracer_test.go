@@ -0,0 +1,191 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// OpenTelemetry tracing test
+//
+//go:build linux || freebsd
+
+package tracing
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/OpenPrinting/go-avahi"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testTracer creates Tracer that records spans into the SpanRecorder.
+// Its fake clock advances by 100ms on each reading.
+func testTracer() (*Tracer, *tracetest.SpanRecorder) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	t := NewTracer(tp)
+
+	now := time.Unix(0, 0)
+	t.now = func() time.Time {
+		now = now.Add(100 * time.Millisecond)
+		return now
+	}
+
+	return t, rec
+}
+
+// testAttr returns value of the attribute, as string.
+func testAttr(attrs []attribute.KeyValue, key string) string {
+	for _, attr := range attrs {
+		if string(attr.Key) == key {
+			return attr.Value.Emit()
+		}
+	}
+	return ""
+}
+
+// TestTracerResolver tests resolver spans
+func TestTracerResolver(t *testing.T) {
+	tracer, rec := testTracer()
+
+	// Create parent span
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+
+	// Simulate HostNameResolver
+	resolver := &avahi.HostNameResolver{}
+	tracer.Created(ctx, resolver, &avahi.ObjectInfo{
+		Kind:      "HostNameResolver",
+		IfIdx:     2,
+		Proto:     avahi.ProtocolIP4,
+		Name:      "printer.local",
+		AddrProto: avahi.ProtocolIP6,
+	})
+
+	tracer.Event(resolver, &avahi.HostNameResolverEvent{
+		Event: avahi.ResolverFound,
+		Flags: avahi.LookupResultCached | avahi.LookupResultMulticast,
+		Addr:  netip.MustParseAddr("fe80::1"),
+	})
+
+	tracer.Closed(resolver)
+	parent.End()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans recorded, expected 2", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name() != "avahi.HostNameResolver" {
+		t.Errorf("span name: %q", span.Name())
+	}
+
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("span parent doesn't match")
+	}
+
+	if d := span.EndTime().Sub(span.StartTime()); d != 200*time.Millisecond {
+		t.Errorf("span duration: %s", d)
+	}
+
+	expected := map[string]string{
+		"avahi.hostname":      "printer.local",
+		"avahi.ifidx":         "2",
+		"avahi.proto":         "ip4",
+		"avahi.addrproto":     "ip6",
+		"avahi.result.flags":  "cached,mdns",
+		"avahi.result.cached": "true",
+	}
+
+	for key, value := range expected {
+		present := testAttr(span.Attributes(), key)
+		if present != value {
+			t.Errorf("%s:\n"+
+				"expected: %q\n"+
+				"present:  %q\n",
+				key, value, present)
+		}
+	}
+
+	events := span.Events()
+	if len(events) != 1 || events[0].Name != "ResolverFound" ||
+		testAttr(events[0].Attributes, "avahi.address") != "fe80::1" {
+		t.Errorf("span events: %+v", events)
+	}
+
+	// Simulate failed ServiceResolver
+	resolver2 := &avahi.ServiceResolver{}
+	tracer.Created(context.Background(), resolver2, &avahi.ObjectInfo{
+		Kind:    "ServiceResolver",
+		SvcType: "_ipp._tcp",
+	})
+
+	tracer.Event(resolver2, &avahi.ServiceResolverEvent{
+		Event: avahi.ResolverFailure,
+		Err:   avahi.ErrTimeout,
+	})
+
+	tracer.Closed(resolver2)
+
+	span = rec.Ended()[2]
+	if testAttr(span.Attributes(), "avahi.service.type") != "_ipp._tcp" {
+		t.Errorf("avahi.service.type missed: %v", span.Attributes())
+	}
+
+	if span.Status().Code != codes.Error {
+		t.Errorf("span status: %v", span.Status())
+	}
+}
+
+// TestTracerEntryGroup tests EntryGroup registration spans
+func TestTracerEntryGroup(t *testing.T) {
+	tracer, rec := testTracer()
+
+	egrp := &avahi.EntryGroup{}
+	tracer.Created(context.Background(), egrp,
+		&avahi.ObjectInfo{Kind: "EntryGroup"})
+
+	states := []avahi.EntryGroupState{
+		avahi.EntryGroupStateUncommited,
+		avahi.EntryGroupStateRegistering,
+		avahi.EntryGroupStateEstablished,
+		avahi.EntryGroupStateRegistering,
+		avahi.EntryGroupStateCollision,
+		avahi.EntryGroupStateUncommited,
+	}
+
+	for _, state := range states {
+		tracer.Event(egrp, &avahi.EntryGroupEvent{State: state})
+	}
+
+	tracer.Closed(egrp)
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans recorded, expected 2", len(spans))
+	}
+
+	for i, state := range []string{"established", "collision"} {
+		span := spans[i]
+		present := testAttr(span.Attributes(), "avahi.entry_group.state")
+		if span.Name() != "avahi.EntryGroup.register" || present != state {
+			t.Errorf("span %d: %s, state=%s", i, span.Name(), present)
+		}
+
+		d := span.EndTime().Sub(span.StartTime())
+		if d != 100*time.Millisecond {
+			t.Errorf("span %d duration: %s", i, d)
+		}
+	}
+
+	if spans[0].Status().Code == codes.Error ||
+		spans[1].Status().Code != codes.Error {
+		t.Errorf("span status: %v, %v",
+			spans[0].Status(), spans[1].Status())
+	}
+}
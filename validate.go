@@ -0,0 +1,327 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Names validation
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits, imposed by DNS. See [RFC1035, 2.3.4.] for details.
+//
+// [RFC1035, 2.3.4.]: https://datatracker.ietf.org/doc/html/rfc1035#section-2.3.4
+const (
+	validateLabelMax  = 63  // Max label length, in bytes
+	validateDomainMax = 255 // Max domain length, in wire format
+)
+
+// ValidationError is returned by [DomainValidate], [ValidateHostName],
+// [ValidateServiceType], [ValidateServiceSubtype] and
+// [ValidateInstanceName] and explains, why the name is invalid.
+//
+// It wraps the appropriate [ErrCode] (for example, [ErrInvalidDomainName]),
+// so errors.Is(err, ErrInvalidDomainName) works as expected.
+type ValidationError struct {
+	Code   ErrCode // Underlying error code
+	Name   string  // The name being validated
+	Label  int     // Index of the failed label, -1 if not applicable
+	Reason string  // Reason of failure
+}
+
+// Error returns the error string.
+// It implements the error interface.
+func (err *ValidationError) Error() string {
+	if err.Label >= 0 {
+		return fmt.Sprintf("%s: %q: label %d: %s",
+			err.Code, err.Name, err.Label, err.Reason)
+	}
+
+	return fmt.Sprintf("%s: %q: %s", err.Code, err.Name, err.Reason)
+}
+
+// Unwrap returns the underlying [ErrCode].
+func (err *ValidationError) Unwrap() error {
+	return err.Code
+}
+
+// DomainValidate checks that d is the valid escaped domain name.
+//
+// It checks the escape syntax, label lengths (up to 63 bytes each),
+// total name length (up to 255 bytes in the DNS wire format) and
+// that labels are not empty. The trailing dot is allowed. The empty
+// string and "." mean the root domain and considered valid.
+//
+// In a case of error it returns [*ValidationError] that wraps
+// [ErrInvalidDomainName].
+func DomainValidate(d string) error {
+	_, err := validateDomain(d, ErrInvalidDomainName)
+	return err
+}
+
+// ValidateHostName checks that name is the valid host name.
+//
+// Host name may be either a single label ("myhost") or the fully
+// qualified domain name ("myhost.local"). It is validated as the
+// domain name (see [DomainValidate]), but must not be empty.
+//
+// In a case of error it returns [*ValidationError] that wraps
+// [ErrInvalidHostName].
+func ValidateHostName(name string) error {
+	labels, err := validateDomain(name, ErrInvalidHostName)
+	if err == nil && len(labels) == 0 {
+		err = validateError(ErrInvalidHostName, name, -1, "empty name")
+	}
+
+	return err
+}
+
+// ValidateServiceType checks that svctype is the valid service type,
+// like "_ipp._tcp".
+//
+// Service type consists of exactly two labels. The first label is
+// the service name, prefixed with underscore, that must conform
+// to [RFC6335, 5.1.]: 1-15 characters, letters, digits and hyphens
+// only, at least one letter, no leading, trailing or consecutive
+// hyphens. The second label is the protocol and must be either
+// "_tcp" or "_udp".
+//
+// Subtypes (i.e., "_universal._sub._ipp._tcp") are not accepted here,
+// use [ValidateServiceSubtype] to validate them.
+//
+// In a case of error it returns [*ValidationError] that wraps
+// [ErrInvalidServiceType].
+//
+// [RFC6335, 5.1.]: https://datatracker.ietf.org/doc/html/rfc6335#section-5.1
+func ValidateServiceType(svctype string) error {
+	return validateServiceTypeName(svctype, true)
+}
+
+// ValidateServiceSubtype checks that subtype is the valid service
+// subtype, like "_universal._sub._ipp._tcp".
+//
+// Subtype consists of the subtype label, followed by the "_sub"
+// label and the service type, as [ValidateServiceType] accepts it.
+// The subtype label may contain any characters, but must not be
+// empty. See [RFC6763, 7.1.] for details.
+//
+// In a case of error it returns [*ValidationError] that wraps
+// [ErrInvalidServiceSubtype].
+//
+// [RFC6763, 7.1.]: https://datatracker.ietf.org/doc/html/rfc6763#section-7.1
+func ValidateServiceSubtype(subtype string) error {
+	return validateServiceSubtypeName(subtype, true)
+}
+
+// ValidateInstanceName checks that name is the valid service
+// instance name.
+//
+// Unlike other names, service instance name is a single
+// unescaped label, like "My Printer". It must not be empty, must
+// not exceed 63 bytes, must be the valid UTF-8 string and must not
+// contain ASCII control characters. See [RFC6763, 4.1.1.] for details.
+//
+// In a case of error it returns [*ValidationError] that wraps
+// [ErrInvalidServiceName].
+//
+// [RFC6763, 4.1.1.]: https://datatracker.ietf.org/doc/html/rfc6763#section-4.1.1
+func ValidateInstanceName(name string) error {
+	var reason string
+
+	switch {
+	case name == "":
+		reason = "empty name"
+	case len(name) > validateLabelMax:
+		reason = fmt.Sprintf("too long (%d > %d bytes)",
+			len(name), validateLabelMax)
+	case !utf8valid([]byte(name)):
+		reason = "invalid UTF-8"
+	}
+
+	for i := 0; reason == "" && i < len(name); i++ {
+		if c := name[i]; c < 0x20 || c == 0x7f {
+			reason = fmt.Sprintf("control character 0x%2.2x", c)
+		}
+	}
+
+	if reason != "" {
+		return validateError(ErrInvalidServiceName, name, -1, reason)
+	}
+
+	return nil
+}
+
+// validateDomain validates the domain name and returns it, split
+// into labels. On error, the returned *ValidationError wraps
+// the code.
+func validateDomain(d string, code ErrCode) ([]string, error) {
+	// The root domain name is explicitly allowed
+	if d == "." {
+		return []string{}, nil
+	}
+
+	labels, bad, reason := domainUnescape(d)
+	if labels == nil {
+		return nil, validateError(code, d, bad, reason)
+	}
+
+	total := 1
+	for i, label := range labels {
+		switch {
+		case label == "":
+			return nil, validateError(code, d, i, "empty label")
+		case len(label) > validateLabelMax:
+			return nil, validateError(code, d, i,
+				fmt.Sprintf("too long (%d > %d bytes)",
+					len(label), validateLabelMax))
+		}
+
+		total += len(label) + 1
+	}
+
+	if total > validateDomainMax {
+		return nil, validateError(code, d, -1,
+			fmt.Sprintf("too long (%d > %d bytes)",
+				total, validateDomainMax))
+	}
+
+	return labels, nil
+}
+
+// validateServiceTypeName validates the service type, like
+// "_ipp._tcp".
+//
+// If strict is false, the service name is not checked against the
+// RFC6335 rules, like avahi-daemon does when publishing services.
+// The EntryGroup uses it, so services, that Avahi accepts (for
+// example, "_androidtvremote2._tcp" or "_microsoft_mcc._tcp"),
+// are not rejected.
+func validateServiceTypeName(svctype string, strict bool) error {
+	labels, err := validateDomain(svctype, ErrInvalidServiceType)
+	if err != nil {
+		return err
+	}
+
+	if len(labels) != 2 {
+		return validateError(ErrInvalidServiceType, svctype, -1,
+			"must consist of 2 labels")
+	}
+
+	return validateServiceType(ErrInvalidServiceType, svctype,
+		labels, 0, strict)
+}
+
+// validateServiceSubtypeName validates the service subtype, like
+// "_universal._sub._ipp._tcp". The strict parameter has the same
+// meaning, as for validateServiceTypeName.
+func validateServiceSubtypeName(subtype string, strict bool) error {
+	labels, err := validateDomain(subtype, ErrInvalidServiceSubtype)
+	if err != nil {
+		return err
+	}
+
+	if len(labels) != 4 {
+		return validateError(ErrInvalidServiceSubtype, subtype, -1,
+			"must consist of 4 labels")
+	}
+
+	if !strcaseequal(labels[1], "_sub") {
+		return validateError(ErrInvalidServiceSubtype, subtype, 1,
+			`must be "_sub"`)
+	}
+
+	return validateServiceType(ErrInvalidServiceSubtype, subtype,
+		labels[2:], 2, strict)
+}
+
+// validateServiceType validates the service type labels (the service
+// name and protocol). The first label index is used for error
+// reporting. If strict is true, the service name is checked
+// against the RFC6335 rules.
+func validateServiceType(code ErrCode, name string,
+	labels []string, first int, strict bool) error {
+
+	// Check the service name
+	svc := labels[0]
+	if len(svc) < 2 || svc[0] != '_' {
+		return validateError(code, name, first,
+			"must start with underscore")
+	}
+
+	if strict {
+		if reason := validateServiceName(svc[1:]); reason != "" {
+			return validateError(code, name, first, reason)
+		}
+	}
+
+	// Check the protocol
+	proto := labels[1]
+	if !strcaseequal(proto, "_tcp") && !strcaseequal(proto, "_udp") {
+		return validateError(code, name, first+1,
+			`must be "_tcp" or "_udp"`)
+	}
+
+	return nil
+}
+
+// validateServiceName validates the service name (without leading
+// underscore), according to the RFC6335 rules. It returns the
+// reason of failure or "" if name is OK.
+func validateServiceName(svc string) string {
+	if len(svc) > 15 {
+		return fmt.Sprintf("service name too long (%d > 15 characters)",
+			len(svc))
+	}
+
+	if svc[0] == '-' || svc[len(svc)-1] == '-' {
+		return "service name must not begin or end with hyphen"
+	}
+
+	letter := false
+	for i := 0; i < len(svc); i++ {
+		c := svc[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+			letter = true
+
+		case isdigit(c):
+
+		case c == '-':
+			if svc[i-1] == '-' {
+				return "service name must not contain " +
+					"consecutive hyphens"
+			}
+
+		default:
+			if r, _ := utf8.DecodeRuneInString(svc[i:]); r != utf8.RuneError {
+				return fmt.Sprintf("invalid character %q "+
+					"in service name", r)
+			}
+			return "invalid character in service name"
+		}
+	}
+
+	if !letter {
+		return "service name must contain at least one letter"
+	}
+
+	return ""
+}
+
+// validateError creates a new *ValidationError.
+func validateError(code ErrCode, name string,
+	label int, reason string) *ValidationError {
+
+	return &ValidationError{
+		Code:   code,
+		Name:   name,
+		Label:  label,
+		Reason: reason,
+	}
+}
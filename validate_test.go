@@ -0,0 +1,313 @@
+// CGo binding for Avahi
+//
+// Copyright (C) 2024 and up by Alexander Pevzner (pzz@apevzner.com)
+// See LICENSE for license terms and conditions
+//
+// Names validation test
+//
+//go:build linux || freebsd
+
+package avahi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestValidate tests names validation functions
+func TestValidate(t *testing.T) {
+	type testData struct {
+		fn    func(string) error // Validation function
+		name  string             // Input name
+		code  ErrCode            // Expected error code, 0 if OK
+		label int                // Expected failed label
+	}
+
+	long := strings.Repeat("x", 64)
+
+	// EntryGroup validates service types as strictly as Avahi does
+	egrpType := func(svctype string) error {
+		return entryGroupValidate("My Printer", svctype, "")
+	}
+
+	egrpSubtype := func(subtype string) error {
+		return validateServiceSubtypeName(subtype, false)
+	}
+
+	tests := []testData{
+		// DomainValidate
+		{fn: DomainValidate, name: ``},
+		{fn: DomainValidate, name: `.`},
+		{fn: DomainValidate, name: `local`},
+		{fn: DomainValidate, name: `example.com.`},
+		{fn: DomainValidate, name: `My\.Printer\032x.local`},
+		{fn: DomainValidate, name: long[1:] + `.local`},
+		{
+			fn:    DomainValidate,
+			name:  `example..com`,
+			code:  ErrInvalidDomainName,
+			label: 1,
+		},
+		{
+			fn:    DomainValidate,
+			name:  `.example.com`,
+			code:  ErrInvalidDomainName,
+			label: 0,
+		},
+		{
+			fn:    DomainValidate,
+			name:  `example.` + long,
+			code:  ErrInvalidDomainName,
+			label: 1,
+		},
+		{
+			fn:    DomainValidate,
+			name:  `example.c\?om`,
+			code:  ErrInvalidDomainName,
+			label: 1,
+		},
+		{
+			fn:    DomainValidate,
+			name:  `example.com\`,
+			code:  ErrInvalidDomainName,
+			label: 1,
+		},
+		{
+			fn:    DomainValidate,
+			name:  `ex\000ample.com`,
+			code:  ErrInvalidDomainName,
+			label: 0,
+		},
+		{
+			fn:    DomainValidate,
+			name:  `ex\25ample.com`,
+			code:  ErrInvalidDomainName,
+			label: 0,
+		},
+		{
+			fn:    DomainValidate,
+			name:  `ex\256ample.com`,
+			code:  ErrInvalidDomainName,
+			label: 0,
+		},
+		{
+			fn:    DomainValidate,
+			name:  `ex\255ample.com`,
+			code:  ErrInvalidDomainName,
+			label: 0,
+		},
+		{
+			fn: DomainValidate,
+			name: strings.Repeat(long[1:]+".", 3) +
+				long[:63-2],
+		},
+		{
+			fn: DomainValidate,
+			name: strings.Repeat(long[1:]+".", 3) +
+				long[:63-1],
+			code:  ErrInvalidDomainName,
+			label: -1,
+		},
+
+		// ValidateHostName
+		{fn: ValidateHostName, name: `myhost`},
+		{fn: ValidateHostName, name: `myhost.local`},
+		{
+			fn:    ValidateHostName,
+			name:  ``,
+			code:  ErrInvalidHostName,
+			label: -1,
+		},
+		{
+			fn:    ValidateHostName,
+			name:  `my..host`,
+			code:  ErrInvalidHostName,
+			label: 1,
+		},
+
+		// ValidateServiceType
+		{fn: ValidateServiceType, name: `_ipp._tcp`},
+		{fn: ValidateServiceType, name: `_pdl-datastream._tcp`},
+		{fn: ValidateServiceType, name: `_dns-sd._UDP`},
+		{fn: ValidateServiceType, name: `_1password4._tcp`},
+		{
+			fn:    ValidateServiceType,
+			name:  `_ipp`,
+			code:  ErrInvalidServiceType,
+			label: -1,
+		},
+		{
+			fn:    ValidateServiceType,
+			name:  `_ipp._tcp.local`,
+			code:  ErrInvalidServiceType,
+			label: -1,
+		},
+		{
+			fn:    ValidateServiceType,
+			name:  `ipp._tcp`,
+			code:  ErrInvalidServiceType,
+			label: 0,
+		},
+		{
+			fn:    ValidateServiceType,
+			name:  `_ipp._sctp`,
+			code:  ErrInvalidServiceType,
+			label: 1,
+		},
+		{
+			fn:    ValidateServiceType,
+			name:  `_0123456789abcdef._tcp`,
+			code:  ErrInvalidServiceType,
+			label: 0,
+		},
+		{
+			fn:    ValidateServiceType,
+			name:  `_-ipp._tcp`,
+			code:  ErrInvalidServiceType,
+			label: 0,
+		},
+		{
+			fn:    ValidateServiceType,
+			name:  `_ipp-._tcp`,
+			code:  ErrInvalidServiceType,
+			label: 0,
+		},
+		{
+			fn:    ValidateServiceType,
+			name:  `_i--pp._tcp`,
+			code:  ErrInvalidServiceType,
+			label: 0,
+		},
+		{
+			fn:    ValidateServiceType,
+			name:  `_i_pp._tcp`,
+			code:  ErrInvalidServiceType,
+			label: 0,
+		},
+		{
+			fn:    ValidateServiceType,
+			name:  `_1234._tcp`,
+			code:  ErrInvalidServiceType,
+			label: 0,
+		},
+
+		// ValidateServiceSubtype
+		{fn: ValidateServiceSubtype, name: `_universal._sub._ipp._tcp`},
+		{fn: ValidateServiceSubtype, name: `Any\032thing._sub._ipp._tcp`},
+		{
+			fn:    ValidateServiceSubtype,
+			name:  `_ipp._tcp`,
+			code:  ErrInvalidServiceSubtype,
+			label: -1,
+		},
+		{
+			fn:    ValidateServiceSubtype,
+			name:  `_universal._subtype._ipp._tcp`,
+			code:  ErrInvalidServiceSubtype,
+			label: 1,
+		},
+		{
+			fn:    ValidateServiceSubtype,
+			name:  `_universal._sub._ipp._xxx`,
+			code:  ErrInvalidServiceSubtype,
+			label: 3,
+		},
+
+		// EntryGroup service types
+		{fn: egrpType, name: `_ipp._tcp`},
+		{fn: egrpType, name: `_androidtvremote2._tcp`},
+		{fn: egrpType, name: `_microsoft_mcc._tcp`},
+		{fn: egrpType, name: `_1234._udp`},
+		{
+			fn:    egrpType,
+			name:  `ipp._tcp`,
+			code:  ErrInvalidServiceType,
+			label: 0,
+		},
+		{
+			fn:    egrpType,
+			name:  `_ipp._sctp`,
+			code:  ErrInvalidServiceType,
+			label: 1,
+		},
+		{
+			fn:    egrpType,
+			name:  `_ipp._tcp.local`,
+			code:  ErrInvalidServiceType,
+			label: -1,
+		},
+		{
+			fn:    egrpType,
+			name:  `_` + long[1:] + `x._tcp`,
+			code:  ErrInvalidServiceType,
+			label: 0,
+		},
+		{fn: egrpSubtype, name: `_tv._sub._androidtvremote2._tcp`},
+		{
+			fn:    egrpSubtype,
+			name:  `_tv._sub._androidtvremote2._xxx`,
+			code:  ErrInvalidServiceSubtype,
+			label: 3,
+		},
+
+		// ValidateInstanceName
+		{fn: ValidateInstanceName, name: `My Printer`},
+		{fn: ValidateInstanceName, name: `Dot.And\Backslash`},
+		{fn: ValidateInstanceName, name: `Принтер`},
+		{
+			fn:    ValidateInstanceName,
+			name:  ``,
+			code:  ErrInvalidServiceName,
+			label: -1,
+		},
+		{
+			fn:    ValidateInstanceName,
+			name:  long,
+			code:  ErrInvalidServiceName,
+			label: -1,
+		},
+		{
+			fn:    ValidateInstanceName,
+			name:  "Bad\tName",
+			code:  ErrInvalidServiceName,
+			label: -1,
+		},
+		{
+			fn:    ValidateInstanceName,
+			name:  "Bad\xffName",
+			code:  ErrInvalidServiceName,
+			label: -1,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.fn(test.name)
+
+		switch {
+		case test.code == 0 && err != nil:
+			t.Errorf("%q: %s", test.name, err)
+
+		case test.code != 0 && err == nil:
+			t.Errorf("%q: error expected", test.name)
+
+		case test.code != 0:
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Errorf("%q: %T is not *ValidationError",
+					test.name, err)
+				continue
+			}
+
+			if !errors.Is(err, test.code) ||
+				verr.Label != test.label {
+				t.Errorf("%q:\n"+
+					"expected: %d %s\n"+
+					"present:  %d %s\n",
+					test.name,
+					test.label, test.code,
+					verr.Label, verr.Code)
+			}
+		}
+	}
+}